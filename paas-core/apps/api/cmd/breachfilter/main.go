@@ -0,0 +1,103 @@
+// Command breachfilter builds the breached password filter loaded through
+// password.breach_filter (PASSWORD_BREACH_FILTER).
+//
+// The input has one entry per line: plaintext passwords by default, or hex
+// SHA-1 digests with -sha1 (the Pwned Passwords "HASH:count" format works as
+// is). The list is read twice, once to size the filter and once to fill it,
+// so it can be much larger than memory.
+//
+//	go run ./cmd/breachfilter -in rockyou.txt -out configs/breached.bloom
+//	go run ./cmd/breachfilter -in pwned-passwords-sha1.txt -sha1 -fp 0.0001 -out breached.bloom
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"paas-core/apps/api/internal/password"
+)
+
+func main() {
+	in := flag.String("in", "", "password list, one entry per line")
+	out := flag.String("out", "breached.bloom", "filter file to write")
+	fpRate := flag.Float64("fp", 0.001, "false positive rate")
+	sha1Input := flag.Bool("sha1", false, "entries are hex SHA-1 digests instead of plaintext")
+	flag.Parse()
+
+	if *in == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var count uint64
+	if err := eachLine(*in, func(string) error { count++; return nil }); err != nil {
+		fail(err)
+	}
+
+	filter, err := password.NewBreachFilter(count, *fpRate)
+	if err != nil {
+		fail(err)
+	}
+
+	err = eachLine(*in, func(line string) error {
+		if !*sha1Input {
+			filter.Add(line)
+			return nil
+		}
+		digest, err := password.ParseSHA1Line(line)
+		if err != nil {
+			return err
+		}
+		filter.AddDigest(digest)
+		return nil
+	})
+	if err != nil {
+		fail(err)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		fail(err)
+	}
+	size, err := filter.WriteTo(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fail(err)
+	}
+
+	fmt.Printf("Wrote %s: %d entries, %d bytes\n", *out, count, size)
+}
+
+// eachLine calls fn for every non-empty line of the file at path. Trailing
+// carriage returns are dropped but other whitespace is kept, since it can be
+// part of a password.
+func eachLine(path string, fn func(line string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "breachfilter:", err)
+	os.Exit(1)
+}
@@ -2,20 +2,23 @@ package main
 
 import (
 	"fmt"
-	"golang.org/x/crypto/bcrypt"
+
+	"paas-core/apps/api/internal/config"
+	"paas-core/apps/api/internal/password"
 )
 
 func main() {
+	hasher := password.NewHasher(config.PasswordConfig{})
 	passwords := map[string]string{
 		"admin": "admin",
 		"local": "local",
 	}
 	for label, pw := range passwords {
-		hash, err := bcrypt.GenerateFromPassword([]byte(pw), bcrypt.DefaultCost)
+		hash, err := hasher.Hash(pw)
 		if err != nil {
 			fmt.Printf("Error hashing %s: %v\n", label, err)
 			continue
 		}
-		fmt.Printf("%s: %s\n", label, string(hash))
+		fmt.Printf("%s: %s\n", label, hash)
 	}
 }
@@ -13,6 +13,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"paas-core/apps/api/internal/account"
+	"paas-core/apps/api/internal/alert"
+	"paas-core/apps/api/internal/audit"
 	"paas-core/apps/api/internal/auth"
 	"paas-core/apps/api/internal/authprovider"
 	"paas-core/apps/api/internal/billing"
@@ -21,11 +24,18 @@ import (
 	"paas-core/apps/api/internal/email"
 	apiErrors "paas-core/apps/api/internal/errors"
 	"paas-core/apps/api/internal/featuregate"
+	"paas-core/apps/api/internal/idp"
+	"paas-core/apps/api/internal/impersonation"
+	"paas-core/apps/api/internal/mfa"
 	"paas-core/apps/api/internal/middleware"
 	"paas-core/apps/api/internal/model"
 	"paas-core/apps/api/internal/oauth"
 	"paas-core/apps/api/internal/org"
+	"paas-core/apps/api/internal/passkey"
+	"paas-core/apps/api/internal/password"
+	"paas-core/apps/api/internal/pat"
 	"paas-core/apps/api/internal/project"
+	"paas-core/apps/api/internal/serviceaccount"
 	"paas-core/apps/api/internal/storage"
 	"paas-core/apps/api/internal/user"
 )
@@ -54,17 +64,42 @@ func main() {
 	defer database.Close(db)
 
 	// --- 3a. Auto-migrate new models (safe: only adds missing tables/columns) ---
+	// Users synced from Supabase before auth_provider existed get its "local"
+	// default and are backfilled below
+	backfillAuthProvider := !db.Migrator().HasColumn(&model.User{}, "AuthProvider")
 	if err := db.AutoMigrate(
 		&model.User{},
 		&model.Role{},
 		&model.UserRole{},
 		&model.RefreshToken{},
+		&model.PersonalAccessToken{},
+		&model.UserSession{},
+		&model.RevokedToken{},
+		&model.TokenWatermark{},
+		&model.SigningKey{},
 		&model.EmailVerificationToken{},
 		&model.PasswordResetToken{},
+		&model.MagicLinkToken{},
+		&model.EmailChangeRequest{},
+		&model.AccountDeletion{},
+		&model.AccountLockout{},
+		&model.AccountUnlockToken{},
+		&model.SecurityAlertToken{},
+		&model.TOTPFactor{},
+		&model.RecoveryCode{},
+		&model.MFAChallenge{},
 		&model.OAuthAccount{},
+		&model.OAuthReauthState{},
+		&model.WebAuthnCredential{},
+		&model.WebAuthnSession{},
 		&model.FileUpload{},
 		&model.Org{},
 		&model.Membership{},
+		&model.ServiceAccount{},
+		&model.OIDCClient{},
+		&model.OIDCAuthorizationCode{},
+		&model.OIDCConsent{},
+		&model.OIDCAccessToken{},
 		&model.Project{},
 		&model.Deployment{},
 		&model.EnvVar{},
@@ -72,37 +107,79 @@ func main() {
 		&model.Subscription{},
 		&model.Invoice{},
 		&model.AuditLog{},
+		&model.ProcessedWebhookEvent{},
 	); err != nil {
 		slog.Error("AutoMigrate failed", "error", err)
 		os.Exit(1)
 	}
 	slog.Info("Database schema migrated")
+	if backfillAuthProvider && (cfg.Supabase.Enabled || cfg.AuthMigration.From == "supabase") {
+		n, err := authprovider.BackfillSupabaseUsers(context.Background(), db)
+		if err != nil {
+			slog.Error("Failed to backfill auth providers", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("Marked existing Supabase users", "count", n)
+	}
 
 	// --- 3b. Seed Default Plans ---
 	featuregate.SeedDefaultPlans(db)
 
+	passwordHasher := password.NewHasher(cfg.Password)
+	var breachFilter *password.BreachFilter
+	if cfg.Password.BreachFilter != "" {
+		breachFilter, err = password.LoadBreachFilter(cfg.Password.BreachFilter)
+		if err != nil {
+			slog.Error("Failed to load breached password filter", "path", cfg.Password.BreachFilter, "error", err)
+			os.Exit(1)
+		}
+		slog.Info("Breached password filter loaded", "path", cfg.Password.BreachFilter)
+	}
+
 	// --- 3c. Seed Dev Users (non-production only) ---
 	if strings.ToLower(cfg.App.Environment) != "production" {
-		database.SeedDevUsers(db)
+		database.SeedDevUsers(db, passwordHasher)
 	}
 
 	// --- 4. Repositories ---
 	userRepo := user.NewRepository(db)
 	orgRepo := org.NewRepository(db)
 	projectRepo := project.NewRepository(db)
-	billingRepo := billing.NewRepository(db)
+	billingRepo := billing.NewRepository(db, cfg.Billing)
 
 	// --- 5. Services ---
-	authService := auth.NewService(&cfg.JWT, db) // creates its own refresh token repo
-	userService := user.NewService(userRepo)
+	keyring, err := auth.NewKeyring(&cfg.JWT, db)
+	if err != nil {
+		slog.Error("Failed to initialize JWT keyring", "error", err)
+		os.Exit(1)
+	}
+	auditService := audit.NewService(db)
+	emailService := email.NewResendProvider(cfg.Email.APIKey, cfg.Email.FromEmail)
+	alertService := alert.NewService(db, emailService, auditService, cfg.App.Name, cfg.Email.AppURL)
+	authService := auth.NewService(&cfg.JWT, db, keyring, alertService) // creates its own refresh token repo
+
+	// Cookies are always Secure in production
+	isSecure := strings.ToLower(cfg.App.Environment) == "production"
+	sessionTransport := auth.NewSessionTransport(cfg.Session, &cfg.JWT, isSecure)
 	orgService := org.NewService(orgRepo)
 	projectService := project.NewService(projectRepo)
 	billingService := billing.NewService(billingRepo)
-	gateService := featuregate.NewGateService(db)
+	gateService := featuregate.NewGateService(db, cfg.Billing)
+	mfaService := mfa.NewService(db, cfg.App.Name, cfg.MFA.EncryptionKey, alertService)
+	if cfg.MFA.EncryptionKey == "" {
+		slog.Warn("MFA_ENCRYPTION_KEY is not set — TOTP secrets are stored unencrypted")
+	} else if n, err := mfaService.EncryptStoredSecrets(context.Background()); err != nil {
+		slog.Error("Failed to encrypt stored TOTP secrets", "error", err)
+		os.Exit(1)
+	} else if n > 0 {
+		slog.Info("Encrypted stored TOTP secrets", "count", n)
+	}
+	serviceAccountService := serviceaccount.NewService(db, authService, auditService)
 
 	// --- 5b. Email Service ---
-	emailService := email.NewResendProvider(cfg.Email.APIKey, cfg.Email.FromEmail)
-	verificationService := user.NewVerificationService(db, emailService, cfg.App.Name, cfg.Email.AppURL)
+	verificationService := user.NewVerificationService(db, emailService, passwordHasher, breachFilter, alertService, cfg.App.Name, cfg.Email.AppURL)
+	lockoutService := user.NewLockoutService(db, emailService, cfg.App.Name, cfg.Email.AppURL)
+	userService := user.NewService(userRepo, lockoutService, passwordHasher, breachFilter)
 
 	// --- 5c. Storage Service ---
 	var uploadService *storage.UploadService
@@ -126,39 +203,123 @@ func main() {
 	}
 
 	// --- 5d. OAuth Providers ---
-	oauthProviders := make(map[string]oauth.Provider)
-	baseURL := fmt.Sprintf("http://localhost:%d", cfg.Server.Port)
+	var oauthProviders []oauth.Provider
+	baseURL := fmt.Sprintf("http://localhost:%s", cfg.Server.Port)
 	if cfg.App.Environment == "production" {
 		baseURL = cfg.OAuth.FrontendURL // use the frontend URL for production redirect URIs
 	}
-	if cfg.OAuth.Google.Enabled {
-		oauthProviders["google"] = oauth.NewGoogleProvider(cfg.OAuth.Google, baseURL)
-		slog.Info("OAuth provider enabled", "provider", "google")
+	for _, providerCfg := range cfg.OAuth.EnabledProviders() {
+		provider, err := oauth.NewProvider(context.Background(), providerCfg, baseURL)
+		if err != nil {
+			slog.Error("Failed to initialize OAuth provider", "provider", providerCfg.Name, "error", err)
+			continue
+		}
+		oauthProviders = append(oauthProviders, provider)
+		slog.Info("OAuth provider enabled", "provider", provider.Name(), "type", providerCfg.Type)
 	}
-	if cfg.OAuth.GitHub.Enabled {
-		oauthProviders["github"] = oauth.NewGitHubProvider(cfg.OAuth.GitHub, baseURL)
-		slog.Info("OAuth provider enabled", "provider", "github")
+	if len(oauthProviders) > 0 && cfg.OAuth.StateSecret == "" {
+		slog.Warn("OAUTH_STATE_SECRET is not set — OAuth logins only work with a single API instance and fail across restarts")
 	}
-	oauthService := oauth.NewOAuthService(db)
-	oauthHandler := oauth.NewHandler(oauthProviders, oauthService, authService, cfg.OAuth.FrontendURL)
-
-	// --- 5e. Auth Provider Selection ---
-	var authProvider authprovider.AuthProvider
-	if cfg.Supabase.Enabled {
-		authProvider = authprovider.NewSupabaseProvider(cfg.Supabase)
-		slog.Info("Auth provider: supabase", "url", cfg.Supabase.URL)
+	oauthService := oauth.NewOAuthService(db, alertService)
+	oauthHandler := oauth.NewHandler(oauthProviders, oauthService, authService, mfaService, sessionTransport, cfg.OAuth.FrontendURL, cfg.OAuth.StateSecret)
+
+	// --- 5e. Passkeys (WebAuthn) ---
+	var passkeyHandler *passkey.Handler
+	if cfg.WebAuthn.RPID != "" {
+		passkeyService, err := passkey.NewService(db, cfg.WebAuthn, alertService)
+		if err != nil {
+			slog.Error("Failed to initialize passkeys", "error", err)
+			os.Exit(1)
+		}
+		passkeyHandler = passkey.NewHandler(passkeyService, authService, sessionTransport)
+		slog.Info("Passkeys enabled", "rp_id", cfg.WebAuthn.RPID)
 	} else {
-		authProvider = authprovider.NewLocalProvider(authService, userService)
-		slog.Info("Auth provider: local")
+		slog.Warn("WebAuthn relying party not configured — passkeys disabled")
 	}
 
+	// --- 5f. OpenID Connect Provider ---
+	var idpHandler *idp.Handler
+	if cfg.IDP.Enabled && keyring.Algorithm() != "HS256" {
+		issuer := cfg.IDP.Issuer
+		if issuer == "" {
+			issuer = cfg.JWT.Issuer
+		}
+		if issuer == "" {
+			issuer = fmt.Sprintf("http://localhost:%s", cfg.Server.Port)
+		}
+		consentURL := cfg.IDP.ConsentURL
+		if consentURL == "" {
+			consentURL = cfg.OAuth.FrontendURL + "/oauth2/consent"
+		}
+		idpProvider := idp.NewProvider(db, keyring, issuer, consentURL)
+		idpHandler = idp.NewHandler(idp.NewService(db, auditService), idpProvider)
+		slog.Info("OpenID Connect provider enabled", "issuer", issuer)
+	} else if cfg.IDP.Enabled {
+		// Clients cannot verify ID tokens without a published public key
+		slog.Warn("OpenID Connect provider requires an asymmetric JWT algorithm — disabled", "algorithm", keyring.Algorithm())
+	}
+
+	// --- 5g. Auth Provider Selection ---
+	// External access tokens can outlive ours, so keep logouts for a day
+	externalRevocations := auth.NewRevocationStore(db, 24*time.Hour)
+	newAuthProvider := func(name string) authprovider.AuthProvider {
+		switch name {
+		case "supabase":
+			return authprovider.NewSupabaseProvider(cfg.Supabase, externalRevocations)
+		case "oidc":
+			oidcProvider, err := authprovider.NewOIDCProvider(context.Background(), cfg.OIDC, db, externalRevocations)
+			if err != nil {
+				slog.Error("Failed to initialize OIDC auth provider", "error", err)
+				os.Exit(1)
+			}
+			return oidcProvider
+		case "ldap":
+			ldapProvider, err := authprovider.NewLDAPProvider(cfg.LDAP, db, authService, mfaService)
+			if err != nil {
+				slog.Error("Failed to initialize LDAP auth provider", "error", err)
+				os.Exit(1)
+			}
+			return ldapProvider
+		default:
+			return authprovider.NewLocalProvider(authService, userService, mfaService)
+		}
+	}
+	authProvider := newAuthProvider(cfg.AuthProvider())
+	slog.Info("Auth provider: " + cfg.AuthProvider())
+	if cfg.AuthMigration.From != "" {
+		// Sessions of the previous provider stay valid while users move over
+		authProvider = authprovider.NewCompositeProvider(db, authProvider, newAuthProvider(cfg.AuthMigration.From))
+		slog.Info("Auth provider migration enabled", "from", cfg.AuthMigration.From, "to", cfg.AuthProvider())
+	}
+	if name := cfg.AuthProvider(); name == "supabase" || name == "oidc" {
+		// Magic link, OAuth, passkey and MFA logins, re-authentication,
+		// impersonation and service accounts still issue local tokens
+		authProvider = authprovider.WithLocalSessions(authProvider, authprovider.NewLocalProvider(authService, userService, mfaService), cfg.JWT.Issuer)
+	}
+
+	// Personal access tokens are accepted alongside whichever provider issues sessions
+	patService := pat.NewService(db)
+	tokenValidator := pat.NewValidator(authProvider, patService)
+
 	// --- 6. Handlers ---
-	authHandler := auth.NewHandler(authProvider)
+	authHandler := auth.NewHandler(authProvider, sessionTransport)
+	keysHandler := auth.NewKeysHandler(keyring)
+	sessionHandler := auth.NewSessionHandler(authService)
+	reauthHandler := auth.NewReauthHandler(authService, userService, mfaService, sessionTransport)
+	mfaHandler := mfa.NewHandler(mfaService, authService, sessionTransport)
+	patHandler := pat.NewHandler(patService)
+	serviceAccountHandler := serviceaccount.NewHandler(serviceAccountService)
+	auditHandler := audit.NewHandler(auditService)
+	alertHandler := alert.NewHandler(alertService, authService)
+	accountHandler := account.NewHandler(account.NewService(db, authService, auditService, uploadService))
+	impersonationHandler := impersonation.NewHandler(impersonation.NewService(db, authService, auditService))
 	userHandler := user.NewHandler(userService)
 	orgHandler := org.NewHandler(orgService)
 	projectHandler := project.NewHandler(projectService)
 	billingHandler := billing.NewHandler(billingService, cfg.Xendit.WebhookToken)
 	verificationHandler := user.NewVerificationHandler(verificationService)
+	lockoutHandler := user.NewLockoutHandler(lockoutService)
+	magicLinkHandler := user.NewMagicLinkHandler(verificationService, authService, mfaService, sessionTransport)
 	uploadHandler := storage.NewHandler(uploadService)
 
 	// --- 7. Gin Router ---
@@ -169,6 +330,7 @@ func main() {
 	r := gin.New()
 	r.Use(middleware.Recovery())
 	r.Use(middleware.RequestID())
+	r.Use(middleware.ClientInfo())
 	r.Use(middleware.Logger())
 	r.Use(apiErrors.ErrorHandler())
 
@@ -199,8 +361,8 @@ func main() {
 	))
 
 	// CSRF (double-submit cookie, secure in production)
-	isSecure := strings.ToLower(cfg.App.Environment) == "production"
-	r.Use(middleware.CSRFProtection(isSecure))
+	// OAuth 2.0 protocol endpoints are called by third-party clients
+	r.Use(middleware.CSRFProtection(sessionTransport.Secure(), cfg.Session.CookieDomain, cfg.Session.SameSiteMode(), "/oauth2/"))
 
 	// --- 8. Health Checks ---
 	r.GET("/healthz", func(c *gin.Context) {
@@ -221,45 +383,124 @@ func main() {
 		})
 	})
 
+	authLimiter := middleware.NewRateLimiter(5, 15*time.Minute) // 5 requests per 15 min per IP
+	tokenLimiter := middleware.NewRateLimiter(60, time.Minute)  // machine clients, 60 per min per IP
+
+	// Public signing keys for verifying access tokens
+	r.GET("/.well-known/jwks.json", keysHandler.JWKS)
+
+	// OpenID Connect provider for apps hosted on the platform
+	if idpHandler != nil {
+		r.GET("/.well-known/openid-configuration", idpHandler.Discovery)
+		oauth2Group := r.Group("/oauth2")
+		{
+			oauth2Group.GET("/authorize", idpHandler.Authorize)
+			oauth2Group.POST("/token", middleware.RateLimit(tokenLimiter), idpHandler.Token)
+			oauth2Group.GET("/userinfo", idpHandler.UserInfo)
+			oauth2Group.POST("/userinfo", idpHandler.UserInfo)
+		}
+	}
+
 	// --- 9. API v1 Routes ---
 	v1 := r.Group("/api/v1")
 
 	// Auth routes (public, rate-limited)
 	authGroup := v1.Group("/auth")
-	authLimiter := middleware.NewRateLimiter(5, 15*time.Minute) // 5 requests per 15 min per IP
 	{
 		authGroup.POST("/register", middleware.RateLimit(authLimiter), authHandler.Register)
 		authGroup.POST("/login", middleware.RateLimit(authLimiter), authHandler.Login)
 		authGroup.POST("/refresh", authHandler.Refresh)
+		authGroup.POST("/token", middleware.RateLimit(tokenLimiter), serviceAccountHandler.Token)
+		authGroup.POST("/mfa/verify", middleware.RateLimit(authLimiter), mfaHandler.Verify)
 		authGroup.POST("/verify-email", verificationHandler.VerifyEmail)
+		authGroup.POST("/email-change/confirm", middleware.RateLimit(authLimiter), verificationHandler.ConfirmEmailChange)
+		authGroup.POST("/email-change/cancel", middleware.RateLimit(authLimiter), verificationHandler.CancelEmailChange)
 		authGroup.POST("/request-reset", middleware.RateLimit(authLimiter), verificationHandler.RequestPasswordReset)
 		authGroup.POST("/reset-password", middleware.RateLimit(authLimiter), verificationHandler.ResetPassword)
+		authGroup.POST("/unlock", middleware.RateLimit(authLimiter), lockoutHandler.UnlockAccount)
+		authGroup.POST("/security/revoke", middleware.RateLimit(authLimiter), alertHandler.RevokeSessions)
+		authGroup.POST("/magic-link", middleware.RateLimit(authLimiter), magicLinkHandler.RequestMagicLink)
+		authGroup.POST("/magic-link/verify", middleware.RateLimit(authLimiter), magicLinkHandler.RedeemMagicLink)
+		authGroup.GET("/oauth/providers", oauthHandler.ListProviders)
 		authGroup.GET("/oauth/:provider", oauthHandler.Initiate)
 		authGroup.GET("/oauth/:provider/callback", oauthHandler.Callback)
+		if passkeyHandler != nil {
+			authGroup.POST("/passkey/begin", passkeyHandler.BeginLogin)
+			authGroup.POST("/passkey/finish", middleware.RateLimit(authLimiter), passkeyHandler.FinishLogin)
+		}
 	}
 
 	// Public billing plans
 	v1.GET("/billing/plans", billingHandler.ListPlans)
 
+	// Destructive and credential-changing routes are off limits to support
+	// staff impersonating a user
+	noImpersonation := middleware.BlockImpersonation()
+
+	// Irreversible or secret-revealing routes need a recent sign-in, even
+	// with a valid access token
+	reauthMaxAge := cfg.Session.ReauthMaxAge
+	if reauthMaxAge == 0 {
+		reauthMaxAge = 10 * time.Minute
+	}
+	recentAuth := middleware.RequireRecentAuth(reauthMaxAge)
+
 	// Authenticated routes
 	authed := v1.Group("")
-	authed.Use(middleware.JWTAuth(authProvider))
+	authed.Use(middleware.JWTAuth(tokenValidator), middleware.RejectScopedTokens(), impersonation.AuditTrail(auditService))
 	{
 		// Auth (requires token)
 		authed.POST("/auth/logout", authHandler.Logout)
+		authed.POST("/auth/impersonation/end", impersonationHandler.End)
+		authed.POST("/auth/reauthenticate", middleware.RateLimit(authLimiter), noImpersonation, reauthHandler.Reauthenticate)
+		authed.POST("/auth/reauthenticate/oauth/:provider", noImpersonation, oauthHandler.BeginReauth)
 
 		// Users
 		authed.GET("/users/me", userHandler.GetMe)
-		authed.PUT("/users/me", userHandler.UpdateMe)
+		authed.PUT("/users/me", noImpersonation, userHandler.UpdateMe)
+		authed.POST("/users/me/email", noImpersonation, recentAuth, verificationHandler.RequestEmailChange)
+		authed.DELETE("/users/me", noImpersonation, recentAuth, accountHandler.RequestDeletion)
+		authed.GET("/users/me/deletion", accountHandler.DeletionStatus)
+		authed.POST("/users/me/deletion/cancel", noImpersonation, accountHandler.CancelDeletion)
+		authed.GET("/users/me/export", noImpersonation, recentAuth, accountHandler.Export)
 		authed.POST("/users/me/avatar", uploadHandler.UploadUserAvatar)
 		authed.GET("/users/me/oauth-accounts", oauthHandler.GetLinkedAccounts)
-		authed.DELETE("/users/me/oauth-accounts/:provider", oauthHandler.UnlinkAccount)
+		authed.DELETE("/users/me/oauth-accounts/:provider", noImpersonation, recentAuth, oauthHandler.UnlinkAccount)
+		authed.GET("/users/me/sessions", sessionHandler.ListSessions)
+		authed.DELETE("/users/me/sessions/:id", noImpersonation, sessionHandler.RevokeSession)
+		authed.GET("/users/me/mfa", mfaHandler.Status)
+		authed.POST("/users/me/mfa/totp", noImpersonation, mfaHandler.Enroll)
+		authed.POST("/users/me/mfa/totp/confirm", noImpersonation, mfaHandler.ConfirmEnrollment)
+		authed.POST("/users/me/mfa/disable", noImpersonation, mfaHandler.Disable)
+		authed.POST("/users/me/mfa/recovery-codes", noImpersonation, mfaHandler.RegenerateRecoveryCodes)
+		if idpHandler != nil {
+			authed.GET("/oauth2/consent", idpHandler.ConsentInfo)
+			authed.POST("/oauth2/consent", noImpersonation, idpHandler.Consent)
+		}
+		if passkeyHandler != nil {
+			authed.GET("/users/me/passkeys", passkeyHandler.ListCredentials)
+			authed.POST("/users/me/passkeys/register/begin", noImpersonation, passkeyHandler.BeginRegistration)
+			authed.POST("/users/me/passkeys/register/finish", noImpersonation, passkeyHandler.FinishRegistration)
+			authed.DELETE("/users/me/passkeys/:id", noImpersonation, passkeyHandler.DeleteCredential)
+		}
+		authed.GET("/users/me/tokens", patHandler.ListTokens)
+		authed.POST("/users/me/tokens", noImpersonation, patHandler.CreateToken)
+		authed.DELETE("/users/me/tokens/:id", noImpersonation, patHandler.RevokeToken)
 
 		// Admin-only user listing
 		admin := authed.Group("")
 		admin.Use(middleware.RequireRole("super_admin", "admin"))
 		{
 			admin.GET("/users", userHandler.ListUsers)
+			admin.POST("/users/:id/unlock", lockoutHandler.AdminUnlock)
+		}
+
+		// Support impersonation and the platform audit trail
+		superAdmin := authed.Group("")
+		superAdmin.Use(middleware.RequireRole(model.RoleSuperAdmin))
+		{
+			superAdmin.POST("/users/:id/impersonate", noImpersonation, impersonationHandler.Start)
+			superAdmin.GET("/audit-logs", auditHandler.ListGlobal)
 		}
 
 		// Orgs (top-level, no org context needed)
@@ -267,7 +508,7 @@ func main() {
 		authed.GET("/orgs", orgHandler.ListOrgs)
 
 		// Invite acceptance (by token, no org context needed)
-		authed.POST("/invites/:token/accept", orgHandler.AcceptInvite)
+		authed.POST("/invites/:token/accept", noImpersonation, orgHandler.AcceptInvite)
 
 		// Org-scoped routes
 		orgs := authed.Group("/orgs/:orgId")
@@ -276,54 +517,87 @@ func main() {
 			// Org management
 			orgs.GET("", orgHandler.GetOrg)
 			orgs.PUT("", orgHandler.UpdateOrg)
-			orgs.DELETE("", orgHandler.DeleteOrg)
+			orgs.DELETE("", noImpersonation, recentAuth, orgHandler.DeleteOrg)
 			orgs.POST("/avatar", uploadHandler.UploadOrgAvatar)
 
 			// Members
 			orgs.GET("/members", orgHandler.ListMembers)
-			orgs.PUT("/members/:memberId", orgHandler.UpdateMemberRole)
-			orgs.DELETE("/members/:memberId", orgHandler.RemoveMember)
+			orgs.PUT("/members/:memberId", noImpersonation, orgHandler.UpdateMemberRole)
+			orgs.DELETE("/members/:memberId", noImpersonation, orgHandler.RemoveMember)
 
 			// Invites
 			orgs.POST("/invites", featuregate.RequireQuota(gateService, "members"), orgHandler.InviteMember)
 			orgs.GET("/invites", orgHandler.ListInvites)
 			orgs.DELETE("/invites/:inviteId", orgHandler.RevokeInvite)
 
-			// Projects
-			orgs.POST("/projects", featuregate.RequireQuota(gateService, "projects"), projectHandler.CreateProject)
-			orgs.GET("/projects", projectHandler.ListProjects)
-			orgs.GET("/projects/:projectId", projectHandler.GetProject)
-			orgs.PUT("/projects/:projectId", projectHandler.UpdateProject)
-			orgs.DELETE("/projects/:projectId", projectHandler.DeleteProject)
-
-			// Deployments
-			orgs.POST("/projects/:projectId/deployments", featuregate.RequireQuota(gateService, "deployments"), projectHandler.CreateDeployment)
-			orgs.GET("/projects/:projectId/deployments", projectHandler.ListDeployments)
-
-			// Env Vars
-			orgs.POST("/projects/:projectId/env", projectHandler.SetEnvVar)
-			orgs.GET("/projects/:projectId/env", projectHandler.ListEnvVars)
-			orgs.DELETE("/projects/:projectId/env/:envVarId", projectHandler.DeleteEnvVar)
+			// Service accounts, OIDC clients & audit trail (admins only)
+			orgAdmin := orgs.Group("")
+			orgAdmin.Use(middleware.RequireOrgRole(model.RoleAdmin))
+			{
+				orgAdmin.GET("/service-accounts", serviceAccountHandler.List)
+				orgAdmin.POST("/service-accounts", noImpersonation, serviceAccountHandler.Create)
+				orgAdmin.GET("/service-accounts/:accountId", serviceAccountHandler.Get)
+				orgAdmin.PUT("/service-accounts/:accountId", noImpersonation, serviceAccountHandler.Update)
+				orgAdmin.DELETE("/service-accounts/:accountId", noImpersonation, serviceAccountHandler.Delete)
+				orgAdmin.POST("/service-accounts/:accountId/secret", noImpersonation, serviceAccountHandler.RotateSecret)
+				orgAdmin.GET("/audit-logs", auditHandler.List)
+				if idpHandler != nil {
+					orgAdmin.GET("/oidc-clients", idpHandler.ListClients)
+					orgAdmin.POST("/oidc-clients", noImpersonation, idpHandler.CreateClient)
+					orgAdmin.GET("/oidc-clients/:clientId", idpHandler.GetClient)
+					orgAdmin.PUT("/oidc-clients/:clientId", noImpersonation, idpHandler.UpdateClient)
+					orgAdmin.DELETE("/oidc-clients/:clientId", noImpersonation, idpHandler.DeleteClient)
+					orgAdmin.POST("/oidc-clients/:clientId/secret", noImpersonation, idpHandler.RotateClientSecret)
+				}
+			}
 
 			// Billing
 			orgs.GET("/billing", billingHandler.GetBillingOverview)
-			orgs.POST("/billing/subscribe", billingHandler.CreateSubscription)
-			orgs.POST("/billing/cancel", billingHandler.CancelSubscription)
+			orgs.POST("/billing/subscribe", noImpersonation, billingHandler.CreateSubscription)
+			orgs.POST("/billing/cancel", noImpersonation, recentAuth, billingHandler.CancelSubscription)
 			orgs.GET("/billing/invoices", billingHandler.ListInvoices)
 			orgs.GET("/billing/usage", billingHandler.GetUsage)
 		}
 	}
 
+	// Org-scoped routes that also accept personal access tokens (CI, scripts).
+	// Every route here must declare the scope it needs.
+	scoped := v1.Group("/orgs/:orgId")
+	scoped.Use(middleware.JWTAuth(tokenValidator), middleware.OrgResolver(db), impersonation.AuditTrail(auditService))
+	{
+		// Projects
+		scoped.POST("/projects", middleware.RequireScope(pat.ScopeProjectsWrite), featuregate.RequireQuota(gateService, "projects"), projectHandler.CreateProject)
+		scoped.GET("/projects", middleware.RequireScope(pat.ScopeProjectsRead), projectHandler.ListProjects)
+		scoped.GET("/projects/:projectId", middleware.RequireScope(pat.ScopeProjectsRead), projectHandler.GetProject)
+		scoped.PUT("/projects/:projectId", middleware.RequireScope(pat.ScopeProjectsWrite), projectHandler.UpdateProject)
+		scoped.DELETE("/projects/:projectId", middleware.RequireScope(pat.ScopeProjectsWrite), noImpersonation, projectHandler.DeleteProject)
+
+		// Deployments
+		scoped.POST("/projects/:projectId/deployments", middleware.RequireScope(pat.ScopeDeploymentsWrite), featuregate.RequireQuota(gateService, "deployments"), projectHandler.CreateDeployment)
+		scoped.GET("/projects/:projectId/deployments", middleware.RequireScope(pat.ScopeDeploymentsRead), projectHandler.ListDeployments)
+
+		// Env Vars
+		scoped.POST("/projects/:projectId/env", middleware.RequireScope(pat.ScopeEnvWrite), projectHandler.SetEnvVar)
+		scoped.GET("/projects/:projectId/env", middleware.RequireScope(pat.ScopeEnvRead), projectHandler.ListEnvVars)
+		scoped.GET("/projects/:projectId/env/:envVarId/reveal", middleware.RequireScope(pat.ScopeEnvRead), recentAuth, projectHandler.RevealEnvVar)
+		scoped.DELETE("/projects/:projectId/env/:envVarId", middleware.RequireScope(pat.ScopeEnvWrite), projectHandler.DeleteEnvVar)
+	}
+
 	// Webhooks (no auth, verified by signature)
 	webhooks := v1.Group("/webhooks")
 	{
 		webhooks.POST("/xendit", billingHandler.XenditWebhook)
 
-		// Supabase auth webhook (syncs auth.users → local users table)
-		if cfg.Supabase.Enabled {
-			webhookHandler := authprovider.NewWebhookHandler(db, cfg.Supabase.WebhookSecret)
-			webhooks.POST("/supabase/auth", webhookHandler.HandleAuthWebhook)
-			slog.Info("Supabase auth webhook registered at /api/v1/webhooks/supabase/auth")
+		// Supabase auth webhook (syncs auth.users → local users table). Events
+		// carry roles and bans, so the route only exists when they can be verified.
+		if cfg.Supabase.Enabled || cfg.AuthMigration.From == "supabase" {
+			if cfg.Supabase.WebhookSecret == "" {
+				slog.Warn("Supabase auth webhook not registered: SUPABASE_WEBHOOK_SECRET is not set")
+			} else {
+				webhookHandler := authprovider.NewWebhookHandler(db, authprovider.NewSupabaseUserSync(db, externalRevocations), cfg.Supabase.WebhookSecret)
+				webhooks.POST("/supabase/auth", webhookHandler.HandleAuthWebhook)
+				slog.Info("Supabase auth webhook registered at /api/v1/webhooks/supabase/auth")
+			}
 		}
 	}
 
@@ -0,0 +1,68 @@
+// Command supabasesync reconciles the local users table with Supabase
+// auth.users. It pages through the GoTrue admin API and fixes drift the auth
+// webhook missed: email, name, verification, roles and bans. Users deleted
+// from Supabase are deleted locally.
+//
+// It reads the same configuration as the server and needs SUPABASE_URL and
+// SUPABASE_SERVICE_KEY.
+//
+//	go run ./cmd/supabasesync -dry-run
+//	go run ./cmd/supabasesync -per-page 500
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"paas-core/apps/api/internal/auth"
+	"paas-core/apps/api/internal/authprovider"
+	"paas-core/apps/api/internal/config"
+	"paas-core/apps/api/internal/database"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report drift without fixing it")
+	perPage := flag.Int("per-page", 100, "users fetched per admin API request")
+	flag.Parse()
+
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		fail(err)
+	}
+	if cfg.Supabase.URL == "" || cfg.Supabase.ServiceKey == "" {
+		fail(fmt.Errorf("SUPABASE_URL and SUPABASE_SERVICE_KEY are required"))
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: cfg.Logging.GetLogLevel()})))
+
+	db, err := database.NewPostgresDB(cfg.Database)
+	if err != nil {
+		fail(err)
+	}
+	defer database.Close(db)
+
+	provider := authprovider.NewSupabaseProvider(cfg.Supabase, nil)
+	sync := authprovider.NewSupabaseUserSync(db, auth.NewRevocationStore(db, 24*time.Hour))
+
+	result, err := sync.Reconcile(context.Background(), provider, *perPage, *dryRun)
+	if result != nil {
+		verb := "Fixed"
+		if *dryRun {
+			verb = "Would fix"
+		}
+		fmt.Printf("Checked %d users. %s: %d created, %d updated, %d deleted\n",
+			result.Checked, verb, result.Created, result.Updated, result.Deleted)
+	}
+	if err != nil {
+		database.Close(db)
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "supabasesync:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,84 @@
+package account
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeletionResponse describes a scheduled account deletion.
+type DeletionResponse struct {
+	ID          uuid.UUID `json:"id"`
+	RequestedAt time.Time `json:"requested_at"`
+	PurgeAt     time.Time `json:"purge_at"` // the account is deleted after this time unless cancelled
+}
+
+// BlockingOrg is an org the user is the only owner of. Deletion is blocked
+// until ownership is transferred or the org is deleted.
+type BlockingOrg struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	Slug string    `json:"slug"`
+}
+
+// DeletionStatusResponse tells the user whether their account is scheduled
+// for deletion and which orgs would block a new request.
+type DeletionStatusResponse struct {
+	Deletion     *DeletionResponse `json:"deletion"` // nil when no deletion is scheduled
+	BlockingOrgs []BlockingOrg     `json:"blocking_orgs"`
+}
+
+// ExportResponse points at a downloadable personal data archive.
+type ExportResponse struct {
+	DownloadURL string    `json:"download_url"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// --- Archive contents ---
+
+type exportProfile struct {
+	ID            uuid.UUID `json:"id"`
+	Name          string    `json:"name"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	AvatarURL     string    `json:"avatar_url,omitempty"`
+	HasPassword   bool      `json:"has_password"`
+	Roles         []string  `json:"roles"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type exportMembership struct {
+	OrgID    uuid.UUID `json:"org_id"`
+	OrgName  string    `json:"org_name"`
+	OrgSlug  string    `json:"org_slug"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+type exportOAuthAccount struct {
+	Provider  string    `json:"provider"`
+	Email     string    `json:"email"`
+	AvatarURL string    `json:"avatar_url,omitempty"`
+	LinkedAt  time.Time `json:"linked_at"`
+}
+
+type exportSession struct {
+	ID         uuid.UUID  `json:"id"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type exportAuditEntry struct {
+	ID        uuid.UUID  `json:"id"`
+	OrgID     *uuid.UUID `json:"org_id,omitempty"`
+	ActorID   uuid.UUID  `json:"actor_id"`
+	Action    string     `json:"action"`
+	Resource  string     `json:"resource"`
+	Details   any        `json:"details,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,168 @@
+package account
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	apiErrors "paas-core/apps/api/internal/errors"
+	"paas-core/apps/api/internal/model"
+)
+
+// exportLinkExpiry is how long the download link of an export stays valid.
+const exportLinkExpiry = 15 * time.Minute
+
+// Export packages the user's personal data into a zip archive of JSON files,
+// stores it and returns a short-lived download link. The archive is deleted
+// by the next sweep after the link expires.
+func (s *Service) Export(ctx context.Context, userID uuid.UUID) (*ExportResponse, error) {
+	if s.uploads == nil {
+		return nil, &apiErrors.APIError{
+			StatusCode: http.StatusServiceUnavailable,
+			Code:       "STORAGE_UNAVAILABLE",
+			Message:    "Data export is not available because file storage is not configured",
+		}
+	}
+
+	files, err := s.collect(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"profile.json", "memberships.json", "oauth_accounts.json", "sessions.json", "audit_log.json"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, apiErrors.InternalServerError(err)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(files[name]); err != nil {
+			return nil, apiErrors.InternalServerError(fmt.Errorf("failed to encode %s: %w", name, err))
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	now := time.Now()
+	filename := fmt.Sprintf("account-export-%s.zip", now.UTC().Format("20060102-150405"))
+	url, err := s.uploads.UploadExport(ctx, userID, filename, buf.Bytes(), exportLinkExpiry)
+	if err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	s.audit.RecordGlobal(ctx, userID, ActionDataExported, resourceName(userID), map[string]any{"size": buf.Len()})
+	return &ExportResponse{DownloadURL: url, ExpiresAt: now.Add(exportLinkExpiry)}, nil
+}
+
+// collect gathers the archive contents, keyed by file name.
+func (s *Service) collect(ctx context.Context, userID uuid.UUID) (map[string]any, error) {
+	db := s.db.WithContext(ctx)
+
+	var user model.User
+	if err := db.Preload("Roles").First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apiErrors.NotFound("User not found")
+		}
+		return nil, apiErrors.InternalServerError(err)
+	}
+	roles := make([]string, len(user.Roles))
+	for i, r := range user.Roles {
+		roles[i] = r.Name
+	}
+
+	var memberships []model.Membership
+	if err := db.Preload("Org").Where("user_id = ?", userID).Order("joined_at ASC").Find(&memberships).Error; err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+	exportedMemberships := make([]exportMembership, len(memberships))
+	for i, m := range memberships {
+		exportedMemberships[i] = exportMembership{
+			OrgID:    m.OrgID,
+			OrgName:  m.Org.Name,
+			OrgSlug:  m.Org.Slug,
+			Role:     m.Role,
+			JoinedAt: m.JoinedAt,
+		}
+	}
+
+	var accounts []model.OAuthAccount
+	if err := db.Where("user_id = ?", userID).Order("created_at ASC").Find(&accounts).Error; err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+	exportedAccounts := make([]exportOAuthAccount, len(accounts))
+	for i, a := range accounts {
+		exportedAccounts[i] = exportOAuthAccount{
+			Provider:  a.Provider,
+			Email:     a.Email,
+			AvatarURL: a.AvatarURL,
+			LinkedAt:  a.CreatedAt,
+		}
+	}
+
+	var sessions []model.UserSession
+	if err := db.Where("user_id = ?", userID).Order("created_at ASC").Find(&sessions).Error; err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+	exportedSessions := make([]exportSession, len(sessions))
+	for i, sess := range sessions {
+		exportedSessions[i] = exportSession{
+			ID:         sess.ID,
+			UserAgent:  sess.UserAgent,
+			IPAddress:  sess.IPAddress,
+			CreatedAt:  sess.CreatedAt,
+			LastUsedAt: sess.LastUsedAt,
+			ExpiresAt:  sess.ExpiresAt,
+			RevokedAt:  sess.RevokedAt,
+		}
+	}
+
+	// What the user did, and what others did to their account, such as
+	// impersonating them
+	var logs []model.AuditLog
+	if err := db.Where("actor_id = ? OR resource = ?", userID, resourceName(userID)).Order("created_at ASC").Find(&logs).Error; err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+	exportedLogs := make([]exportAuditEntry, len(logs))
+	for i, l := range logs {
+		exportedLogs[i] = exportAuditEntry{
+			ID:        l.ID,
+			OrgID:     l.OrgID,
+			ActorID:   l.ActorID,
+			Action:    l.Action,
+			Resource:  l.Resource,
+			CreatedAt: l.CreatedAt,
+		}
+		if l.Details != "" {
+			exportedLogs[i].Details = json.RawMessage(l.Details)
+		}
+	}
+
+	return map[string]any{
+		"profile.json": exportProfile{
+			ID:            user.ID,
+			Name:          user.Name,
+			Email:         user.Email,
+			EmailVerified: user.EmailVerified,
+			AvatarURL:     user.AvatarURL,
+			HasPassword:   user.HasPassword(),
+			Roles:         roles,
+			CreatedAt:     user.CreatedAt,
+			UpdatedAt:     user.UpdatedAt,
+		},
+		"memberships.json":    exportedMemberships,
+		"oauth_accounts.json": exportedAccounts,
+		"sessions.json":       exportedSessions,
+		"audit_log.json":      exportedLogs,
+	}, nil
+}
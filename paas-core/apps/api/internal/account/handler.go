@@ -0,0 +1,104 @@
+package account
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"paas-core/apps/api/internal/auth"
+	apiErrors "paas-core/apps/api/internal/errors"
+)
+
+// Handler serves account deletion and data export for the signed-in user.
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new account handler.
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RequestDeletion godoc
+// @Summary Delete my account
+// @Description Schedules the account for deletion after a 30-day grace period, during which it can be cancelled. Refused while you are the only owner of an org. Requires a recent sign-in.
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Success 202 {object} errors.Response{data=DeletionResponse}
+// @Failure 403 {object} errors.Response "Re-authentication required"
+// @Failure 409 {object} errors.Response "Only owner of an org"
+// @Router /api/v1/users/me [delete]
+func (h *Handler) RequestDeletion(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	deletion, err := h.service.RequestDeletion(c.Request.Context(), claims.UserID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, apiErrors.Success(deletion))
+}
+
+// DeletionStatus godoc
+// @Summary Get my account deletion status
+// @Description Returns the scheduled deletion, if any, and the orgs that block deletion because you are their only owner.
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} errors.Response{data=DeletionStatusResponse}
+// @Router /api/v1/users/me/deletion [get]
+func (h *Handler) DeletionStatus(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	status, err := h.service.DeletionStatus(c.Request.Context(), claims.UserID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(status))
+}
+
+// CancelDeletion godoc
+// @Summary Cancel my account deletion
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} errors.Response "Deletion cancelled"
+// @Failure 404 {object} errors.Response "No deletion scheduled"
+// @Router /api/v1/users/me/deletion/cancel [post]
+func (h *Handler) CancelDeletion(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	if err := h.service.CancelDeletion(c.Request.Context(), claims.UserID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(gin.H{"message": "Account deletion cancelled"}))
+}
+
+// Export godoc
+// @Summary Export my data
+// @Description Packages your profile, org memberships, linked OAuth accounts, sessions and audit log entries into a zip archive and returns a download link valid for 15 minutes. Requires a recent sign-in.
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} errors.Response{data=ExportResponse}
+// @Failure 403 {object} errors.Response "Re-authentication required"
+// @Failure 503 {object} errors.Response "Storage not configured"
+// @Router /api/v1/users/me/export [get]
+func (h *Handler) Export(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	export, err := h.service.Export(c.Request.Context(), claims.UserID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, apiErrors.Success(export))
+}
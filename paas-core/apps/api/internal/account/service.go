@@ -0,0 +1,291 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"paas-core/apps/api/internal/audit"
+	"paas-core/apps/api/internal/auth"
+	apiErrors "paas-core/apps/api/internal/errors"
+	"paas-core/apps/api/internal/model"
+	"paas-core/apps/api/internal/storage"
+)
+
+// Audit actions recorded for account deletion and export.
+const (
+	ActionDeletionRequested = "user.deletion_requested"
+	ActionDeletionCancelled = "user.deletion_cancelled"
+	ActionDeleted           = "user.deleted"
+	ActionDataExported      = "user.data_exported"
+)
+
+const (
+	// DeletionGracePeriod is how long a deletion request can be cancelled.
+	DeletionGracePeriod = 30 * 24 * time.Hour
+	purgeInterval       = time.Hour
+	purgeBatchSize      = 100
+)
+
+// userOwnedTables are removed when an account is purged. Audit logs are kept;
+// they reference the user by ID only, and the user row is anonymized.
+var userOwnedTables = []any{
+	&model.Membership{},
+	&model.UserRole{},
+	&model.RefreshToken{},
+	&model.UserSession{},
+	&model.EmailVerificationToken{},
+	&model.PasswordResetToken{},
+	&model.MagicLinkToken{},
+	&model.EmailChangeRequest{},
+	&model.AccountLockout{},
+	&model.AccountUnlockToken{},
+	&model.SecurityAlertToken{},
+	&model.PersonalAccessToken{},
+	&model.TOTPFactor{},
+	&model.RecoveryCode{},
+	&model.MFAChallenge{},
+	&model.OAuthAccount{},
+	&model.OAuthReauthState{},
+	&model.WebAuthnCredential{},
+	&model.WebAuthnSession{},
+	&model.OIDCAuthorizationCode{},
+	&model.OIDCConsent{},
+	&model.OIDCAccessToken{},
+}
+
+// Service handles self-service account deletion and personal data export.
+// Deletions are scheduled with a grace period and purged in the background.
+type Service struct {
+	db      *gorm.DB
+	auth    auth.Service
+	audit   *audit.Service
+	uploads *storage.UploadService // nil when storage is not configured
+}
+
+// NewService creates the account service and starts the background purge of
+// deletions whose grace period has ended.
+func NewService(db *gorm.DB, authService auth.Service, auditService *audit.Service, uploads *storage.UploadService) *Service {
+	s := &Service{db: db, auth: authService, audit: auditService, uploads: uploads}
+	go s.maintain()
+	return s
+}
+
+// RequestDeletion schedules the user's account for deletion. It is refused
+// while the user is the only owner of an org, which would otherwise be left
+// without anyone able to manage it.
+func (s *Service) RequestDeletion(ctx context.Context, userID uuid.UUID) (*DeletionResponse, error) {
+	blocking, err := s.soleOwnedOrgs(s.db.WithContext(ctx), userID)
+	if err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+	if len(blocking) > 0 {
+		names := make([]string, len(blocking))
+		for i, org := range blocking {
+			names[i] = org.Name
+		}
+		return nil, apiErrors.Conflict(fmt.Sprintf(
+			"You are the only owner of %s. Transfer ownership or delete the organization first.",
+			strings.Join(names, ", ")))
+	}
+
+	if pending, err := s.pending(ctx, userID); err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	} else if pending != nil {
+		return toDeletionResponse(pending), nil
+	}
+
+	record := &model.AccountDeletion{UserID: userID, PurgeAt: time.Now().Add(DeletionGracePeriod)}
+	if err := s.db.WithContext(ctx).Create(record).Error; err != nil {
+		return nil, apiErrors.InternalServerError(fmt.Errorf("failed to schedule deletion: %w", err))
+	}
+
+	s.audit.RecordGlobal(ctx, userID, ActionDeletionRequested, resourceName(userID), map[string]any{"purge_at": record.PurgeAt})
+	return toDeletionResponse(record), nil
+}
+
+// DeletionStatus returns the user's pending deletion, if any, and the orgs
+// that would block a new request.
+func (s *Service) DeletionStatus(ctx context.Context, userID uuid.UUID) (*DeletionStatusResponse, error) {
+	pending, err := s.pending(ctx, userID)
+	if err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+	blocking, err := s.soleOwnedOrgs(s.db.WithContext(ctx), userID)
+	if err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	resp := &DeletionStatusResponse{BlockingOrgs: blocking}
+	if pending != nil {
+		resp.Deletion = toDeletionResponse(pending)
+	}
+	return resp, nil
+}
+
+// CancelDeletion cancels the user's pending deletion.
+func (s *Service) CancelDeletion(ctx context.Context, userID uuid.UUID) error {
+	result := s.db.WithContext(ctx).Model(&model.AccountDeletion{}).
+		Where("user_id = ? AND cancelled_at IS NULL AND completed_at IS NULL", userID).
+		Update("cancelled_at", time.Now())
+	if result.Error != nil {
+		return apiErrors.InternalServerError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return apiErrors.NotFound("No account deletion is scheduled")
+	}
+
+	s.audit.RecordGlobal(ctx, userID, ActionDeletionCancelled, resourceName(userID), nil)
+	return nil
+}
+
+// maintain purges due deletions and expired data exports periodically.
+func (s *Service) maintain() {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.purgeDue(context.Background())
+		s.sweepExports(context.Background())
+	}
+}
+
+// sweepExports deletes export archives whose download link has expired, so
+// copies of personal data do not pile up in storage.
+func (s *Service) sweepExports(ctx context.Context) {
+	if s.uploads == nil {
+		return
+	}
+	n, err := s.uploads.DeleteExportsBefore(ctx, time.Now().Add(-exportLinkExpiry))
+	if err != nil {
+		slog.Error("Failed to delete expired exports", "error", err)
+	}
+	if n > 0 {
+		slog.Info("Deleted expired exports", "count", n)
+	}
+}
+
+// purgeDue purges a batch of deletions whose grace period has ended. Rows
+// are claimed with SKIP LOCKED so replicas do not purge the same account
+// twice.
+func (s *Service) purgeDue(ctx context.Context) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var due []model.AccountDeletion
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("purge_at <= ? AND cancelled_at IS NULL AND completed_at IS NULL", time.Now()).
+			Order("purge_at ASC").
+			Limit(purgeBatchSize).
+			Find(&due).Error; err != nil {
+			return err
+		}
+		for i := range due {
+			if err := s.purge(ctx, tx, &due[i]); err != nil {
+				slog.Error("Failed to purge account", "user_id", due[i].UserID, "error", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("Account purge failed", "error", err)
+	}
+}
+
+// purge deletes everything the user owns and anonymizes the user row, which
+// is kept so audit logs still resolve. A user who became the only owner of an
+// org during the grace period is skipped until that is resolved.
+func (s *Service) purge(ctx context.Context, tx *gorm.DB, deletion *model.AccountDeletion) error {
+	blocking, err := s.soleOwnedOrgs(tx, deletion.UserID)
+	if err != nil {
+		return err
+	}
+	if len(blocking) > 0 {
+		slog.Warn("Account deletion postponed: user is the only owner of an org", "user_id", deletion.UserID, "orgs", len(blocking))
+		return nil
+	}
+
+	// Sign the user out everywhere before the session rows disappear
+	if err := s.auth.RevokeAllUserTokens(ctx, deletion.UserID); err != nil {
+		return fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+
+	err = tx.Transaction(func(tx *gorm.DB) error {
+		for _, table := range userOwnedTables {
+			if err := tx.Unscoped().Where("user_id = ?", deletion.UserID).Delete(table).Error; err != nil {
+				return fmt.Errorf("failed to delete %T: %w", table, err)
+			}
+		}
+		if err := tx.Model(&model.User{}).Where("id = ?", deletion.UserID).Updates(map[string]any{
+			"name":           "Deleted user",
+			"email":          fmt.Sprintf("deleted-%s@deleted.invalid", deletion.UserID),
+			"password_hash":  "",
+			"avatar_url":     "",
+			"email_verified": false,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to anonymize user: %w", err)
+		}
+		if err := tx.Delete(&model.User{}, "id = ?", deletion.UserID).Error; err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		return tx.Model(deletion).Update("completed_at", time.Now()).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.uploads != nil {
+		if err := s.uploads.DeleteOwnerFiles(ctx, "user", deletion.UserID); err != nil {
+			slog.Error("Failed to delete files of purged account", "user_id", deletion.UserID, "error", err)
+		}
+	}
+
+	s.audit.RecordGlobal(ctx, deletion.UserID, ActionDeleted, resourceName(deletion.UserID), nil)
+	slog.Info("Account purged", "user_id", deletion.UserID)
+	return nil
+}
+
+func (s *Service) pending(ctx context.Context, userID uuid.UUID) (*model.AccountDeletion, error) {
+	var record model.AccountDeletion
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND cancelled_at IS NULL AND completed_at IS NULL", userID).
+		First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// soleOwnedOrgs returns the orgs where the user is the only human owner.
+func (s *Service) soleOwnedOrgs(db *gorm.DB, userID uuid.UUID) ([]BlockingOrg, error) {
+	otherOwners := db.Session(&gorm.Session{NewDB: true}).Model(&model.Membership{}).
+		Scopes(model.HumanMembers).
+		Select("1").
+		Where("memberships.org_id = m.org_id AND memberships.role = ? AND memberships.user_id <> m.user_id AND users.deleted_at IS NULL", model.RoleOwner)
+
+	orgs := []BlockingOrg{}
+	err := db.Table("memberships AS m").
+		Select("orgs.id, orgs.name, orgs.slug").
+		Joins("JOIN orgs ON orgs.id = m.org_id AND orgs.deleted_at IS NULL").
+		Where("m.user_id = ? AND m.role = ?", userID, model.RoleOwner).
+		Where("NOT EXISTS (?)", otherOwners).
+		Order("orgs.name ASC").
+		Scan(&orgs).Error
+	return orgs, err
+}
+
+func toDeletionResponse(d *model.AccountDeletion) *DeletionResponse {
+	return &DeletionResponse{ID: d.ID, RequestedAt: d.CreatedAt, PurgeAt: d.PurgeAt}
+}
+
+func resourceName(userID uuid.UUID) string {
+	return "user:" + userID.String()
+}
@@ -0,0 +1,60 @@
+package alert
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"paas-core/apps/api/internal/auth"
+	apiErrors "paas-core/apps/api/internal/errors"
+)
+
+// Handler handles the "this wasn't me" link of security alert emails.
+type Handler struct {
+	service     *Service
+	authService auth.Service
+}
+
+// NewHandler creates a new security alert handler.
+func NewHandler(service *Service, authService auth.Service) *Handler {
+	return &Handler{service: service, authService: authService}
+}
+
+// RevokeSessionsRequest is the DTO for the "this wasn't me" link.
+type RevokeSessionsRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// RevokeSessions godoc
+// @Summary Report a security alert as not me
+// @Description Sign the user out on every device with the token from a security alert email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body RevokeSessionsRequest true "Alert token"
+// @Success 200 {object} errors.Response "All sessions revoked"
+// @Failure 400 {object} errors.Response "Invalid or expired token"
+// @Router /api/v1/auth/security/revoke [post]
+func (h *Handler) RevokeSessions(c *gin.Context) {
+	var req RevokeSessionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	userID, err := h.service.Redeem(c.Request.Context(), req.Token)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if err := h.authService.SignOutEverywhere(c.Request.Context(), userID); err != nil {
+		_ = c.Error(apiErrors.InternalServerError(fmt.Errorf("failed to revoke sessions: %w", err)))
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(gin.H{
+		"message": "You have been signed out on all devices. Reset your password to secure your account.",
+	}))
+}
@@ -0,0 +1,214 @@
+package alert
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"paas-core/apps/api/internal/audit"
+	"paas-core/apps/api/internal/auth"
+	"paas-core/apps/api/internal/email"
+	apiErrors "paas-core/apps/api/internal/errors"
+	"paas-core/apps/api/internal/model"
+)
+
+// Security events the user is emailed about.
+const (
+	EventTokenReuse      = "token_reuse"
+	EventNewSignIn       = "new_sign_in"
+	EventPasswordChanged = "password_changed"
+	EventMFAChanged      = "mfa_changed"
+	EventOAuthLinked     = "oauth_linked"
+)
+
+// ActionReported is audited when a user follows the "this wasn't me" link.
+const ActionReported = "user.security_alert_reported"
+
+const (
+	revokeTokenExpiry = 7 * 24 * time.Hour
+	revokeTokenLength = 32
+)
+
+// Service emails the user about security-relevant account events. Every
+// alert carries a "this wasn't me" link that signs the user out everywhere.
+type Service struct {
+	db           *gorm.DB
+	emailService email.Service
+	audit        *audit.Service
+	appName      string
+	appURL       string
+}
+
+// NewService creates a new security alert service.
+func NewService(db *gorm.DB, emailService email.Service, auditService *audit.Service, appName, appURL string) *Service {
+	return &Service{
+		db:           db,
+		emailService: emailService,
+		audit:        auditService,
+		appName:      appName,
+		appURL:       appURL,
+	}
+}
+
+// TokenReused alerts the user that a rotated refresh token was presented
+// again, the usual sign of a stolen token.
+func (s *Service) TokenReused(ctx context.Context, userID uuid.UUID) {
+	s.notify(ctx, userID, EventTokenReuse, "", email.RenderTokenReuseAlertEmail)
+}
+
+// SessionStarted alerts the user when a new session comes from a device and
+// IP address pair none of their earlier sessions used. The very first
+// session of an account is not reported.
+func (s *Service) SessionStarted(ctx context.Context, userID, sessionID uuid.UUID) {
+	info := auth.ClientInfoFromContext(ctx)
+	db := s.db.WithContext(ctx).Model(&model.UserSession{}).Where("user_id = ? AND id <> ?", userID, sessionID)
+
+	var earlier, known int64
+	if err := db.Session(&gorm.Session{}).Count(&earlier).Error; err != nil {
+		slog.Error("Failed to check for a new device", "user_id", userID, "error", err)
+		return
+	}
+	if earlier == 0 {
+		return
+	}
+	if err := db.Where("user_agent = ? AND ip_address = ?", truncate(info.UserAgent, 512), info.IPAddress).Count(&known).Error; err != nil {
+		slog.Error("Failed to check for a new device", "user_id", userID, "error", err)
+		return
+	}
+	if known == 0 {
+		s.notify(ctx, userID, EventNewSignIn, "", email.RenderNewSignInAlertEmail)
+	}
+}
+
+// PasswordChanged alerts the user that their password was changed or reset.
+func (s *Service) PasswordChanged(ctx context.Context, userID uuid.UUID) {
+	s.notify(ctx, userID, EventPasswordChanged, "", email.RenderPasswordChangedAlertEmail)
+}
+
+// MFAChanged alerts the user that a second factor was added, removed or
+// replaced. detail is a sentence such as "Two-factor authentication was
+// turned off".
+func (s *Service) MFAChanged(ctx context.Context, userID uuid.UUID, detail string) {
+	s.notify(ctx, userID, EventMFAChanged, detail, email.RenderMFAChangedAlertEmail)
+}
+
+// OAuthLinked alerts the user that a sign-in provider was linked to their
+// account.
+func (s *Service) OAuthLinked(ctx context.Context, userID uuid.UUID, provider string) {
+	name := provider
+	if name != "" {
+		name = strings.ToUpper(name[:1]) + name[1:]
+	}
+	s.notify(ctx, userID, EventOAuthLinked, name, email.RenderOAuthLinkedAlertEmail)
+}
+
+// Redeem checks a "this wasn't me" token and returns the user it belongs to.
+// The token stays valid until it expires, since signing out everywhere twice
+// does no harm; the first use is recorded.
+func (s *Service) Redeem(ctx context.Context, rawToken string) (uuid.UUID, error) {
+	db := s.db.WithContext(ctx)
+
+	var token model.SecurityAlertToken
+	if err := db.Where("token_hash = ? AND expires_at > ?", hashToken(rawToken), time.Now()).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return uuid.Nil, apiErrors.BadRequest("Invalid or expired link")
+		}
+		return uuid.Nil, apiErrors.InternalServerError(err)
+	}
+
+	if token.UsedAt == nil {
+		if err := db.Model(&token).Update("used_at", time.Now()).Error; err != nil {
+			return uuid.Nil, apiErrors.InternalServerError(err)
+		}
+	}
+
+	s.audit.RecordGlobal(ctx, token.UserID, ActionReported, "user:"+token.UserID.String(), map[string]any{"event": token.Event})
+	return token.UserID, nil
+}
+
+// notify sends an alert in the background so a slow email provider never
+// delays the request that triggered it. Failures are logged.
+func (s *Service) notify(ctx context.Context, userID uuid.UUID, event, detail string, render func(email.TemplateData) email.Message) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := s.send(ctx, userID, event, detail, render); err != nil {
+			slog.Error("Failed to send security alert", "event", event, "user_id", userID, "error", err)
+		}
+	}()
+}
+
+func (s *Service) send(ctx context.Context, userID uuid.UUID, event, detail string, render func(email.TemplateData) email.Message) error {
+	db := s.db.WithContext(ctx)
+
+	var usr model.User
+	if err := db.First(&usr, "id = ?", userID).Error; err != nil {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+
+	rawToken, tokenHash, err := generateToken()
+	if err != nil {
+		return err
+	}
+	if err := db.Create(&model.SecurityAlertToken{
+		UserID:    userID,
+		Event:     event,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(revokeTokenExpiry),
+	}).Error; err != nil {
+		return fmt.Errorf("failed to store alert token: %w", err)
+	}
+
+	info := auth.ClientInfoFromContext(ctx)
+	return s.emailService.Send(ctx, render(email.TemplateData{
+		AppName:   s.appName,
+		AppURL:    s.appURL,
+		UserName:  usr.Name,
+		UserEmail: usr.Email,
+		Token:     rawToken,
+		Link:      fmt.Sprintf("%s/auth/security/revoke?token=%s", s.appURL, rawToken),
+		ExpiresIn: "7 days",
+		Device:    orUnknown(info.UserAgent),
+		IPAddress: orUnknown(info.IPAddress),
+		Detail:    detail,
+	}))
+}
+
+// generateToken returns a raw token for the email link and its SHA-256 hash
+// for storage.
+func generateToken() (rawToken, tokenHash string, err error) {
+	b := make([]byte, revokeTokenLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate alert token: %w", err)
+	}
+	rawToken = hex.EncodeToString(b)
+	return rawToken, hashToken(rawToken), nil
+}
+
+func hashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+// truncate matches how sessions store the user agent.
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
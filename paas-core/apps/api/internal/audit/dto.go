@@ -0,0 +1,18 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LogResponse is the public DTO for an audit event.
+type LogResponse struct {
+	ID        uuid.UUID       `json:"id"`
+	ActorID   uuid.UUID       `json:"actor_id"`
+	Action    string          `json:"action"`
+	Resource  string          `json:"resource"`
+	Details   json.RawMessage `json:"details,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
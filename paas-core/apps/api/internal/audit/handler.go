@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	apiErrors "paas-core/apps/api/internal/errors"
+)
+
+// Handler exposes the org and platform audit trails.
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new audit handler.
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// List godoc
+// @Summary List audit events
+// @Tags orgs
+// @Security BearerAuth
+// @Param orgId path string true "Organization ID"
+// @Param resource query string false "Filter by resource, e.g. service_account:{id}"
+// @Param limit query int false "Maximum number of events (default 50, max 200)"
+// @Success 200 {object} errors.Response{data=[]LogResponse}
+// @Router /api/v1/orgs/{orgId}/audit-logs [get]
+func (h *Handler) List(c *gin.Context) {
+	orgID := c.MustGet("org_id").(uuid.UUID)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	entries, err := h.service.List(c.Request.Context(), orgID, c.Query("resource"), limit)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(entries))
+}
+
+// ListGlobal godoc
+// @Summary List platform audit events (super admin only)
+// @Description Events that do not belong to an org, such as impersonation sessions
+// @Tags admin
+// @Security BearerAuth
+// @Param resource query string false "Filter by resource, e.g. user:{id}"
+// @Param limit query int false "Maximum number of events (default 50, max 200)"
+// @Success 200 {object} errors.Response{data=[]LogResponse}
+// @Router /api/v1/audit-logs [get]
+func (h *Handler) ListGlobal(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	entries, err := h.service.ListGlobal(c.Request.Context(), c.Query("resource"), limit)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(entries))
+}
@@ -0,0 +1,107 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	apiErrors "paas-core/apps/api/internal/errors"
+	"paas-core/apps/api/internal/model"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+)
+
+// Service records and lists org audit events.
+type Service struct {
+	db *gorm.DB
+}
+
+// NewService creates a new audit service.
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// Record stores an org audit event. Failures are logged rather than returned
+// so that auditing never breaks the action being audited.
+func (s *Service) Record(ctx context.Context, orgID, actorID uuid.UUID, action, resource string, details any) {
+	s.record(ctx, &orgID, actorID, action, resource, details)
+}
+
+// RecordGlobal stores a platform audit event that does not belong to an org,
+// such as an impersonation session.
+func (s *Service) RecordGlobal(ctx context.Context, actorID uuid.UUID, action, resource string, details any) {
+	s.record(ctx, nil, actorID, action, resource, details)
+}
+
+func (s *Service) record(ctx context.Context, orgID *uuid.UUID, actorID uuid.UUID, action, resource string, details any) {
+	payload := []byte("{}")
+	if details != nil {
+		b, err := json.Marshal(details)
+		if err != nil {
+			slog.Error("Failed to encode audit details", "action", action, "error", err)
+		} else {
+			payload = b
+		}
+	}
+
+	entry := &model.AuditLog{
+		OrgID:    orgID,
+		ActorID:  actorID,
+		Action:   action,
+		Resource: resource,
+		Details:  string(payload),
+	}
+	if err := s.db.WithContext(ctx).Create(entry).Error; err != nil {
+		slog.Error("Failed to record audit event", "action", action, "actor_id", actorID, "error", err)
+	}
+}
+
+// List returns the org's most recent audit events, optionally filtered by
+// resource.
+func (s *Service) List(ctx context.Context, orgID uuid.UUID, resource string, limit int) ([]LogResponse, error) {
+	return s.list(ctx, s.db.Where("org_id = ?", orgID), resource, limit)
+}
+
+// ListGlobal returns the most recent platform audit events, optionally
+// filtered by resource.
+func (s *Service) ListGlobal(ctx context.Context, resource string, limit int) ([]LogResponse, error) {
+	return s.list(ctx, s.db.Where("org_id IS NULL"), resource, limit)
+}
+
+func (s *Service) list(ctx context.Context, q *gorm.DB, resource string, limit int) ([]LogResponse, error) {
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	q = q.WithContext(ctx)
+	if resource != "" {
+		q = q.Where("resource = ?", resource)
+	}
+
+	var entries []model.AuditLog
+	if err := q.Order("created_at DESC").Limit(limit).Find(&entries).Error; err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	result := make([]LogResponse, len(entries))
+	for i, e := range entries {
+		result[i] = LogResponse{
+			ID:        e.ID,
+			ActorID:   e.ActorID,
+			Action:    e.Action,
+			Resource:  e.Resource,
+			Details:   json.RawMessage(e.Details),
+			CreatedAt: e.CreatedAt,
+		}
+	}
+	return result, nil
+}
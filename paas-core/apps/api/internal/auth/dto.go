@@ -5,21 +5,88 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+
+	"paas-core/apps/api/internal/model"
 )
 
 // Claims represents the JWT token claims.
 type Claims struct {
 	jwt.RegisteredClaims
-	UserID uuid.UUID `json:"user_id"`
-	Email  string    `json:"email"`
-	Name   string    `json:"name"`
-	Roles  []string  `json:"roles"`
+	UserID    uuid.UUID `json:"user_id"`
+	SessionID uuid.UUID `json:"sid"` // refresh token family / device session
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	Roles     []string  `json:"roles"`
+
+	// Scopes and OrgID are only set for personal access tokens and service
+	// account tokens. A nil Scopes means an interactive session with the
+	// user's full permissions.
+	Scopes []string   `json:"scopes,omitempty"`
+	OrgID  *uuid.UUID `json:"org_id,omitempty"`
+
+	// Actor is set on impersonation tokens and identifies the super admin
+	// acting as the user (the RFC 8693 "act" claim).
+	Actor *Actor `json:"act,omitempty"`
+
+	// AuthTime and AMR record when and how the user last proved their
+	// identity in this session. Refreshing keeps them; re-authenticating
+	// moves AuthTime forward. Scoped and impersonation tokens have neither.
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+	AMR      []string         `json:"amr,omitempty"`
+}
+
+// Authentication methods recorded in the "amr" claim. Values follow RFC 8176
+// where it defines one.
+const (
+	AMRPassword  = "pwd"
+	AMROTP       = "otp"
+	AMRMFA       = "mfa"
+	AMRPasskey   = "hwk"
+	AMRFederated = "fed"   // OAuth provider login
+	AMRMagicLink = "email" // emailed sign-in link
+)
+
+// Actor identifies the user behind an impersonation token.
+type Actor struct {
+	Subject string    `json:"sub"`
+	UserID  uuid.UUID `json:"user_id"`
+	Email   string    `json:"email"`
+}
+
+// IsImpersonated reports whether the claims come from an impersonation token.
+func (c *Claims) IsImpersonated() bool {
+	return c.Actor != nil
+}
+
+// AuthenticatedWithin reports whether the user proved their identity no more
+// than maxAge ago.
+func (c *Claims) AuthenticatedWithin(maxAge time.Duration) bool {
+	return c.AuthTime != nil && time.Since(c.AuthTime.Time) <= maxAge
+}
+
+// IsScoped reports whether the claims come from a scoped token.
+func (c *Claims) IsScoped() bool {
+	return c.Scopes != nil
+}
+
+// HasScope reports whether the claims grant scope. Interactive sessions have
+// every scope.
+func (c *Claims) HasScope(scope string) bool {
+	if !c.IsScoped() {
+		return true
+	}
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
 }
 
 // TokenPair holds an access + refresh token.
 type TokenPair struct {
-	AccessToken  string    `json:"access_token"`
-	RefreshToken string    `json:"refresh_token"`
+	AccessToken  string    `json:"access_token,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
 	TokenType    string    `json:"token_type"`
 	ExpiresIn    int64     `json:"expires_in"`
 	TokenFamily  uuid.UUID `json:"-"`
@@ -38,17 +105,29 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-// RefreshRequest is the DTO for token refresh.
+// RefreshRequest is the DTO for token refresh. In cookie mode the body may be
+// omitted and the refresh_token cookie is used instead.
 type RefreshRequest struct {
-	RefreshToken string `json:"refresh_token" binding:"required"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// ReauthenticateRequest is the DTO for step-up re-authentication. Exactly one
+// of Password or Code (TOTP or recovery code) must be given.
+type ReauthenticateRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
 }
 
-// AuthResponse is returned after successful authentication.
+// AuthResponse is returned after successful authentication. When the user has
+// MFA enabled, login returns only MFARequired and MFAToken; the token must be
+// exchanged at /auth/mfa/verify for the actual token pair.
 type AuthResponse struct {
-	AccessToken  string       `json:"access_token"`
-	RefreshToken string       `json:"refresh_token"`
-	TokenType    string       `json:"token_type"`
-	ExpiresIn    int64        `json:"expires_in"`
+	AccessToken  string       `json:"access_token,omitempty"`
+	RefreshToken string       `json:"refresh_token,omitempty"`
+	TokenType    string       `json:"token_type,omitempty"`
+	ExpiresIn    int64        `json:"expires_in,omitempty"`
+	MFARequired  bool         `json:"mfa_required,omitempty"`
+	MFAToken     string       `json:"mfa_token,omitempty"`
 	User         UserResponse `json:"user"`
 }
 
@@ -61,3 +140,22 @@ type UserResponse struct {
 	Roles     []string  `json:"roles"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// NewAuthResponse builds the login response for a user and a freshly issued
+// token pair.
+func NewAuthResponse(pair *TokenPair, u *model.User, roles []string) *AuthResponse {
+	return &AuthResponse{
+		AccessToken:  pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		TokenType:    pair.TokenType,
+		ExpiresIn:    pair.ExpiresIn,
+		User: UserResponse{
+			ID:        u.ID,
+			Name:      u.Name,
+			Email:     u.Email,
+			AvatarURL: u.AvatarURL,
+			Roles:     roles,
+			CreatedAt: u.CreatedAt,
+		},
+	}
+}
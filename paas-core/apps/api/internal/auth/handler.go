@@ -2,6 +2,8 @@ package auth
 
 import (
 	"context"
+	"errors"
+	"io"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -11,23 +13,30 @@ import (
 )
 
 // AuthProvider is a minimal interface that the auth handler needs.
-// Both the local and Supabase providers satisfy it.
+// Every provider in the authprovider package satisfies it.
 type AuthProvider interface {
 	Register(ctx context.Context, req RegisterRequest) (*AuthResponse, error)
 	Login(ctx context.Context, req LoginRequest) (*AuthResponse, error)
 	RefreshToken(ctx context.Context, refreshToken string) (*TokenPair, error)
-	Logout(ctx context.Context, userID uuid.UUID) error
+	Logout(ctx context.Context, claims *Claims) error
 	Name() string
 }
 
+// MFAChallenger issues second-factor challenges for users who enrolled MFA.
+type MFAChallenger interface {
+	IsEnabled(ctx context.Context, userID uuid.UUID) (bool, error)
+	CreateChallenge(ctx context.Context, userID uuid.UUID) (string, error)
+}
+
 // Handler handles authentication HTTP requests.
 type Handler struct {
-	provider AuthProvider
+	provider  AuthProvider
+	transport *SessionTransport
 }
 
 // NewHandler creates a new auth handler using the given provider.
-func NewHandler(provider AuthProvider) *Handler {
-	return &Handler{provider: provider}
+func NewHandler(provider AuthProvider, transport *SessionTransport) *Handler {
+	return &Handler{provider: provider, transport: transport}
 }
 
 // Register godoc
@@ -54,7 +63,7 @@ func (h *Handler) Register(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, apiErrors.Success(authResp))
+	h.transport.WriteAuthResponse(c, authResp)
 }
 
 // Login godoc
@@ -80,38 +89,48 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, apiErrors.Success(authResp))
+	h.transport.WriteAuthResponse(c, authResp)
 }
 
 // Refresh godoc
 // @Summary Refresh access token
-// @Description Exchange a valid refresh token for a new token pair
+// @Description Exchange a valid refresh token for a new token pair. In cookie mode the refresh_token cookie is used when the body is empty.
 // @Tags auth
 // @Accept json
 // @Produce json
-// @Param request body RefreshRequest true "Refresh request"
+// @Param request body RefreshRequest false "Refresh request"
 // @Success 200 {object} errors.Response{data=TokenPair} "Success"
 // @Failure 401 {object} errors.Response "Invalid or expired refresh token"
 // @Router /api/v1/auth/refresh [post]
 func (h *Handler) Refresh(c *gin.Context) {
 	var req RefreshRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
 		_ = c.Error(apiErrors.FromGinValidation(err))
 		return
 	}
 
-	tokenPair, err := h.provider.RefreshToken(c.Request.Context(), req.RefreshToken)
+	refreshToken := req.RefreshToken
+	if refreshToken == "" {
+		refreshToken = h.transport.RefreshToken(c)
+	}
+	if refreshToken == "" {
+		_ = c.Error(apiErrors.Unauthorized("Missing refresh token"))
+		return
+	}
+
+	tokenPair, err := h.provider.RefreshToken(c.Request.Context(), refreshToken)
 	if err != nil {
+		h.transport.ClearCookies(c)
 		_ = c.Error(apiErrors.Unauthorized(err.Error()))
 		return
 	}
 
-	c.JSON(http.StatusOK, apiErrors.Success(tokenPair))
+	h.transport.WriteTokenPair(c, tokenPair)
 }
 
 // Logout godoc
 // @Summary Logout user
-// @Description Revoke all refresh tokens for the authenticated user
+// @Description Revoke all refresh tokens for the authenticated user and clear the session cookies
 // @Tags auth
 // @Security BearerAuth
 // @Success 200 {object} errors.Response "Success"
@@ -124,10 +143,12 @@ func (h *Handler) Logout(c *gin.Context) {
 	}
 	authClaims := claims.(*Claims)
 
-	if err := h.provider.Logout(c.Request.Context(), authClaims.UserID); err != nil {
+	if err := h.provider.Logout(c.Request.Context(), authClaims); err != nil {
 		_ = c.Error(apiErrors.InternalServerError(err))
 		return
 	}
 
+	h.transport.ClearCookies(c)
+
 	c.JSON(http.StatusOK, apiErrors.Success(gin.H{"message": "Logged out successfully"}))
 }
@@ -0,0 +1,504 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+
+	"paas-core/apps/api/internal/config"
+	"paas-core/apps/api/internal/model"
+)
+
+// Signing key statuses stored in model.SigningKey.Status.
+const (
+	KeyStatusNext     = "next"     // published ahead of signing, see nextKeyLead
+	KeyStatusActive   = "active"   // signs new tokens
+	KeyStatusPrevious = "previous" // verifies tokens until RetireAt
+	KeyStatusRetired  = "retired"  // no longer trusted
+)
+
+// nextKeyLead is how long a scheduled successor key is published in the JWKS
+// before it starts signing, so verifiers holding a cached JWKS already know
+// it. It covers the JWKS cache lifetime plus a rotation check.
+const nextKeyLead = jwksMaxAge + 2*time.Minute
+
+// keyringLockID is the Postgres advisory lock that serialises rotation across
+// API replicas.
+const keyringLockID = 728_314_001
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Keyring holds the JWT signing keys. The active key signs new tokens; the
+// next, active and previous keys are all published in the JWKS and accepted
+// when validating, so a rotation never invalidates tokens that are still
+// live and a new key is known to verifiers before its first token. Private
+// keys are encrypted at rest when a key encryption key is configured.
+//
+// With algorithm HS256 the keyring falls back to the shared JWTConfig.Secret
+// and publishes an empty JWKS.
+type Keyring struct {
+	db               *gorm.DB
+	algorithm        string
+	secret           []byte
+	rotationInterval time.Duration
+	previousKeyTTL   time.Duration
+	sealer           *keySealer
+
+	mu          sync.RWMutex
+	active      *signingKey
+	keys        map[string]*signingKey
+	lastRefresh time.Time
+}
+
+type signingKey struct {
+	kid       string
+	method    jwt.SigningMethod
+	private   crypto.Signer
+	public    crypto.PublicKey
+	createdAt time.Time
+}
+
+// NewKeyring loads the signing keys from the database, generating the first
+// key if none exists, and starts the background rotation loop.
+func NewKeyring(cfg *config.JWTConfig, db *gorm.DB) (*Keyring, error) {
+	algorithm := cfg.Algorithm
+	if algorithm == "" {
+		algorithm = "RS256"
+	}
+
+	rotationInterval := cfg.RotationInterval
+	if rotationInterval == 0 {
+		rotationInterval = 30 * 24 * time.Hour
+	}
+
+	previousKeyTTL := cfg.PreviousKeyTTL
+	if previousKeyTTL == 0 {
+		previousKeyTTL = 24 * time.Hour
+	}
+	if previousKeyTTL < cfg.AccessTokenTTL {
+		// A rotated-out key must outlive every token it signed.
+		previousKeyTTL = cfg.AccessTokenTTL
+	}
+
+	k := &Keyring{
+		db:               db,
+		algorithm:        algorithm,
+		rotationInterval: rotationInterval,
+		previousKeyTTL:   previousKeyTTL,
+		sealer:           newKeySealer(cfg.KeyEncryptionKey),
+		keys:             make(map[string]*signingKey),
+	}
+
+	if algorithm == jwt.SigningMethodHS256.Alg() {
+		secret := cfg.Secret
+		if secret == "" {
+			secret = "default-secret-change-in-production"
+		}
+		k.secret = []byte(secret)
+		return k, nil
+	}
+
+	ctx := context.Background()
+	if err := k.sealStoredKeys(ctx); err != nil {
+		return nil, fmt.Errorf("failed to encrypt stored signing keys: %w", err)
+	}
+	if err := k.rotate(ctx, false); err != nil {
+		return nil, fmt.Errorf("failed to initialise signing keys: %w", err)
+	}
+	if err := k.refresh(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load signing keys: %w", err)
+	}
+
+	go k.maintain()
+
+	return k, nil
+}
+
+// Algorithm returns the JWS algorithm used for newly signed tokens.
+func (k *Keyring) Algorithm() string {
+	return k.algorithm
+}
+
+// Sign signs the claims with the active key and sets the "kid" header.
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	if k.secret != nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(k.secret)
+	}
+
+	k.mu.RLock()
+	key := k.active
+	k.mu.RUnlock()
+	if key == nil {
+		return "", ErrUnknownKey
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.private)
+}
+
+// Keyfunc resolves the verification key for a parsed token by its "kid"
+// header. It is meant to be passed to jwt.Parse.
+func (k *Keyring) Keyfunc(t *jwt.Token) (interface{}, error) {
+	if k.secret != nil {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return k.secret, nil
+	}
+
+	kid, _ := t.Header["kid"].(string)
+	key := k.lookup(kid)
+	if key == nil {
+		return nil, ErrUnknownKey
+	}
+	if t.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+	}
+	return key.public, nil
+}
+
+// JWKS returns the public keys that are currently trusted.
+func (k *Keyring) JWKS() JWKS {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := JWKS{Keys: make([]JWK, 0, len(k.keys))}
+	for _, key := range k.keys {
+		set.Keys = append(set.Keys, newJWK(key.kid, key.method.Alg(), key.public))
+	}
+	return set
+}
+
+// Rotate generates a new active key immediately, for when the current one may
+// be compromised. Unlike scheduled rotations it is not published ahead, so
+// verifiers with a cached JWKS refetch it on the first unknown kid. The
+// current active key is demoted to previous and keeps verifying tokens for
+// the configured TTL.
+func (k *Keyring) Rotate(ctx context.Context) error {
+	if k.secret != nil {
+		return errors.New("key rotation is not supported with HS256")
+	}
+	if err := k.rotate(ctx, true); err != nil {
+		return err
+	}
+	return k.refresh(ctx)
+}
+
+// lookup returns the key for kid, reloading from the database once if it is
+// unknown (another replica may have rotated).
+func (k *Keyring) lookup(kid string) *signingKey {
+	k.mu.RLock()
+	key := k.keys[kid]
+	stale := time.Since(k.lastRefresh) > 10*time.Second
+	k.mu.RUnlock()
+
+	if key != nil || kid == "" || !stale {
+		return key
+	}
+
+	if err := k.refresh(context.Background()); err != nil {
+		slog.Error("failed to reload signing keys", "error", err)
+		return nil
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.keys[kid]
+}
+
+// maintain periodically rotates and reloads keys.
+func (k *Keyring) maintain() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx := context.Background()
+		if err := k.rotate(ctx, false); err != nil {
+			slog.Error("signing key rotation failed", "error", err)
+		}
+		if err := k.refresh(ctx); err != nil {
+			slog.Error("failed to reload signing keys", "error", err)
+		}
+	}
+}
+
+// rotate retires expired previous keys and keeps the active key fresh. A
+// scheduled rotation first publishes the successor as the next key and only
+// promotes it once it has been in the JWKS for nextKeyLead; the first key
+// and forced rotations sign at once. It holds an advisory lock so concurrent
+// replicas do not rotate twice.
+func (k *Keyring) rotate(ctx context.Context, force bool) error {
+	return k.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", keyringLockID).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+
+		err := tx.Model(&model.SigningKey{}).
+			Where("status = ? AND retire_at < ?", KeyStatusPrevious, now).
+			Updates(map[string]interface{}{"status": KeyStatusRetired, "private_key": ""}).Error
+		if err != nil {
+			return err
+		}
+
+		var current model.SigningKey
+		err = tx.Where("status = ?", KeyStatusActive).Order("created_at DESC").First(&current).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		hasActive := err == nil
+
+		var next model.SigningKey
+		err = tx.Where("status = ?", KeyStatusNext).Order("created_at DESC").First(&next).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		hasNext := err == nil
+		if hasNext && next.Algorithm != k.algorithm {
+			// Published for an algorithm that is no longer configured
+			if err := tx.Delete(&model.SigningKey{}, "id = ?", next.ID).Error; err != nil {
+				return err
+			}
+			hasNext = false
+		}
+
+		demote := func() error {
+			if !hasActive {
+				return nil
+			}
+			retireAt := now.Add(k.previousKeyTTL)
+			return tx.Model(&model.SigningKey{}).
+				Where("status = ?", KeyStatusActive).
+				Updates(map[string]interface{}{"status": KeyStatusPrevious, "rotated_at": now, "retire_at": retireAt}).Error
+		}
+
+		if !hasActive || force {
+			key, err := k.generate(KeyStatusActive)
+			if err != nil {
+				return err
+			}
+			if err := demote(); err != nil {
+				return err
+			}
+			if err := tx.Create(key).Error; err != nil {
+				return err
+			}
+			slog.Info("Rotated JWT signing key", "kid", key.ID, "algorithm", key.Algorithm)
+			return nil
+		}
+
+		// Switching algorithms in config also triggers a rotation.
+		age := now.Sub(current.CreatedAt)
+		due := current.Algorithm != k.algorithm || age >= k.rotationInterval
+
+		if !hasNext {
+			if !due && age < k.rotationInterval-nextKeyLead {
+				return nil
+			}
+			key, err := k.generate(KeyStatusNext)
+			if err != nil {
+				return err
+			}
+			if err := tx.Create(key).Error; err != nil {
+				return err
+			}
+			slog.Info("Published next JWT signing key", "kid", key.ID, "algorithm", key.Algorithm)
+			return nil
+		}
+
+		if !due || now.Sub(next.CreatedAt) < nextKeyLead {
+			return nil
+		}
+		if err := demote(); err != nil {
+			return err
+		}
+		if err := tx.Model(&model.SigningKey{}).Where("id = ?", next.ID).
+			Update("status", KeyStatusActive).Error; err != nil {
+			return err
+		}
+		slog.Info("Rotated JWT signing key", "kid", next.ID, "algorithm", next.Algorithm)
+		return nil
+	})
+}
+
+// generate creates a key for the configured algorithm with its private key
+// sealed for storage.
+func (k *Keyring) generate(status string) (*model.SigningKey, error) {
+	key, err := generateSigningKey(k.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	key.Status = status
+	key.PrivateKey, err = k.sealer.seal(key.ID, key.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// sealStoredKeys encrypts private keys that were stored in plaintext, before
+// a key encryption key was configured.
+func (k *Keyring) sealStoredKeys(ctx context.Context) error {
+	if k.sealer.aead == nil {
+		return nil
+	}
+
+	var rows []model.SigningKey
+	if err := k.db.WithContext(ctx).
+		Where("private_key <> '' AND private_key NOT LIKE ?", sealedKeyPrefix+"%").
+		Find(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		sealed, err := k.sealer.seal(row.ID, row.PrivateKey)
+		if err != nil {
+			return err
+		}
+		// Conditional so a key retired meanwhile is left alone.
+		if err := k.db.WithContext(ctx).Model(&model.SigningKey{}).
+			Where("id = ? AND private_key = ?", row.ID, row.PrivateKey).
+			Update("private_key", sealed).Error; err != nil {
+			return err
+		}
+	}
+	if len(rows) > 0 {
+		slog.Info("Encrypted stored JWT signing keys", "count", len(rows))
+	}
+	return nil
+}
+
+// refresh reloads the next, active and previous keys from the database.
+func (k *Keyring) refresh(ctx context.Context) error {
+	var rows []model.SigningKey
+	err := k.db.WithContext(ctx).
+		Where("status IN ?", []string{KeyStatusNext, KeyStatusActive, KeyStatusPrevious}).
+		Order("created_at DESC").
+		Find(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*signingKey, len(rows))
+	var active *signingKey
+	for i := range rows {
+		key, err := k.parseSigningKey(&rows[i])
+		if err != nil {
+			return fmt.Errorf("signing key %s: %w", rows[i].ID, err)
+		}
+		keys[key.kid] = key
+		if rows[i].Status == KeyStatusActive && (active == nil || key.createdAt.After(active.createdAt)) {
+			active = key
+		}
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	if active != nil {
+		k.active = active
+	}
+	k.lastRefresh = time.Now()
+	k.mu.Unlock()
+
+	return nil
+}
+
+func generateSigningKey(algorithm string) (*model.SigningKey, error) {
+	var private crypto.Signer
+	switch algorithm {
+	case "RS256":
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		private = key
+	case "EdDSA":
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		private = key
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	// kid is derived from the public key so it is stable and collision-free.
+	sum := sha256.Sum256(pubDER)
+
+	return &model.SigningKey{
+		ID:         base64.RawURLEncoding.EncodeToString(sum[:16]),
+		Algorithm:  algorithm,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER})),
+		PublicKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})),
+		Status:     KeyStatusActive,
+	}, nil
+}
+
+func (k *Keyring) parseSigningKey(row *model.SigningKey) (*signingKey, error) {
+	method := jwt.GetSigningMethod(row.Algorithm)
+	if method == nil {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", row.Algorithm)
+	}
+
+	block, _ := pem.Decode([]byte(row.PublicKey))
+	if block == nil {
+		return nil, errors.New("invalid public key PEM")
+	}
+	public, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{
+		kid:       row.ID,
+		method:    method,
+		public:    public,
+		createdAt: row.CreatedAt,
+	}
+
+	if row.Status == KeyStatusActive {
+		privatePEM, err := k.sealer.open(row.ID, row.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		block, _ := pem.Decode([]byte(privatePEM))
+		if block == nil {
+			return nil, errors.New("invalid private key PEM")
+		}
+		private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := private.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("private key cannot sign")
+		}
+		key.private = signer
+	}
+
+	return key, nil
+}
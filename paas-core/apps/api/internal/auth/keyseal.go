@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// sealedKeyPrefix marks encrypted private signing keys. PEM starts with
+// dashes, so keys stored before encryption was enabled are told apart.
+const sealedKeyPrefix = "v1:"
+
+var errKeyEncryptionKeyMissing = errors.New("signing key is encrypted but no JWT key encryption key is configured")
+
+// keySealer encrypts private signing keys at rest with AES-256-GCM. Each
+// ciphertext is bound to its kid, so a key copied onto another row does not
+// open.
+type keySealer struct {
+	aead cipher.AEAD // nil when no key is configured; keys stay in plaintext
+}
+
+// newKeySealer derives the encryption key from key. An empty key disables
+// encryption, which is only allowed outside production.
+func newKeySealer(key string) *keySealer {
+	if key == "" {
+		return &keySealer{}
+	}
+	derived := sha256.Sum256([]byte("jwt-signing-key:" + key))
+	block, err := aes.NewCipher(derived[:])
+	if err != nil {
+		panic(err) // unreachable with a 32-byte key
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return &keySealer{aead: aead}
+}
+
+// seal encrypts a PEM private key for storage.
+func (s *keySealer) seal(kid, privatePEM string) (string, error) {
+	if s.aead == nil {
+		return privatePEM, nil
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to encrypt signing key: %w", err)
+	}
+	sealed := s.aead.Seal(nonce, nonce, []byte(privatePEM), []byte(kid))
+	return sealedKeyPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// open returns the PEM private key of a stored value. Values written before
+// encryption was enabled are returned as they are.
+func (s *keySealer) open(kid, stored string) (string, error) {
+	encoded, ok := strings.CutPrefix(stored, sealedKeyPrefix)
+	if !ok {
+		return stored, nil
+	}
+	if s.aead == nil {
+		return "", errKeyEncryptionKeyMissing
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	nonceSize := s.aead.NonceSize()
+	if err != nil || len(sealed) < nonceSize {
+		return "", errors.New("malformed signing key")
+	}
+	privatePEM, err := s.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(kid))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt signing key: %w", err)
+	}
+	return string(privatePEM), nil
+}
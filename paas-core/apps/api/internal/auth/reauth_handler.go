@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	apiErrors "paas-core/apps/api/internal/errors"
+)
+
+// PasswordVerifier checks the password of a signed-in user.
+type PasswordVerifier interface {
+	VerifyPassword(ctx context.Context, userID uuid.UUID, password string) error
+}
+
+// FactorVerifier checks a TOTP or recovery code of a signed-in user.
+type FactorVerifier interface {
+	VerifyFactor(ctx context.Context, userID uuid.UUID, code string) error
+}
+
+// ReauthHandler lets a signed-in user prove their identity again before a
+// sensitive operation. OAuth re-login is handled by the OAuth handler.
+type ReauthHandler struct {
+	service   Service
+	passwords PasswordVerifier
+	factors   FactorVerifier
+	transport *SessionTransport
+}
+
+// NewReauthHandler creates a new re-authentication handler.
+func NewReauthHandler(service Service, passwords PasswordVerifier, factors FactorVerifier, transport *SessionTransport) *ReauthHandler {
+	return &ReauthHandler{service: service, passwords: passwords, factors: factors, transport: transport}
+}
+
+// Reauthenticate godoc
+// @Summary Re-authenticate the current session
+// @Description Confirms the user's identity with their password or a TOTP/recovery code and returns a new access token with a fresh auth_time. Call this when an endpoint responds with REAUTH_REQUIRED, then retry.
+// @Tags auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body ReauthenticateRequest true "Password or verification code"
+// @Success 200 {object} errors.Response{data=TokenPair}
+// @Failure 400 {object} errors.Response "Invalid verification code"
+// @Failure 401 {object} errors.Response "Invalid password"
+// @Router /api/v1/auth/reauthenticate [post]
+func (h *ReauthHandler) Reauthenticate(c *gin.Context) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		_ = c.Error(apiErrors.Unauthorized(""))
+		return
+	}
+	authClaims := claims.(*Claims)
+
+	// Sessions of an external provider (Supabase, OIDC) carry the provider's
+	// authentication time; signing in there again refreshes it
+	if authClaims.SessionID == uuid.Nil {
+		_ = c.Error(apiErrors.BadRequest("Re-authentication requires an interactive session; sign in again instead"))
+		return
+	}
+
+	var req ReauthenticateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+	if (req.Password == "") == (req.Code == "") {
+		_ = c.Error(apiErrors.BadRequest("Provide either your password or a verification code"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	var amr []string
+	if req.Password != "" {
+		if err := h.passwords.VerifyPassword(ctx, authClaims.UserID, req.Password); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		amr = []string{AMRPassword}
+	} else {
+		if err := h.factors.VerifyFactor(ctx, authClaims.UserID, req.Code); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		amr = []string{AMROTP}
+	}
+
+	pair, err := h.service.Reauthenticate(ctx, authClaims.UserID, authClaims.SessionID, amr)
+	if err != nil {
+		if errors.Is(err, ErrNoSession) {
+			_ = c.Error(apiErrors.Unauthorized("Session has been revoked"))
+			return
+		}
+		if errors.Is(err, ErrSuspended) {
+			_ = c.Error(apiErrors.Forbidden("Your account is suspended"))
+			return
+		}
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	h.transport.WriteTokenPair(c, pair)
+}
@@ -75,3 +75,97 @@ func (r *refreshTokenRepository) DeleteExpired(ctx context.Context) error {
 		Where("expires_at < ?", time.Now()).
 		Delete(&model.RefreshToken{}).Error
 }
+
+// SessionRepository defines the storage interface for device sessions.
+type SessionRepository interface {
+	Touch(ctx context.Context, session *model.UserSession) error
+	FindActive(ctx context.Context, userID, sessionID uuid.UUID) (*model.UserSession, error)
+	Reauthenticate(ctx context.Context, userID, sessionID uuid.UUID, authTime time.Time, amr string) (bool, error)
+	ListActive(ctx context.Context, userID uuid.UUID) ([]model.UserSession, error)
+	Revoke(ctx context.Context, userID, sessionID uuid.UUID) (bool, error)
+	RevokeAllForUser(ctx context.Context, userID uuid.UUID) error
+}
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository creates a new session repository backed by GORM.
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+// Touch records a login or refresh for the session, creating it if needed.
+func (r *sessionRepository) Touch(ctx context.Context, session *model.UserSession) error {
+	res := r.db.WithContext(ctx).
+		Model(&model.UserSession{}).
+		Where("id = ?", session.ID).
+		Updates(map[string]interface{}{
+			"user_agent":   session.UserAgent,
+			"ip_address":   session.IPAddress,
+			"last_used_at": session.LastUsedAt,
+			"expires_at":   session.ExpiresAt,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+// FindActive returns the session, or nil if it does not exist, belongs to
+// another user, or has been revoked.
+func (r *sessionRepository) FindActive(ctx context.Context, userID, sessionID uuid.UUID) (*model.UserSession, error) {
+	var session model.UserSession
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Reauthenticate records a fresh proof of identity for an active session. It
+// reports false if the session is not active.
+func (r *sessionRepository) Reauthenticate(ctx context.Context, userID, sessionID uuid.UUID, authTime time.Time, amr string) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&model.UserSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Updates(map[string]interface{}{
+			"auth_time": authTime,
+			"amr":       amr,
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *sessionRepository) ListActive(ctx context.Context, userID uuid.UUID) ([]model.UserSession, error) {
+	var sessions []model.UserSession
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Revoke marks a single session as revoked. It reports false if the session
+// does not exist, belongs to another user, or is already revoked.
+func (r *sessionRepository) Revoke(ctx context.Context, userID, sessionID uuid.UUID) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&model.UserSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("revoked_at", time.Now())
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *sessionRepository) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Model(&model.UserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
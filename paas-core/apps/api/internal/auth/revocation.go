@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"paas-core/apps/api/internal/model"
+)
+
+const (
+	// revocationSyncInterval bounds how long a revocation made on another
+	// instance can take to be enforced here.
+	revocationSyncInterval = 10 * time.Second
+	// revocationSyncOverlap re-reads a little history on every sync so rows
+	// committed late or written with a skewed clock are not missed.
+	revocationSyncOverlap  = 30 * time.Second
+	revocationCleanupEvery = time.Hour
+
+	// sessionKeyPrefix marks denylist rows that revoke a whole device session
+	// rather than a single jti.
+	sessionKeyPrefix = "sid:"
+)
+
+// RevocationStore tracks revoked access tokens: single tokens by jti, every
+// token of a signed-out device session by sid, and all of a user's tokens
+// issued before a watermark. Lookups are served from
+// memory; the cache is kept in sync with Postgres in the background so the
+// request path never queries the database.
+type RevocationStore struct {
+	db  *gorm.DB
+	ttl time.Duration // access token TTL; older entries cannot match a live token
+
+	mu         sync.RWMutex
+	jtis       map[string]time.Time    // jti -> token expiry
+	sessions   map[uuid.UUID]time.Time // sid -> expiry of the last token it may have issued
+	watermarks map[uuid.UUID]time.Time // user -> tokens issued at or before are revoked
+	syncedAt   time.Time
+}
+
+// NewRevocationStore loads current revocations and starts the background sync.
+func NewRevocationStore(db *gorm.DB, accessTokenTTL time.Duration) *RevocationStore {
+	r := &RevocationStore{
+		db:         db,
+		ttl:        accessTokenTTL,
+		jtis:       make(map[string]time.Time),
+		sessions:   make(map[uuid.UUID]time.Time),
+		watermarks: make(map[uuid.UUID]time.Time),
+	}
+	if err := r.sync(context.Background()); err != nil {
+		slog.Error("Failed to load token revocations", "error", err)
+	}
+	go r.maintain()
+	return r
+}
+
+// IsRevoked reports whether the access token described by claims has been
+// revoked, either individually, with its session or by the user's watermark.
+func (r *RevocationStore) IsRevoked(claims *Claims) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.jtis[claims.ID]; ok && claims.ID != "" {
+		return true
+	}
+	if _, ok := r.sessions[claims.SessionID]; ok && claims.SessionID != uuid.Nil {
+		return true
+	}
+	if watermark, ok := r.watermarks[claims.UserID]; ok {
+		// iat has second precision, so a token issued in the same second as
+		// the watermark is treated as issued before it.
+		if claims.IssuedAt == nil || !claims.IssuedAt.Time.After(watermark.Truncate(time.Second)) {
+			return true
+		}
+	}
+	return false
+}
+
+// RevokeToken denylists a single access token until it expires.
+func (r *RevocationStore) RevokeToken(ctx context.Context, jti string, userID uuid.UUID, expiresAt time.Time) error {
+	record := &model.RevokedToken{JTI: jti, UserID: userID, ExpiresAt: expiresAt}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(record).Error
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.jtis[jti] = expiresAt
+	r.mu.Unlock()
+	return nil
+}
+
+// RevokeSession revokes every access token carrying sessionID. Tokens are
+// issued to a session for at most one TTL after this call, so the entry
+// expires with them.
+func (r *RevocationStore) RevokeSession(ctx context.Context, sessionID, userID uuid.UUID, now time.Time) error {
+	expiresAt := now.Add(r.ttl)
+	record := &model.RevokedToken{JTI: sessionKeyPrefix + sessionID.String(), UserID: userID, ExpiresAt: expiresAt}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "jti"}},
+		DoUpdates: clause.AssignmentColumns([]string{"expires_at", "created_at"}),
+	}).Create(record).Error
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.sessions[sessionID] = expiresAt
+	r.mu.Unlock()
+	return nil
+}
+
+// RevokeAllBefore revokes every access token issued to the user up to now.
+func (r *RevocationStore) RevokeAllBefore(ctx context.Context, userID uuid.UUID, now time.Time) error {
+	watermark := now.Truncate(time.Second)
+	record := &model.TokenWatermark{UserID: userID, ValidAfter: watermark}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"valid_after", "updated_at"}),
+	}).Create(record).Error
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.watermarks[userID] = watermark
+	r.mu.Unlock()
+	return nil
+}
+
+// maintain periodically pulls revocations made by other instances and prunes
+// entries that can no longer match a live token.
+func (r *RevocationStore) maintain() {
+	syncTicker := time.NewTicker(revocationSyncInterval)
+	cleanupTicker := time.NewTicker(revocationCleanupEvery)
+	defer syncTicker.Stop()
+	defer cleanupTicker.Stop()
+
+	for {
+		select {
+		case <-syncTicker.C:
+			if err := r.sync(context.Background()); err != nil {
+				slog.Error("Failed to sync token revocations", "error", err)
+			}
+		case <-cleanupTicker.C:
+			r.cleanup(context.Background())
+		}
+	}
+}
+
+// sync loads revocations written since the last successful sync.
+func (r *RevocationStore) sync(ctx context.Context) error {
+	now := time.Now()
+
+	r.mu.RLock()
+	since := r.syncedAt
+	r.mu.RUnlock()
+	if !since.IsZero() {
+		since = since.Add(-revocationSyncOverlap)
+	}
+
+	var tokens []model.RevokedToken
+	if err := r.db.WithContext(ctx).
+		Where("expires_at > ? AND created_at >= ?", now, since).
+		Find(&tokens).Error; err != nil {
+		return err
+	}
+
+	var watermarks []model.TokenWatermark
+	if err := r.db.WithContext(ctx).
+		Where("valid_after > ? AND updated_at >= ?", now.Add(-r.ttl), since).
+		Find(&watermarks).Error; err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, t := range tokens {
+		if sid, ok := strings.CutPrefix(t.JTI, sessionKeyPrefix); ok {
+			if sessionID, err := uuid.Parse(sid); err == nil {
+				r.sessions[sessionID] = t.ExpiresAt
+			}
+			continue
+		}
+		r.jtis[t.JTI] = t.ExpiresAt
+	}
+	for _, w := range watermarks {
+		if w.ValidAfter.After(r.watermarks[w.UserID]) {
+			r.watermarks[w.UserID] = w.ValidAfter
+		}
+	}
+
+	for jti, exp := range r.jtis {
+		if now.After(exp) {
+			delete(r.jtis, jti)
+		}
+	}
+	for sessionID, exp := range r.sessions {
+		if now.After(exp) {
+			delete(r.sessions, sessionID)
+		}
+	}
+	for userID, watermark := range r.watermarks {
+		if now.Sub(watermark) > r.ttl {
+			delete(r.watermarks, userID)
+		}
+	}
+
+	r.syncedAt = now
+	return nil
+}
+
+// cleanup deletes denylist rows for tokens that have expired anyway.
+func (r *RevocationStore) cleanup(ctx context.Context) {
+	err := r.db.WithContext(ctx).
+		Where("expires_at < ?", time.Now()).
+		Delete(&model.RevokedToken{}).Error
+	if err != nil {
+		slog.Error("Failed to clean up revoked tokens", "error", err)
+	}
+}
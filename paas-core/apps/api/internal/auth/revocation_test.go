@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestRevocationStoreIsRevoked(t *testing.T) {
+	userID := uuid.New()
+	revokedSession := uuid.New()
+	// Watermarks are stored truncated, but one synced from an older replica
+	// may carry sub-second precision.
+	watermark := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	store := &RevocationStore{
+		jtis:       map[string]time.Time{"revoked-jti": watermark.Add(time.Hour)},
+		sessions:   map[uuid.UUID]time.Time{revokedSession: watermark.Add(time.Hour)},
+		watermarks: map[uuid.UUID]time.Time{userID: watermark.Add(400 * time.Millisecond)},
+	}
+
+	tests := []struct {
+		name      string
+		userID    uuid.UUID
+		jti       string
+		sessionID uuid.UUID
+		issuedAt  *jwt.NumericDate
+		want      bool
+	}{
+		{"issued before the watermark", userID, "", uuid.Nil, jwt.NewNumericDate(watermark.Add(-time.Second)), true},
+		{"issued in the watermark's second", userID, "", uuid.Nil, jwt.NewNumericDate(watermark), true},
+		{"issued in the next second", userID, "", uuid.Nil, jwt.NewNumericDate(watermark.Add(time.Second)), false},
+		{"missing iat", userID, "", uuid.Nil, nil, true},
+		{"other user", uuid.New(), "", uuid.Nil, jwt.NewNumericDate(watermark.Add(-time.Hour)), false},
+		{"revoked jti", uuid.New(), "revoked-jti", uuid.Nil, jwt.NewNumericDate(watermark), true},
+		{"revoked session", uuid.New(), "", revokedSession, jwt.NewNumericDate(watermark), true},
+		{"live session", uuid.New(), "live-jti", uuid.New(), jwt.NewNumericDate(watermark), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := &Claims{
+				RegisteredClaims: jwt.RegisteredClaims{ID: tt.jti, IssuedAt: tt.issuedAt},
+				UserID:           tt.userID,
+				SessionID:        tt.sessionID,
+			}
+			if got := store.IsRevoked(claims); got != tt.want {
+				t.Errorf("IsRevoked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRevokeAllBeforeSameSecond issues a token right after a logout in the
+// same second: iat only has second precision, so it cannot be told apart
+// from tokens issued before the logout and must be rejected.
+func TestRevokeAllBeforeSameSecond(t *testing.T) {
+	userID := uuid.New()
+	logoutAt := time.Date(2026, 3, 1, 12, 0, 0, 700_000_000, time.UTC)
+	store := &RevocationStore{watermarks: map[uuid.UUID]time.Time{userID: logoutAt.Truncate(time.Second)}}
+
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		want     bool
+	}{
+		{"same second, earlier", logoutAt.Add(-500 * time.Millisecond), true},
+		{"same second, later", logoutAt.Add(200 * time.Millisecond), true},
+		{"next second", logoutAt.Add(300 * time.Millisecond), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// jwt.NewNumericDate truncates to whole seconds, like a signed token.
+			claims := &Claims{
+				RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(tt.issuedAt)},
+				UserID:           userID,
+			}
+			if got := store.IsRevoked(claims); got != tt.want {
+				t.Errorf("IsRevoked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
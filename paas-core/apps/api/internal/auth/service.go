@@ -8,6 +8,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -23,32 +24,49 @@ var (
 	ErrExpiredToken = errors.New("token expired")
 	ErrTokenReuse   = errors.New("token reuse detected")
 	ErrTokenRevoked = errors.New("token has been revoked")
+	ErrNoSession    = errors.New("session not found")
+	ErrSuspended    = errors.New("account is suspended")
 )
 
 // Service defines the authentication service interface.
 type Service interface {
-	GenerateTokenPair(ctx context.Context, userID uuid.UUID, email, name string, roles []string) (*TokenPair, error)
+	GenerateTokenPair(ctx context.Context, userID uuid.UUID, email, name string, roles []string, amr []string) (*TokenPair, error)
+	GenerateScopedToken(ctx context.Context, userID uuid.UUID, email, name string, scopes []string, orgID *uuid.UUID) (*TokenPair, error)
+	GenerateImpersonationToken(ctx context.Context, userID uuid.UUID, email, name string, roles []string, actor *Actor, ttl time.Duration) (string, *Claims, error)
 	RefreshAccessToken(ctx context.Context, refreshToken string) (*TokenPair, error)
+	Reauthenticate(ctx context.Context, userID, sessionID uuid.UUID, amr []string) (*TokenPair, error)
 	ValidateToken(tokenString string) (*Claims, error)
 	RevokeRefreshToken(ctx context.Context, tokenHash string) error
+	RevokeAccessToken(ctx context.Context, claims *Claims) error
 	RevokeAllUserTokens(ctx context.Context, userID uuid.UUID) error
+	SignOutEverywhere(ctx context.Context, userID uuid.UUID) error
+	ListSessions(ctx context.Context, userID uuid.UUID) ([]SessionResponse, error)
+	RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error
+}
+
+// SecurityNotifier is told about session events the user should hear about.
+type SecurityNotifier interface {
+	// SessionStarted is called after a new sign-in session is stored.
+	SessionStarted(ctx context.Context, userID, sessionID uuid.UUID)
+	// TokenReused is called when a rotated refresh token is presented again.
+	TokenReused(ctx context.Context, userID uuid.UUID)
 }
 
 type service struct {
-	jwtSecret        string
+	keyring          *Keyring
+	issuer           string
 	accessTokenTTL   time.Duration
 	refreshTokenTTL  time.Duration
 	refreshTokenRepo RefreshTokenRepository
+	sessionRepo      SessionRepository
+	revocations      *RevocationStore
+	notifier         SecurityNotifier
 	db               *gorm.DB
 }
 
-// NewService creates a new authentication service.
-func NewService(cfg *config.JWTConfig, db *gorm.DB) Service {
-	jwtSecret := cfg.Secret
-	if jwtSecret == "" {
-		jwtSecret = "default-secret-change-in-production"
-	}
-
+// NewService creates a new authentication service that signs access tokens
+// with the keyring's active key. notifier may be nil.
+func NewService(cfg *config.JWTConfig, db *gorm.DB, keyring *Keyring, notifier SecurityNotifier) Service {
 	accessTokenTTL := cfg.AccessTokenTTL
 	if accessTokenTTL == 0 {
 		accessTokenTTL = 15 * time.Minute
@@ -60,34 +78,28 @@ func NewService(cfg *config.JWTConfig, db *gorm.DB) Service {
 	}
 
 	return &service{
-		jwtSecret:        jwtSecret,
+		keyring:          keyring,
+		issuer:           cfg.Issuer,
 		accessTokenTTL:   accessTokenTTL,
 		refreshTokenTTL:  refreshTokenTTL,
 		refreshTokenRepo: NewRefreshTokenRepository(db),
+		sessionRepo:      NewSessionRepository(db),
+		revocations:      NewRevocationStore(db, accessTokenTTL),
+		notifier:         notifier,
 		db:               db,
 	}
 }
 
-// GenerateTokenPair creates an access token and a refresh token with a shared family.
-func (s *service) GenerateTokenPair(ctx context.Context, userID uuid.UUID, email, name string, roles []string) (*TokenPair, error) {
-	now := time.Now()
-	expiresAt := now.Add(s.accessTokenTTL)
-
-	claims := Claims{
-		RegisteredClaims: jwt.RegisteredClaims{
-			Subject:   userID.String(),
-			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(expiresAt),
-			ID:        uuid.New().String(),
-		},
-		UserID: userID,
-		Email:  email,
-		Name:   name,
-		Roles:  roles,
+// GenerateTokenPair creates an access token and a refresh token with a shared
+// family. amr lists the methods the user just authenticated with.
+func (s *service) GenerateTokenPair(ctx context.Context, userID uuid.UUID, email, name string, roles []string, amr []string) (*TokenPair, error) {
+	if err := s.checkNotSuspended(ctx, userID); err != nil {
+		return nil, err
 	}
+	now := time.Now()
+	family := uuid.New()
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	accessToken, err := token.SignedString([]byte(s.jwtSecret))
+	accessToken, err := s.signAccessToken(now, userID, family, email, name, roles, &now, amr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign access token: %w", err)
 	}
@@ -99,8 +111,6 @@ func (s *service) GenerateTokenPair(ctx context.Context, userID uuid.UUID, email
 	}
 	refreshToken := base64.URLEncoding.EncodeToString(refreshBytes)
 
-	family := uuid.New()
-
 	// Store refresh token hash
 	tokenHash := hashToken(refreshToken)
 	rtRecord := &model.RefreshToken{
@@ -113,6 +123,13 @@ func (s *service) GenerateTokenPair(ctx context.Context, userID uuid.UUID, email
 		return nil, fmt.Errorf("failed to store refresh token: %w", err)
 	}
 
+	if err := s.touchSession(ctx, family, userID, now, rtRecord.ExpiresAt, &now, amr); err != nil {
+		return nil, fmt.Errorf("failed to store session: %w", err)
+	}
+	if s.notifier != nil {
+		s.notifier.SessionStarted(ctx, userID, family)
+	}
+
 	return &TokenPair{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
@@ -122,6 +139,52 @@ func (s *service) GenerateTokenPair(ctx context.Context, userID uuid.UUID, email
 	}, nil
 }
 
+// GenerateScopedToken issues a standalone access token restricted to scopes
+// and, optionally, one org. No refresh token or device session is created.
+func (s *service) GenerateScopedToken(ctx context.Context, userID uuid.UUID, email, name string, scopes []string, orgID *uuid.UUID) (*TokenPair, error) {
+	if err := s.checkNotSuspended(ctx, userID); err != nil {
+		return nil, err
+	}
+	if scopes == nil {
+		scopes = []string{} // non-nil marks the token as scoped
+	}
+
+	claims := s.newClaims(time.Now(), userID, uuid.Nil, email, name, nil)
+	claims.Scopes = scopes
+	claims.OrgID = orgID
+
+	accessToken, err := s.keyring.Sign(claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign access token: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.accessTokenTTL.Seconds()),
+	}, nil
+}
+
+// GenerateImpersonationToken issues a short-lived access token that lets actor
+// act as the user. No refresh token or device session is created, so the
+// token cannot outlive ttl. The signed token is returned with its claims.
+func (s *service) GenerateImpersonationToken(ctx context.Context, userID uuid.UUID, email, name string, roles []string, actor *Actor, ttl time.Duration) (string, *Claims, error) {
+	now := time.Now()
+	if ttl <= 0 || ttl > s.accessTokenTTL {
+		ttl = s.accessTokenTTL
+	}
+
+	claims := s.newClaims(now, userID, uuid.Nil, email, name, roles)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(ttl))
+	claims.Actor = actor
+
+	token, err := s.keyring.Sign(claims)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to sign access token: %w", err)
+	}
+	return token, claims, nil
+}
+
 // RefreshAccessToken validates a refresh token and issues a new pair (rotation).
 func (s *service) RefreshAccessToken(ctx context.Context, refreshToken string) (*TokenPair, error) {
 	tokenHash := hashToken(refreshToken)
@@ -137,6 +200,10 @@ func (s *service) RefreshAccessToken(ctx context.Context, refreshToken string) (
 	// Token reuse detection: if already revoked, revoke entire family
 	if stored.Revoked {
 		_ = s.refreshTokenRepo.RevokeByFamily(ctx, stored.Family)
+		_, _ = s.sessionRepo.Revoke(ctx, stored.UserID, stored.Family)
+		if s.notifier != nil {
+			s.notifier.TokenReused(ctx, stored.UserID)
+		}
 		return nil, ErrTokenReuse
 	}
 
@@ -149,44 +216,36 @@ func (s *service) RefreshAccessToken(ctx context.Context, refreshToken string) (
 		return nil, fmt.Errorf("failed to revoke old token: %w", err)
 	}
 
-	// Fetch user roles for the new access token
-	var roles []string
-	if s.db != nil {
-		err := s.db.Table("roles").
-			Select("roles.name").
-			Joins("JOIN user_roles ON user_roles.role_id = roles.id").
-			Where("user_roles.user_id = ?", stored.UserID).
-			Find(&roles).Error
-		if err != nil {
-			return nil, fmt.Errorf("failed to fetch roles: %w", err)
+	user, roles, err := s.loadUser(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, ErrSuspended) {
+			_ = s.refreshTokenRepo.RevokeByFamily(ctx, stored.Family)
+			_, _ = s.sessionRepo.Revoke(ctx, stored.UserID, stored.Family)
 		}
+		return nil, err
 	}
 
-	// Fetch user info
-	var user model.User
-	if err := s.db.First(&user, "id = ?", stored.UserID).Error; err != nil {
-		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	// The new access token keeps the session's authentication time. Sessions
+	// from before it was tracked fall back to when they were created; families
+	// without a session get none, so sensitive routes ask to re-authenticate.
+	session, err := s.sessionRepo.FindActive(ctx, stored.UserID, stored.Family)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch session: %w", err)
+	}
+	var authTime *time.Time
+	var amr []string
+	if session != nil {
+		authTime = &session.CreatedAt
+		if session.AuthTime != nil {
+			authTime = session.AuthTime
+		}
+		amr = strings.Fields(session.AMR)
 	}
 
 	// Issue new pair in the same family
 	now := time.Now()
-	expiresAt := now.Add(s.accessTokenTTL)
 
-	claims := Claims{
-		RegisteredClaims: jwt.RegisteredClaims{
-			Subject:   stored.UserID.String(),
-			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(expiresAt),
-			ID:        uuid.New().String(),
-		},
-		UserID: stored.UserID,
-		Email:  user.Email,
-		Name:   user.Name,
-		Roles:  roles,
-	}
-
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	newAccessToken, err := jwtToken.SignedString([]byte(s.jwtSecret))
+	newAccessToken, err := s.signAccessToken(now, stored.UserID, stored.Family, user.Email, user.Name, roles, authTime, amr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign new access token: %w", err)
 	}
@@ -209,6 +268,10 @@ func (s *service) RefreshAccessToken(ctx context.Context, refreshToken string) (
 		return nil, fmt.Errorf("failed to store new refresh token: %w", err)
 	}
 
+	if err := s.touchSession(ctx, stored.Family, stored.UserID, now, newRT.ExpiresAt, authTime, amr); err != nil {
+		return nil, fmt.Errorf("failed to update session: %w", err)
+	}
+
 	return &TokenPair{
 		AccessToken:  newAccessToken,
 		RefreshToken: newRefreshToken,
@@ -218,14 +281,48 @@ func (s *service) RefreshAccessToken(ctx context.Context, refreshToken string) (
 	}, nil
 }
 
-// ValidateToken parses and validates a JWT access token.
+// Reauthenticate records that the user of an active session has just proved
+// their identity again and issues a new access token carrying the fresh
+// auth_time. The refresh token is unchanged and later refreshes keep the new
+// authentication time.
+func (s *service) Reauthenticate(ctx context.Context, userID, sessionID uuid.UUID, amr []string) (*TokenPair, error) {
+	now := time.Now()
+
+	ok, err := s.sessionRepo.Reauthenticate(ctx, userID, sessionID, now, strings.Join(amr, " "))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update session: %w", err)
+	}
+	if !ok {
+		return nil, ErrNoSession
+	}
+
+	user, roles, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.signAccessToken(now, userID, sessionID, user.Email, user.Name, roles, &now, amr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign access token: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.accessTokenTTL.Seconds()),
+		TokenFamily: sessionID,
+	}, nil
+}
+
+// ValidateToken parses and validates a JWT access token. Tokens signed by any
+// non-retired key in the keyring are accepted unless they have been revoked.
 func (s *service) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-		}
-		return []byte(s.jwtSecret), nil
-	})
+	var opts []jwt.ParserOption
+	if s.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(s.issuer))
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, s.keyring.Keyfunc, opts...)
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
 			return nil, ErrExpiredToken
@@ -238,6 +335,16 @@ func (s *service) ValidateToken(tokenString string) (*Claims, error) {
 		return nil, ErrInvalidToken
 	}
 
+	// ID tokens issued to OIDC clients share the signing keys but always carry
+	// an audience; they are not API access tokens.
+	if len(claims.Audience) > 0 {
+		return nil, ErrInvalidToken
+	}
+
+	if s.revocations.IsRevoked(claims) {
+		return nil, ErrTokenRevoked
+	}
+
 	return claims, nil
 }
 
@@ -246,9 +353,159 @@ func (s *service) RevokeRefreshToken(ctx context.Context, rawToken string) error
 	return s.refreshTokenRepo.RevokeByHash(ctx, hashToken(rawToken))
 }
 
-// RevokeAllUserTokens revokes all refresh tokens for a user.
+// RevokeAccessToken immediately invalidates a single access token by its jti.
+func (s *service) RevokeAccessToken(ctx context.Context, claims *Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return ErrInvalidToken
+	}
+	return s.revocations.RevokeToken(ctx, claims.ID, claims.UserID, claims.ExpiresAt.Time)
+}
+
+// RevokeAllUserTokens revokes all refresh tokens and sessions for a user, and
+// every access token issued to them so far.
 func (s *service) RevokeAllUserTokens(ctx context.Context, userID uuid.UUID) error {
-	return s.refreshTokenRepo.RevokeAllForUser(ctx, userID)
+	if err := s.revocations.RevokeAllBefore(ctx, userID, time.Now()); err != nil {
+		return err
+	}
+	if err := s.refreshTokenRepo.RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	return s.sessionRepo.RevokeAllForUser(ctx, userID)
+}
+
+// SignOutEverywhere revokes everything RevokeAllUserTokens does and the
+// user's personal access tokens. Plain logouts leave tokens made for CI and
+// scripts alone; this is for "this wasn't me" and lost access.
+func (s *service) SignOutEverywhere(ctx context.Context, userID uuid.UUID) error {
+	if err := s.RevokeAllUserTokens(ctx, userID); err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Model(&model.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// ListSessions returns the user's active device sessions, most recent first.
+func (s *service) ListSessions(ctx context.Context, userID uuid.UUID) ([]SessionResponse, error) {
+	sessions, err := s.sessionRepo.ListActive(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]SessionResponse, len(sessions))
+	for i, sess := range sessions {
+		result[i] = SessionResponse{
+			ID:         sess.ID,
+			UserAgent:  sess.UserAgent,
+			IPAddress:  sess.IPAddress,
+			LastUsedAt: sess.LastUsedAt,
+			CreatedAt:  sess.CreatedAt,
+		}
+	}
+	return result, nil
+}
+
+// RevokeSession signs out a single device by revoking its refresh token family
+// and the access tokens already issued to it.
+func (s *service) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	revoked, err := s.sessionRepo.Revoke(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrNoSession
+	}
+	if err := s.refreshTokenRepo.RevokeByFamily(ctx, sessionID); err != nil {
+		return err
+	}
+	return s.revocations.RevokeSession(ctx, sessionID, userID, time.Now())
+}
+
+// touchSession records the device behind a refresh token family. The
+// authentication time and methods are only stored when the session is created.
+func (s *service) touchSession(ctx context.Context, family, userID uuid.UUID, now, expiresAt time.Time, authTime *time.Time, amr []string) error {
+	info := ClientInfoFromContext(ctx)
+
+	userAgent := info.UserAgent
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+
+	return s.sessionRepo.Touch(ctx, &model.UserSession{
+		ID:         family,
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IPAddress:  info.IPAddress,
+		LastUsedAt: now,
+		ExpiresAt:  expiresAt,
+		AuthTime:   authTime,
+		AMR:        strings.Join(amr, " "),
+	})
+}
+
+// checkNotSuspended returns ErrSuspended for users banned from signing in,
+// so no sign-in method can issue them a session.
+func (s *service) checkNotSuspended(ctx context.Context, userID uuid.UUID) error {
+	var user model.User
+	if err := s.db.WithContext(ctx).Select("id", "suspended_until").First(&user, "id = ?", userID).Error; err != nil {
+		return fmt.Errorf("failed to fetch user: %w", err)
+	}
+	if user.IsSuspended() {
+		return ErrSuspended
+	}
+	return nil
+}
+
+// loadUser fetches the user and role names for a new access token. Suspended
+// users get ErrSuspended, which ends their sessions at the next refresh.
+func (s *service) loadUser(ctx context.Context, userID uuid.UUID) (*model.User, []string, error) {
+	var roles []string
+	err := s.db.WithContext(ctx).Table("roles").
+		Select("roles.name").
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Find(&roles).Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch roles: %w", err)
+	}
+
+	var user model.User
+	if err := s.db.WithContext(ctx).First(&user, "id = ?", userID).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch user: %w", err)
+	}
+	if user.IsSuspended() {
+		return nil, nil, ErrSuspended
+	}
+	return &user, roles, nil
+}
+
+// signAccessToken builds the claims for an interactive session and signs them
+// with the keyring's active key.
+func (s *service) signAccessToken(now time.Time, userID, sessionID uuid.UUID, email, name string, roles []string, authTime *time.Time, amr []string) (string, error) {
+	claims := s.newClaims(now, userID, sessionID, email, name, roles)
+	if authTime != nil {
+		claims.AuthTime = jwt.NewNumericDate(*authTime)
+	}
+	claims.AMR = amr
+	return s.keyring.Sign(claims)
+}
+
+// newClaims returns access token claims valid for accessTokenTTL from now.
+func (s *service) newClaims(now time.Time, userID, sessionID uuid.UUID, email, name string, roles []string) *Claims {
+	return &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.issuer,
+			Subject:   userID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.accessTokenTTL)),
+			ID:        uuid.New().String(),
+		},
+		UserID:    userID,
+		SessionID: sessionID,
+		Email:     email,
+		Name:      name,
+		Roles:     roles,
+	}
 }
 
 func hashToken(token string) string {
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	apiErrors "paas-core/apps/api/internal/errors"
+)
+
+// SessionHandler lets users see and revoke their device sessions.
+type SessionHandler struct {
+	service Service
+}
+
+// NewSessionHandler creates a new session handler.
+func NewSessionHandler(service Service) *SessionHandler {
+	return &SessionHandler{service: service}
+}
+
+// ListSessions godoc
+// @Summary List active sessions
+// @Description Lists the devices currently signed in to the user's account
+// @Tags sessions
+// @Security BearerAuth
+// @Success 200 {object} errors.Response{data=[]SessionResponse}
+// @Router /api/v1/users/me/sessions [get]
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		_ = c.Error(apiErrors.Unauthorized(""))
+		return
+	}
+	authClaims := claims.(*Claims)
+
+	sessions, err := h.service.ListSessions(c.Request.Context(), authClaims.UserID)
+	if err != nil {
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == authClaims.SessionID
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(sessions))
+}
+
+// RevokeSession godoc
+// @Summary Revoke a session
+// @Description Signs out a single device; other sessions stay active
+// @Tags sessions
+// @Security BearerAuth
+// @Param id path string true "Session ID"
+// @Success 200 {object} errors.Response "Session revoked"
+// @Failure 404 {object} errors.Response "Session not found"
+// @Router /api/v1/users/me/sessions/{id} [delete]
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		_ = c.Error(apiErrors.Unauthorized(""))
+		return
+	}
+	userID := userIDVal.(uuid.UUID)
+
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		_ = c.Error(apiErrors.BadRequest("Invalid session ID"))
+		return
+	}
+
+	if err := h.service.RevokeSession(c.Request.Context(), userID, sessionID); err != nil {
+		if errors.Is(err, ErrNoSession) {
+			_ = c.Error(apiErrors.NotFound("Session not found"))
+			return
+		}
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(gin.H{"message": "Session revoked"}))
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"paas-core/apps/api/internal/config"
+	apiErrors "paas-core/apps/api/internal/errors"
+)
+
+// Session cookie names. JWTAuth reads AccessTokenCookie when no Authorization
+// header is sent.
+const (
+	AccessTokenCookie  = "access_token"
+	RefreshTokenCookie = "refresh_token"
+
+	// refreshCookiePath limits the refresh token to the auth endpoints so it
+	// is not sent with every API request.
+	refreshCookiePath = "/api/v1/auth"
+)
+
+// SessionTransport delivers issued tokens to the client according to the
+// configured mode: in JSON bodies, as HttpOnly cookies, or both.
+type SessionTransport struct {
+	mode       string
+	domain     string
+	sameSite   http.SameSite
+	secure     bool
+	refreshTTL time.Duration
+}
+
+// NewSessionTransport creates a transport for the session config. secure
+// forces the Secure attribute, e.g. in production.
+func NewSessionTransport(cfg config.SessionConfig, jwtCfg *config.JWTConfig, secure bool) *SessionTransport {
+	mode := cfg.Transport
+	if mode == "" {
+		mode = config.SessionTransportBearer
+	}
+
+	refreshTTL := jwtCfg.RefreshTokenTTL
+	if refreshTTL == 0 {
+		refreshTTL = 168 * time.Hour // 7 days
+	}
+
+	sameSite := cfg.SameSiteMode()
+	return &SessionTransport{
+		mode:       mode,
+		domain:     cfg.CookieDomain,
+		sameSite:   sameSite,
+		secure:     secure || cfg.Secure || sameSite == http.SameSiteNoneMode, // browsers reject SameSite=None without Secure
+		refreshTTL: refreshTTL,
+	}
+}
+
+// UsesCookies reports whether tokens are set as cookies.
+func (t *SessionTransport) UsesCookies() bool {
+	return t.mode == config.SessionTransportCookie || t.mode == config.SessionTransportBoth
+}
+
+// Secure reports whether cookies are marked Secure.
+func (t *SessionTransport) Secure() bool {
+	return t.secure
+}
+
+// ExposesTokens reports whether tokens are included in response bodies and
+// redirect URLs.
+func (t *SessionTransport) ExposesTokens() bool {
+	return t.mode != config.SessionTransportCookie
+}
+
+// WriteAuthResponse sets the session cookies for a completed login and writes
+// the response. MFA challenges carry no tokens and are written unchanged.
+func (t *SessionTransport) WriteAuthResponse(c *gin.Context, resp *AuthResponse) {
+	if resp.AccessToken != "" {
+		t.SetCookies(c, &TokenPair{AccessToken: resp.AccessToken, RefreshToken: resp.RefreshToken, ExpiresIn: resp.ExpiresIn})
+		if !t.ExposesTokens() {
+			stripped := *resp
+			stripped.AccessToken = ""
+			stripped.RefreshToken = ""
+			resp = &stripped
+		}
+	}
+	c.JSON(http.StatusOK, apiErrors.Success(resp))
+}
+
+// WriteTokenPair sets the session cookies for a refreshed pair and writes the
+// response.
+func (t *SessionTransport) WriteTokenPair(c *gin.Context, pair *TokenPair) {
+	t.SetCookies(c, pair)
+	if !t.ExposesTokens() {
+		stripped := *pair
+		stripped.AccessToken = ""
+		stripped.RefreshToken = ""
+		pair = &stripped
+	}
+	c.JSON(http.StatusOK, apiErrors.Success(pair))
+}
+
+// SetCookies sets the access and refresh token cookies. It is a no-op in
+// bearer mode.
+func (t *SessionTransport) SetCookies(c *gin.Context, pair *TokenPair) {
+	if !t.UsesCookies() {
+		return
+	}
+	t.setCookie(c, AccessTokenCookie, pair.AccessToken, "/", int(pair.ExpiresIn))
+	if pair.RefreshToken != "" {
+		t.setCookie(c, RefreshTokenCookie, pair.RefreshToken, refreshCookiePath, int(t.refreshTTL.Seconds()))
+	}
+}
+
+// ClearCookies expires the session cookies. It is a no-op in bearer mode.
+func (t *SessionTransport) ClearCookies(c *gin.Context) {
+	if !t.UsesCookies() {
+		return
+	}
+	t.setCookie(c, AccessTokenCookie, "", "/", -1)
+	t.setCookie(c, RefreshTokenCookie, "", refreshCookiePath, -1)
+}
+
+// RefreshToken returns the refresh token cookie, or "" when there is none or
+// cookies are disabled.
+func (t *SessionTransport) RefreshToken(c *gin.Context) string {
+	if !t.UsesCookies() {
+		return ""
+	}
+	token, err := c.Cookie(RefreshTokenCookie)
+	if err != nil {
+		return ""
+	}
+	return token
+}
+
+func (t *SessionTransport) setCookie(c *gin.Context, name, value, path string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   t.domain,
+		MaxAge:   maxAge,
+		Secure:   t.secure,
+		HttpOnly: true,
+		SameSite: t.sameSite,
+	})
+}
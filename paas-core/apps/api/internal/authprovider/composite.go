@@ -0,0 +1,193 @@
+package authprovider
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+
+	"paas-core/apps/api/internal/auth"
+	"paas-core/apps/api/internal/model"
+)
+
+// PasswordImporter is implemented by providers that can take over a password
+// user from another provider during a migration.
+type PasswordImporter interface {
+	// ImportPasswordUser creates or updates the user with the password they
+	// just signed in with elsewhere, keeping their ID.
+	ImportPasswordUser(ctx context.Context, user auth.UserResponse, password string) error
+}
+
+// tokenIssuer is implemented by providers whose access tokens carry a fixed
+// "iss", so tokens can be routed without trying every provider.
+type tokenIssuer interface {
+	Issuer() string
+}
+
+// CompositeProvider accepts sessions from several providers at once so a live
+// user base can move between them without a forced sign-out. New users are
+// registered with the primary provider. Everyone else signs in and refreshes
+// at their home provider (users.auth_provider) until their next successful
+// password login, which moves them to the primary provider.
+type CompositeProvider struct {
+	db        *gorm.DB
+	primary   AuthProvider
+	providers []AuthProvider // primary first
+}
+
+// NewCompositeProvider creates a provider that issues sessions through
+// primary and still honors those of the legacy providers.
+func NewCompositeProvider(db *gorm.DB, primary AuthProvider, legacy ...AuthProvider) *CompositeProvider {
+	return &CompositeProvider{
+		db:        db,
+		primary:   primary,
+		providers: append([]AuthProvider{primary}, legacy...),
+	}
+}
+
+func (p *CompositeProvider) Name() string { return p.primary.Name() }
+
+// Register always creates the user at the primary provider.
+func (p *CompositeProvider) Register(ctx context.Context, req auth.RegisterRequest) (*auth.AuthResponse, error) {
+	return p.primary.Register(ctx, req)
+}
+
+// Login signs the user in at their home provider. Users without a local row
+// or a known home are tried at every provider, primary first. A password login at a
+// legacy provider migrates the user to the primary one.
+func (p *CompositeProvider) Login(ctx context.Context, req auth.LoginRequest) (*auth.AuthResponse, error) {
+	candidates := p.providers
+	if home := p.homeProvider(ctx, req.Email); home != nil {
+		candidates = []AuthProvider{home}
+	}
+
+	var firstErr error
+	for _, provider := range candidates {
+		resp, err := provider.Login(ctx, req)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		// Users mid-MFA are migrated on a later login without a challenge
+		if provider == p.primary || resp.MFARequired {
+			return resp, nil
+		}
+		return p.migrate(ctx, provider, resp, req), nil
+	}
+	return nil, firstErr
+}
+
+// ValidateToken routes the token to the provider matching its issuer, or
+// tries each provider in turn when no issuer matches.
+func (p *CompositeProvider) ValidateToken(tokenString string) (*auth.Claims, error) {
+	var unverified jwt.RegisteredClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, &unverified); err != nil {
+		return nil, auth.ErrInvalidToken
+	}
+	for _, provider := range p.providers {
+		if ti, ok := provider.(tokenIssuer); ok && unverified.Issuer != "" && ti.Issuer() == unverified.Issuer {
+			return provider.ValidateToken(tokenString)
+		}
+	}
+
+	err := auth.ErrInvalidToken
+	for _, provider := range p.providers {
+		claims, validateErr := provider.ValidateToken(tokenString)
+		if validateErr == nil {
+			return claims, nil
+		}
+		// Prefer a specific reason such as expiry from the provider that
+		// recognized the token
+		if !errors.Is(validateErr, auth.ErrInvalidToken) {
+			err = validateErr
+		}
+	}
+	return nil, err
+}
+
+// RefreshToken tries each provider in turn, since refresh tokens are opaque.
+// A detected token reuse stops the search.
+func (p *CompositeProvider) RefreshToken(ctx context.Context, refreshToken string) (*auth.TokenPair, error) {
+	var firstErr error
+	for _, provider := range p.providers {
+		pair, err := provider.RefreshToken(ctx, refreshToken)
+		if err == nil {
+			return pair, nil
+		}
+		if errors.Is(err, auth.ErrTokenReuse) {
+			return nil, err
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// Logout ends the user's sessions at every provider, since a user who was
+// just migrated may still hold sessions at the old one.
+func (p *CompositeProvider) Logout(ctx context.Context, claims *auth.Claims) error {
+	var errs []error
+	for _, provider := range p.providers {
+		if err := provider.Logout(ctx, claims); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// homeProvider returns the provider recorded for the user with this email,
+// or nil when the user is unknown or their provider is not configured. A
+// "local" user without a password is most likely a legacy provider's user
+// who only got the column default, so they are tried everywhere too.
+func (p *CompositeProvider) homeProvider(ctx context.Context, email string) AuthProvider {
+	var usr model.User
+	if err := p.db.WithContext(ctx).Select("auth_provider", "password_hash").Where("email = ?", email).First(&usr).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			slog.Error("Failed to look up auth provider", "error", err)
+		}
+		return nil
+	}
+	if usr.AuthProvider == "local" && !usr.HasPassword() {
+		return nil
+	}
+	for _, provider := range p.providers {
+		if provider.Name() == usr.AuthProvider {
+			return provider
+		}
+	}
+	return nil
+}
+
+// migrate moves a user who signed in at a legacy provider to the primary one
+// and returns the primary's session. Any failure leaves the user where they
+// are, signed in with the legacy session.
+func (p *CompositeProvider) migrate(ctx context.Context, from AuthProvider, resp *auth.AuthResponse, req auth.LoginRequest) *auth.AuthResponse {
+	importer, ok := p.primary.(PasswordImporter)
+	if !ok {
+		return resp
+	}
+	userID := resp.User.ID
+
+	if err := importer.ImportPasswordUser(ctx, resp.User, req.Password); err != nil {
+		slog.Warn("Failed to migrate user", "user_id", userID, "from", from.Name(), "to", p.primary.Name(), "error", err)
+		return resp
+	}
+	if err := p.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).
+		Update("auth_provider", p.primary.Name()).Error; err != nil {
+		slog.Error("Failed to record auth provider", "user_id", userID, "error", err)
+		return resp
+	}
+
+	migrated, err := p.primary.Login(ctx, req)
+	if err != nil {
+		slog.Warn("Failed to sign in migrated user", "user_id", userID, "provider", p.primary.Name(), "error", err)
+		return resp
+	}
+	slog.Info("Migrated user to auth provider", "user_id", userID, "from", from.Name(), "to", p.primary.Name())
+	return migrated
+}
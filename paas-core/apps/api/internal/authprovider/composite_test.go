@@ -0,0 +1,208 @@
+package authprovider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"paas-core/apps/api/internal/auth"
+	"paas-core/apps/api/internal/database/dbtest"
+	apiErrors "paas-core/apps/api/internal/errors"
+	"paas-core/apps/api/internal/model"
+)
+
+// fakeProvider signs in one user with the password it holds and records the
+// logins it was asked for.
+type fakeProvider struct {
+	name     string
+	userID   uuid.UUID
+	password string // empty when the provider does not know the user
+	mfa      bool   // answer logins with an MFA challenge
+	logins   int
+}
+
+func (f *fakeProvider) Name() string { return f.name }
+
+func (f *fakeProvider) Register(ctx context.Context, req auth.RegisterRequest) (*auth.AuthResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeProvider) Login(ctx context.Context, req auth.LoginRequest) (*auth.AuthResponse, error) {
+	f.logins++
+	if f.password == "" || req.Password != f.password {
+		return nil, apiErrors.Unauthorized("Invalid email or password")
+	}
+	user := auth.UserResponse{ID: f.userID, Email: req.Email}
+	if f.mfa {
+		return &auth.AuthResponse{MFARequired: true, MFAToken: f.name + "-mfa", User: user}, nil
+	}
+	return &auth.AuthResponse{AccessToken: f.name + "-access", User: user}, nil
+}
+
+func (f *fakeProvider) ValidateToken(tokenString string) (*auth.Claims, error) {
+	return nil, auth.ErrInvalidToken
+}
+
+func (f *fakeProvider) RefreshToken(ctx context.Context, refreshToken string) (*auth.TokenPair, error) {
+	return nil, auth.ErrInvalidToken
+}
+
+func (f *fakeProvider) Logout(ctx context.Context, claims *auth.Claims) error { return nil }
+
+// importingProvider is a primary provider that takes over migrated users.
+type importingProvider struct {
+	*fakeProvider
+	imported []uuid.UUID
+}
+
+func (p *importingProvider) ImportPasswordUser(ctx context.Context, user auth.UserResponse, password string) error {
+	p.imported = append(p.imported, user.ID)
+	p.password = password
+	return nil
+}
+
+func TestCompositeLogin(t *testing.T) {
+	const email, password = "ada@example.com", "correct horse"
+
+	tests := []struct {
+		name         string
+		user         *model.User // nil when there is no local row
+		localPass    string      // password known to the primary provider
+		legacyPass   string      // password known to the legacy provider
+		legacyMFA    bool
+		password     string
+		wantToken    string // "" when login fails
+		wantMFA      bool
+		wantLogins   [2]int // primary, legacy
+		wantImported bool
+		wantProvider string // users.auth_provider afterwards
+	}{
+		{
+			name:         "local user routed to primary",
+			user:         &model.User{AuthProvider: "local", PasswordHash: "hash"},
+			localPass:    password,
+			legacyPass:   password,
+			password:     password,
+			wantToken:    "local-access",
+			wantLogins:   [2]int{1, 0},
+			wantProvider: "local",
+		},
+		{
+			name:         "local user with wrong password is not tried elsewhere",
+			user:         &model.User{AuthProvider: "local", PasswordHash: "hash"},
+			localPass:    password,
+			legacyPass:   "wrong",
+			password:     "wrong",
+			wantLogins:   [2]int{1, 0},
+			wantProvider: "local",
+		},
+		{
+			name:         "legacy user routed to legacy and migrated",
+			user:         &model.User{AuthProvider: "supabase"},
+			legacyPass:   password,
+			password:     password,
+			wantToken:    "local-access",
+			wantLogins:   [2]int{1, 1}, // legacy, then primary after the import
+			wantImported: true,
+			wantProvider: "local",
+		},
+		{
+			name:         "legacy user with the local default falls through and is migrated",
+			user:         &model.User{AuthProvider: "local"},
+			legacyPass:   password,
+			password:     password,
+			wantToken:    "local-access",
+			wantLogins:   [2]int{2, 1}, // primary fails, legacy, primary after the import
+			wantImported: true,
+			wantProvider: "local",
+		},
+		{
+			name:         "legacy user with unknown home falls through",
+			user:         &model.User{AuthProvider: "ldap"},
+			legacyPass:   password,
+			password:     password,
+			wantToken:    "local-access",
+			wantLogins:   [2]int{2, 1},
+			wantImported: true,
+			wantProvider: "local",
+		},
+		{
+			name:         "unknown user tried at every provider",
+			legacyPass:   password,
+			password:     password,
+			wantToken:    "local-access",
+			wantLogins:   [2]int{2, 1},
+			wantImported: true,
+		},
+		{
+			name:         "legacy user mid-MFA is not migrated",
+			user:         &model.User{AuthProvider: "supabase"},
+			legacyPass:   password,
+			legacyMFA:    true,
+			password:     password,
+			wantMFA:      true,
+			wantLogins:   [2]int{0, 1},
+			wantProvider: "supabase",
+		},
+		{
+			name:       "wrong password everywhere",
+			localPass:  password,
+			legacyPass: password,
+			password:   "wrong",
+			wantLogins: [2]int{1, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.Open(t, &model.User{}, &model.Role{}, &model.UserRole{})
+			userID := uuid.New()
+			if tt.user != nil {
+				tt.user.ID = userID
+				tt.user.Name = "Ada"
+				tt.user.Email = email
+				if err := db.Create(tt.user).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			primary := &importingProvider{fakeProvider: &fakeProvider{name: "local", userID: userID, password: tt.localPass}}
+			legacy := &fakeProvider{name: "supabase", userID: userID, password: tt.legacyPass, mfa: tt.legacyMFA}
+			composite := NewCompositeProvider(db, primary, legacy)
+
+			resp, err := composite.Login(context.Background(), auth.LoginRequest{Email: email, Password: tt.password})
+			switch {
+			case tt.wantToken == "" && !tt.wantMFA:
+				assertStatus(t, err, http.StatusUnauthorized)
+			case err != nil:
+				t.Fatalf("Login() error = %v", err)
+			case resp.AccessToken != tt.wantToken || resp.MFARequired != tt.wantMFA:
+				t.Errorf("Login() = token %q, mfa %v; want token %q, mfa %v", resp.AccessToken, resp.MFARequired, tt.wantToken, tt.wantMFA)
+			}
+
+			if got := [2]int{primary.logins, legacy.logins}; got != tt.wantLogins {
+				t.Errorf("logins (primary, legacy) = %v, want %v", got, tt.wantLogins)
+			}
+			var wantImported []uuid.UUID
+			if tt.wantImported {
+				wantImported = []uuid.UUID{userID}
+			}
+			if !reflect.DeepEqual(primary.imported, wantImported) {
+				t.Errorf("imported = %v, want %v", primary.imported, wantImported)
+			}
+
+			if tt.user != nil {
+				var stored model.User
+				if err := db.First(&stored, "id = ?", userID).Error; err != nil {
+					t.Fatal(err)
+				}
+				if stored.AuthProvider != tt.wantProvider {
+					t.Errorf("auth_provider = %q, want %q", stored.AuthProvider, tt.wantProvider)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package authprovider
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"paas-core/apps/api/internal/auth"
+)
+
+const (
+	// jwksCacheTTL is how long fetched keys are trusted before a refetch.
+	jwksCacheTTL = time.Hour
+	// jwksMinRefresh limits refetches triggered by unknown "kid" values, so
+	// tokens with made-up key IDs cannot hammer the issuer.
+	jwksMinRefresh = time.Minute
+	jwksMaxBytes   = 1 << 20
+)
+
+// asymmetricMethods are the signing algorithms accepted for tokens verified
+// against a remote key set. HMAC and "none" are never valid there.
+var asymmetricMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// remoteKeySet caches the public keys an external issuer publishes as a JWKS
+// and resolves them by "kid". Keys are refetched when the cache is stale or a
+// token names a key that is not cached yet, which covers issuer key rotation.
+type remoteKeySet struct {
+	url        string
+	httpClient *http.Client
+
+	mu          sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+func newRemoteKeySet(url string, httpClient *http.Client) *remoteKeySet {
+	return &remoteKeySet{url: url, httpClient: httpClient, keys: map[string]crypto.PublicKey{}}
+}
+
+// Keyfunc resolves the verification key for a parsed token.
+func (s *remoteKeySet) Keyfunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	s.mu.RLock()
+	key, ok := s.lookup(kid)
+	stale := time.Since(s.fetchedAt) > jwksCacheTTL
+	s.mu.RUnlock()
+	if ok && !stale {
+		return key, nil
+	}
+
+	if err := s.refresh(); err != nil {
+		// Keep serving cached keys while the issuer is unreachable
+		slog.Warn("Failed to refresh JWKS", "url", s.url, "error", err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if key, ok := s.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookup finds a key by kid. Tokens without a kid are accepted only when the
+// issuer publishes a single key. Callers hold s.mu.
+func (s *remoteKeySet) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" {
+		if len(s.keys) != 1 {
+			return nil, false
+		}
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// refresh refetches the key set unless it was attempted very recently.
+func (s *remoteKeySet) refresh() error {
+	s.mu.Lock()
+	if time.Since(s.lastAttempt) < jwksMinRefresh {
+		s.mu.Unlock()
+		return nil
+	}
+	s.lastAttempt = time.Now()
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	keys, err := s.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *remoteKeySet) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS endpoint returned HTTP %d", resp.StatusCode)
+	}
+
+	var set auth.JWKS
+	if err := json.NewDecoder(io.LimitReader(resp.Body, jwksMaxBytes)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			// Skip keys we cannot use rather than rejecting the whole set
+			slog.Debug("Skipping JWK", "kid", jwk.Kid, "error", err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS contains no usable signing keys")
+	}
+	return keys, nil
+}
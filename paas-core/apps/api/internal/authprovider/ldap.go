@@ -0,0 +1,552 @@
+package authprovider
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"paas-core/apps/api/internal/auth"
+	"paas-core/apps/api/internal/config"
+	apiErrors "paas-core/apps/api/internal/errors"
+	"paas-core/apps/api/internal/model"
+)
+
+// Sentinel errors for LDAP operations.
+var (
+	ErrLDAPConfig      = errors.New("invalid ldap configuration")
+	ErrLDAPUnavailable = errors.New("ldap server unavailable")
+)
+
+const ldapTimeout = 10 * time.Second
+
+// ldapOrgGroup grants a role in an org to the members of a directory group.
+type ldapOrgGroup struct {
+	orgSlug string
+	role    string
+	groupDN string // normalized
+}
+
+// LDAPProvider implements AuthProvider against an LDAP or Active Directory
+// server. Passwords are checked by binding as the user; sessions are then
+// issued by auth.Service exactly as for local users, so MFA, refresh token
+// rotation and revocation work unchanged.
+type LDAPProvider struct {
+	db          *gorm.DB
+	authService auth.Service
+	mfa         auth.MFAChallenger // optional; nil disables the MFA step
+
+	url          string
+	startTLS     bool
+	tlsConfig    *tls.Config
+	bindDN       string
+	bindPassword string
+	baseDN       string
+	userFilter   string
+	emailAttr    string
+	nameAttr     string
+	groupFilter  string
+	groupBaseDN  string
+	roleGroups   map[string][]string // normalized group DN -> system roles; nil leaves roles alone
+	orgGroups    []ldapOrgGroup
+}
+
+// NewLDAPProvider creates an LDAP auth provider. The server is not contacted
+// until the first sign-in.
+func NewLDAPProvider(cfg config.LDAPConfig, db *gorm.DB, authService auth.Service, mfa auth.MFAChallenger) (*LDAPProvider, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") {
+		return nil, fmt.Errorf("%w: url must start with ldap:// or ldaps://", ErrLDAPConfig)
+	}
+
+	tlsConfig := &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+	if cfg.CACertFile != "" {
+		pem, err := os.ReadFile(cfg.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrLDAPConfig, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%w: no certificates in %s", ErrLDAPConfig, cfg.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	p := &LDAPProvider{
+		db:           db,
+		authService:  authService,
+		mfa:          mfa,
+		url:          cfg.URL,
+		startTLS:     cfg.StartTLS && u.Scheme == "ldap",
+		tlsConfig:    tlsConfig,
+		bindDN:       cfg.BindDN,
+		bindPassword: cfg.BindPassword,
+		baseDN:       cfg.BaseDN,
+		userFilter:   orDefault(cfg.UserFilter, "(mail=%s)"),
+		emailAttr:    orDefault(cfg.EmailAttr, "mail"),
+		nameAttr:     orDefault(cfg.NameAttr, "cn"),
+		groupFilter:  cfg.GroupFilter,
+		groupBaseDN:  orDefault(cfg.GroupBaseDN, cfg.BaseDN),
+	}
+
+	if cfg.RoleGroups != "" {
+		p.roleGroups = map[string][]string{}
+		pairs, err := parseGroupPairs(cfg.RoleGroups)
+		if err != nil {
+			return nil, err
+		}
+		for _, pair := range pairs {
+			p.roleGroups[pair.groupDN] = append(p.roleGroups[pair.groupDN], pair.key)
+		}
+	}
+
+	pairs, err := parseGroupPairs(cfg.OrgGroups)
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range pairs {
+		slug, role, ok := strings.Cut(pair.key, "/")
+		if !ok || slug == "" {
+			return nil, fmt.Errorf("%w: org_groups entry %q must look like org-slug/role=group DN", ErrLDAPConfig, pair.key)
+		}
+		switch role {
+		case model.RoleAdmin, model.RoleDeveloper, model.RoleViewer:
+		default:
+			// Owners are managed in the app so an org can never lose its last one
+			return nil, fmt.Errorf("%w: unsupported org role %q", ErrLDAPConfig, role)
+		}
+		p.orgGroups = append(p.orgGroups, ldapOrgGroup{orgSlug: slug, role: role, groupDN: pair.groupDN})
+	}
+
+	return p, nil
+}
+
+func (p *LDAPProvider) Name() string { return "ldap" }
+
+// Register is not offered; accounts are created in the directory.
+func (p *LDAPProvider) Register(ctx context.Context, req auth.RegisterRequest) (*auth.AuthResponse, error) {
+	return nil, apiErrors.Forbidden("Accounts are managed in your organization's directory")
+}
+
+// Login binds as the user to check the password, provisions or updates the
+// local user from the directory entry and issues a session.
+func (p *LDAPProvider) Login(ctx context.Context, req auth.LoginRequest) (*auth.AuthResponse, error) {
+	// An empty password would be an unauthenticated bind, which many
+	// servers accept for any DN
+	if req.Password == "" {
+		return nil, apiErrors.Unauthorized("Invalid email or password")
+	}
+
+	entry, groups, err := p.authenticate(req.Email, req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	usr, roles, err := p.provision(ctx, req.Email, entry, groups)
+	if err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	// Users with MFA enabled get a challenge instead of tokens.
+	if p.mfa != nil {
+		enabled, err := p.mfa.IsEnabled(ctx, usr.ID)
+		if err != nil {
+			return nil, apiErrors.InternalServerError(err)
+		}
+		if enabled {
+			mfaToken, err := p.mfa.CreateChallenge(ctx, usr.ID)
+			if err != nil {
+				return nil, apiErrors.InternalServerError(err)
+			}
+			return &auth.AuthResponse{
+				MFARequired: true,
+				MFAToken:    mfaToken,
+				User: auth.UserResponse{
+					ID:        usr.ID,
+					Name:      usr.Name,
+					Email:     usr.Email,
+					AvatarURL: usr.AvatarURL,
+					Roles:     roles,
+					CreatedAt: usr.CreatedAt,
+				},
+			}, nil
+		}
+	}
+
+	tokenPair, err := p.authService.GenerateTokenPair(ctx, usr.ID, usr.Email, usr.Name, roles, []string{auth.AMRPassword})
+	if err != nil {
+		if errors.Is(err, auth.ErrSuspended) {
+			return nil, apiErrors.Forbidden("Your account is suspended")
+		}
+		return nil, err
+	}
+
+	return auth.NewAuthResponse(tokenPair, usr, roles), nil
+}
+
+// ValidateToken delegates to the auth service that issued the session.
+func (p *LDAPProvider) ValidateToken(tokenString string) (*auth.Claims, error) {
+	return p.authService.ValidateToken(tokenString)
+}
+
+// RefreshToken rotates the session and checks that the user is still in the
+// directory, so removed or disabled accounts lose access at the next refresh
+// instead of when the refresh token expires. An unreachable directory does
+// not sign anyone out.
+func (p *LDAPProvider) RefreshToken(ctx context.Context, refreshToken string) (*auth.TokenPair, error) {
+	pair, err := p.authService.RefreshAccessToken(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := p.authService.ValidateToken(pair.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := p.userExists(claims.Email)
+	if err != nil {
+		slog.Warn("Could not check directory user on refresh", "user_id", claims.UserID, "error", err)
+		return pair, nil
+	}
+	if !exists {
+		if err := p.authService.SignOutEverywhere(ctx, claims.UserID); err != nil {
+			return nil, err
+		}
+		slog.Info("Signed out user missing from directory", "user_id", claims.UserID)
+		return nil, auth.ErrInvalidToken
+	}
+	return pair, nil
+}
+
+// Logout revokes the current access token and all refresh tokens for the user.
+func (p *LDAPProvider) Logout(ctx context.Context, claims *auth.Claims) error {
+	if err := p.authService.RevokeAccessToken(ctx, claims); err != nil {
+		return err
+	}
+	if claims.IsImpersonated() {
+		return nil
+	}
+	return p.authService.RevokeAllUserTokens(ctx, claims.UserID)
+}
+
+// --- Directory access ---
+
+// authenticate finds the user's entry, binds as it with the password and
+// returns the entry with the DNs of the user's groups.
+func (p *LDAPProvider) authenticate(login, password string) (*ldap.Entry, []string, error) {
+	conn, err := p.connect()
+	if err != nil {
+		return nil, nil, apiErrors.InternalServerError(err)
+	}
+	defer conn.Close()
+
+	entry, err := p.findUser(conn, login)
+	if err != nil {
+		return nil, nil, apiErrors.InternalServerError(err)
+	}
+	if entry == nil {
+		return nil, nil, apiErrors.Unauthorized("Invalid email or password")
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, nil, apiErrors.Unauthorized("Invalid email or password")
+		}
+		return nil, nil, apiErrors.InternalServerError(fmt.Errorf("%w: %v", ErrLDAPUnavailable, err))
+	}
+
+	groups := entry.GetAttributeValues("memberOf")
+	if p.groupFilter != "" {
+		// Group searches run as the service account; users often cannot
+		// read group entries themselves
+		if err := p.bindService(conn); err != nil {
+			return nil, nil, apiErrors.InternalServerError(err)
+		}
+		if groups, err = p.findGroups(conn, entry.DN); err != nil {
+			return nil, nil, apiErrors.InternalServerError(err)
+		}
+	}
+	return entry, groups, nil
+}
+
+// userExists reports whether the directory still has a user with this email.
+func (p *LDAPProvider) userExists(email string) (bool, error) {
+	conn, err := p.connect()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	entry, err := p.findUser(conn, email)
+	return entry != nil, err
+}
+
+func (p *LDAPProvider) connect() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(p.url,
+		ldap.DialWithDialer(&net.Dialer{Timeout: ldapTimeout}),
+		ldap.DialWithTLSConfig(p.tlsConfig),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLDAPUnavailable, err)
+	}
+	conn.SetTimeout(ldapTimeout)
+
+	if p.startTLS {
+		if err := conn.StartTLS(p.tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("%w: StartTLS: %v", ErrLDAPUnavailable, err)
+		}
+	}
+	if err := p.bindService(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// bindService binds as the service account. Without one the connection
+// stays anonymous.
+func (p *LDAPProvider) bindService(conn *ldap.Conn) error {
+	if p.bindDN == "" {
+		return nil
+	}
+	if err := conn.Bind(p.bindDN, p.bindPassword); err != nil {
+		return fmt.Errorf("%w: service account bind: %v", ErrLDAPUnavailable, err)
+	}
+	return nil
+}
+
+// findUser returns the single entry matching the login, or nil when there is
+// none or the filter is ambiguous.
+func (p *LDAPProvider) findUser(conn *ldap.Conn, login string) (*ldap.Entry, error) {
+	filter := strings.ReplaceAll(p.userFilter, "%s", ldap.EscapeFilter(login))
+	req := ldap.NewSearchRequest(p.baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(ldapTimeout.Seconds()), false, filter,
+		[]string{p.emailAttr, p.nameAttr, "memberOf"}, nil)
+
+	res, err := conn.Search(req)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+			slog.Warn("LDAP user filter matched several entries", "login", login)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: user search: %v", ErrLDAPUnavailable, err)
+	}
+	if len(res.Entries) != 1 {
+		return nil, nil
+	}
+	return res.Entries[0], nil
+}
+
+func (p *LDAPProvider) findGroups(conn *ldap.Conn, userDN string) ([]string, error) {
+	filter := strings.ReplaceAll(p.groupFilter, "%s", ldap.EscapeFilter(userDN))
+	req := ldap.NewSearchRequest(p.groupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, int(ldapTimeout.Seconds()), false, filter, []string{"dn"}, nil)
+
+	res, err := conn.Search(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: group search: %v", ErrLDAPUnavailable, err)
+	}
+	groups := make([]string, 0, len(res.Entries))
+	for _, e := range res.Entries {
+		groups = append(groups, e.DN)
+	}
+	return groups, nil
+}
+
+// --- Provisioning ---
+
+// provision creates the local user on first sign-in and brings name,
+// system roles and org memberships in line with the directory.
+func (p *LDAPProvider) provision(ctx context.Context, login string, entry *ldap.Entry, groups []string) (*model.User, []string, error) {
+	email := entry.GetAttributeValue(p.emailAttr)
+	if email == "" {
+		email = login
+	}
+	name := entry.GetAttributeValue(p.nameAttr)
+	if name == "" {
+		name = email
+	}
+
+	inGroup := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		inGroup[normalizeDN(g)] = true
+	}
+
+	var usr model.User
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Preload("Roles").Where("email = ?", email).First(&usr).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			// The directory vouches for the address
+			usr = model.User{Name: name, Email: email, EmailVerified: true, AuthProvider: "ldap"}
+			if err := tx.Create(&usr).Error; err != nil {
+				return fmt.Errorf("failed to create user: %w", err)
+			}
+			slog.Info("Provisioned LDAP user", "user_id", usr.ID, "dn", entry.DN)
+			if p.roleGroups == nil {
+				if _, err := syncRoles(tx, &usr, []string{model.RoleUser}); err != nil {
+					return err
+				}
+			}
+		case err != nil:
+			return fmt.Errorf("failed to load user: %w", err)
+		default:
+			updates := map[string]interface{}{}
+			if usr.Name != name {
+				updates["name"] = name
+			}
+			if usr.AuthProvider != "ldap" {
+				updates["auth_provider"] = "ldap"
+			}
+			if len(updates) > 0 {
+				if err := tx.Model(&usr).Updates(updates).Error; err != nil {
+					return fmt.Errorf("failed to update user: %w", err)
+				}
+			}
+		}
+
+		if p.roleGroups != nil {
+			roles := []string{model.RoleUser}
+			for dn, mapped := range p.roleGroups {
+				if inGroup[dn] {
+					roles = append(roles, mapped...)
+				}
+			}
+			if _, err := syncRoles(tx, &usr, roles); err != nil {
+				return err
+			}
+		}
+		return p.syncOrgs(tx, usr.ID, inGroup)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var roles []string
+	if err := p.db.WithContext(ctx).Table("roles").Select("roles.name").
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", usr.ID).Find(&roles).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load roles: %w", err)
+	}
+	return &usr, roles, nil
+}
+
+// syncOrgs gives the user the highest mapped role in each mapped org they
+// are a group member of, and removes them from mapped orgs they no longer
+// belong to. Owners are never changed.
+func (p *LDAPProvider) syncOrgs(tx *gorm.DB, userID uuid.UUID, inGroup map[string]bool) error {
+	if len(p.orgGroups) == 0 {
+		return nil
+	}
+
+	wanted := map[string]string{} // org slug -> role, "" when not a member
+	for _, og := range p.orgGroups {
+		current, seen := wanted[og.orgSlug]
+		if !seen {
+			wanted[og.orgSlug] = ""
+		}
+		if inGroup[og.groupDN] && model.RoleHierarchy[og.role] > model.RoleHierarchy[current] {
+			wanted[og.orgSlug] = og.role
+		}
+	}
+
+	for slug, role := range wanted {
+		var org model.Org
+		if err := tx.Where("slug = ?", slug).First(&org).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				slog.Warn("LDAP org mapping names an unknown org", "slug", slug)
+				continue
+			}
+			return fmt.Errorf("failed to load org: %w", err)
+		}
+
+		var membership model.Membership
+		err := tx.Where("user_id = ? AND org_id = ?", userID, org.ID).First(&membership).Error
+		found := err == nil
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to load membership: %w", err)
+		}
+
+		switch {
+		case found && membership.Role == model.RoleOwner:
+		case role == "" && found:
+			if err := tx.Delete(&membership).Error; err != nil {
+				return fmt.Errorf("failed to remove membership: %w", err)
+			}
+			slog.Info("Removed LDAP org membership", "user_id", userID, "org", slug)
+		case role == "":
+		case !found:
+			if err := tx.Create(&model.Membership{UserID: userID, OrgID: org.ID, Role: role}).Error; err != nil {
+				return fmt.Errorf("failed to add membership: %w", err)
+			}
+			slog.Info("Added LDAP org membership", "user_id", userID, "org", slug, "role", role)
+		case membership.Role != role:
+			if err := tx.Model(&membership).Update("role", role).Error; err != nil {
+				return fmt.Errorf("failed to update membership: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// --- Helpers ---
+
+type groupPair struct {
+	key     string
+	groupDN string // normalized
+}
+
+// parseGroupPairs parses "key=group DN" pairs separated by ";". The key ends
+// at the first "=", since group DNs contain "=" themselves.
+func parseGroupPairs(s string) ([]groupPair, error) {
+	var pairs []groupPair
+	for _, item := range strings.Split(s, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, dn, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(key) == "" || strings.TrimSpace(dn) == "" {
+			return nil, fmt.Errorf("%w: group mapping %q must look like key=group DN", ErrLDAPConfig, item)
+		}
+		pairs = append(pairs, groupPair{key: strings.TrimSpace(key), groupDN: normalizeDN(dn)})
+	}
+	return pairs, nil
+}
+
+// normalizeDN makes DNs comparable regardless of case and spacing, as
+// directories compare them.
+func normalizeDN(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(dn))
+	}
+	rdns := make([]string, 0, len(parsed.RDNs))
+	for _, rdn := range parsed.RDNs {
+		attrs := make([]string, 0, len(rdn.Attributes))
+		for _, a := range rdn.Attributes {
+			attrs = append(attrs, strings.ToLower(a.Type)+"="+strings.ToLower(a.Value))
+		}
+		rdns = append(rdns, strings.Join(attrs, "+"))
+	}
+	return strings.Join(rdns, ",")
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
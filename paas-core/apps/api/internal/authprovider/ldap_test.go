@@ -0,0 +1,414 @@
+package authprovider
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+
+	"paas-core/apps/api/internal/auth"
+	"paas-core/apps/api/internal/config"
+	apiErrors "paas-core/apps/api/internal/errors"
+)
+
+const (
+	testBindDN       = "cn=svc,dc=example,dc=com"
+	testBindPassword = "svc-secret"
+)
+
+// fakeEntry is a directory entry of the fake server. Entries with a
+// password can be bound as.
+type fakeEntry struct {
+	dn       string
+	password string
+	attrs    map[string][]string
+}
+
+// fakeDirectory is a minimal in-process LDAP server: simple binds, searches
+// with equality, presence and boolean filters, and unbind.
+type fakeDirectory struct {
+	entries []fakeEntry
+	addr    string
+
+	mu      sync.Mutex
+	binds   []string // DNs of successful binds, in order
+	filters []string // filters of every search, as received
+}
+
+func newFakeDirectory(t *testing.T, entries ...fakeEntry) *fakeDirectory {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	d := &fakeDirectory{entries: entries, addr: ln.Addr().String()}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go d.serve(conn)
+		}
+	}()
+	return d
+}
+
+func (d *fakeDirectory) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		id := packet.Children[0].Value
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			code := d.bind(ber.DecodeString(op.Children[1].Data.Bytes()), ber.DecodeString(op.Children[2].Data.Bytes()))
+			d.reply(conn, id, ldapResult(ldap.ApplicationBindResponse, code))
+		case ldap.ApplicationSearchRequest:
+			d.search(conn, id, op)
+		default:
+			return
+		}
+	}
+}
+
+func (d *fakeDirectory) bind(dn, password string) uint16 {
+	if dn == testBindDN && password == testBindPassword {
+		d.recordBind(dn)
+		return ldap.LDAPResultSuccess
+	}
+	for _, e := range d.entries {
+		if e.password != "" && strings.EqualFold(e.dn, dn) && e.password == password {
+			d.recordBind(dn)
+			return ldap.LDAPResultSuccess
+		}
+	}
+	return ldap.LDAPResultInvalidCredentials
+}
+
+func (d *fakeDirectory) recordBind(dn string) {
+	d.mu.Lock()
+	d.binds = append(d.binds, dn)
+	d.mu.Unlock()
+}
+
+// recorded returns the binds and search filters seen so far and clears them.
+func (d *fakeDirectory) recorded() (binds, filters []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	binds, filters = d.binds, d.filters
+	d.binds, d.filters = nil, nil
+	return binds, filters
+}
+
+func (d *fakeDirectory) search(conn net.Conn, id interface{}, op *ber.Packet) {
+	filter := op.Children[6]
+	raw, _ := ldap.DecompileFilter(filter)
+	d.mu.Lock()
+	d.filters = append(d.filters, raw)
+	d.mu.Unlock()
+
+	sizeLimit, _ := op.Children[3].Value.(int64)
+	matched := 0
+	for _, e := range d.entries {
+		if !matchFilter(filter, e) {
+			continue
+		}
+		if matched++; sizeLimit > 0 && int64(matched) > sizeLimit {
+			d.reply(conn, id, ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSizeLimitExceeded))
+			return
+		}
+		result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+		result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.dn, "DN"))
+		attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+		for name, values := range e.attrs {
+			attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+			attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+			set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+			for _, v := range values {
+				set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "Value"))
+			}
+			attr.AppendChild(set)
+			attrs.AppendChild(attr)
+		}
+		result.AppendChild(attrs)
+		d.reply(conn, id, result)
+	}
+	d.reply(conn, id, ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess))
+}
+
+func (d *fakeDirectory) reply(conn net.Conn, id interface{}, op *ber.Packet) {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "MessageID"))
+	packet.AppendChild(op)
+	_, _ = conn.Write(packet.Bytes())
+}
+
+func ldapResult(tag ber.Tag, code uint16) *ber.Packet {
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	return result
+}
+
+// matchFilter evaluates the subset of filters the provider builds. Values are
+// compared case-insensitively, as directories do for mail and DN attributes.
+func matchFilter(f *ber.Packet, e fakeEntry) bool {
+	switch f.Tag {
+	case ldap.FilterAnd:
+		for _, child := range f.Children {
+			if !matchFilter(child, e) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range f.Children {
+			if matchFilter(child, e) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !matchFilter(f.Children[0], e)
+	case ldap.FilterEqualityMatch:
+		attr := ber.DecodeString(f.Children[0].Data.Bytes())
+		want := ber.DecodeString(f.Children[1].Data.Bytes())
+		for _, v := range e.attrs[attr] {
+			if strings.EqualFold(v, want) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterPresent:
+		return len(e.attrs[ber.DecodeString(f.Data.Bytes())]) > 0
+	}
+	return false
+}
+
+func (d *fakeDirectory) provider(t *testing.T, cfg config.LDAPConfig) *LDAPProvider {
+	t.Helper()
+	cfg.URL = "ldap://" + d.addr
+	cfg.BaseDN = "dc=example,dc=com"
+	cfg.BindDN = testBindDN
+	cfg.BindPassword = testBindPassword
+	p, err := NewLDAPProvider(cfg, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewLDAPProvider: %v", err)
+	}
+	return p
+}
+
+var (
+	ada = fakeEntry{
+		dn:       "uid=ada,ou=people,dc=example,dc=com",
+		password: "ada-secret",
+		attrs: map[string][]string{
+			"mail":     {"ada@example.com"},
+			"cn":       {"Ada Lovelace"},
+			"memberOf": {"cn=Admins,ou=groups,dc=example,dc=com"},
+		},
+	}
+	grace = fakeEntry{
+		dn:       "uid=grace,ou=people,dc=example,dc=com",
+		password: "grace-secret",
+		attrs:    map[string][]string{"mail": {"grace@example.com"}, "cn": {"Grace Hopper"}},
+	}
+	engineers = fakeEntry{
+		dn:    "cn=Engineers,ou=groups,dc=example,dc=com",
+		attrs: map[string][]string{"member": {"uid=ada,ou=people,dc=example,dc=com"}},
+	}
+)
+
+func assertStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	var apiErr *apiErrors.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != status {
+		t.Fatalf("err = %v, want HTTP %d", err, status)
+	}
+}
+
+func TestLDAPAuthenticateBindsAsUser(t *testing.T) {
+	dir := newFakeDirectory(t, ada, grace)
+	p := dir.provider(t, config.LDAPConfig{})
+
+	entry, groups, err := p.authenticate("ada@example.com", "ada-secret")
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if entry.DN != ada.dn || entry.GetAttributeValue("cn") != "Ada Lovelace" {
+		t.Errorf("entry = %s %v", entry.DN, entry.GetAttributeValue("cn"))
+	}
+	if !reflect.DeepEqual(groups, ada.attrs["memberOf"]) {
+		t.Errorf("groups = %v", groups)
+	}
+	if binds, _ := dir.recorded(); !reflect.DeepEqual(binds, []string{testBindDN, ada.dn}) {
+		t.Errorf("binds = %v, want service account then user", binds)
+	}
+}
+
+func TestLDAPAuthenticateRejects(t *testing.T) {
+	dir := newFakeDirectory(t, ada, grace)
+	p := dir.provider(t, config.LDAPConfig{})
+
+	tests := []struct {
+		name, login, password string
+	}{
+		{"wrong password", "ada@example.com", "grace-secret"},
+		{"unknown user", "alan@example.com", "ada-secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := p.authenticate(tt.login, tt.password)
+			assertStatus(t, err, http.StatusUnauthorized)
+		})
+	}
+}
+
+func TestLDAPLoginRejectsEmptyPassword(t *testing.T) {
+	dir := newFakeDirectory(t, ada)
+	p := dir.provider(t, config.LDAPConfig{})
+
+	_, err := p.Login(context.Background(), auth.LoginRequest{Email: "ada@example.com"})
+	assertStatus(t, err, http.StatusUnauthorized)
+	if binds, _ := dir.recorded(); len(binds) != 0 {
+		t.Errorf("binds = %v, want none", binds)
+	}
+}
+
+func TestLDAPServiceAccountBindFailure(t *testing.T) {
+	dir := newFakeDirectory(t, ada)
+	p := dir.provider(t, config.LDAPConfig{})
+	p.bindPassword = "wrong"
+
+	_, _, err := p.authenticate("ada@example.com", "ada-secret")
+	assertStatus(t, err, http.StatusInternalServerError)
+}
+
+func TestLDAPFilterEscaping(t *testing.T) {
+	dir := newFakeDirectory(t, ada, grace)
+	p := dir.provider(t, config.LDAPConfig{})
+
+	logins := []struct {
+		login, filter string
+	}{
+		{"*", `(mail=\2a)`},
+		{"ada@example.com)(mail=*", `(mail=ada@example.com\29\28mail=\2a)`},
+		{`x\2a`, `(mail=x\5c2a)`},
+	}
+	for _, tt := range logins {
+		t.Run(tt.login, func(t *testing.T) {
+			_, _, err := p.authenticate(tt.login, "ada-secret")
+			assertStatus(t, err, http.StatusUnauthorized)
+			if _, filters := dir.recorded(); len(filters) != 1 || filters[0] != tt.filter {
+				t.Errorf("filters = %v, want [%s]", filters, tt.filter)
+			}
+		})
+	}
+}
+
+func TestLDAPGroupSearch(t *testing.T) {
+	dir := newFakeDirectory(t, ada, engineers)
+	p := dir.provider(t, config.LDAPConfig{GroupFilter: "(member=%s)"})
+
+	_, groups, err := p.authenticate("ada@example.com", "ada-secret")
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if !reflect.DeepEqual(groups, []string{engineers.dn}) {
+		t.Errorf("groups = %v, want [%s]", groups, engineers.dn)
+	}
+	// Groups are searched as the service account, not as the user
+	binds, filters := dir.recorded()
+	if want := []string{testBindDN, ada.dn, testBindDN}; !reflect.DeepEqual(binds, want) {
+		t.Errorf("binds = %v, want %v", binds, want)
+	}
+	if want := []string{"(mail=ada@example.com)", "(member=uid=ada,ou=people,dc=example,dc=com)"}; !reflect.DeepEqual(filters, want) {
+		t.Errorf("filters = %v, want %v", filters, want)
+	}
+}
+
+func TestLDAPUserExists(t *testing.T) {
+	dir := newFakeDirectory(t, ada)
+	p := dir.provider(t, config.LDAPConfig{})
+
+	for login, want := range map[string]bool{"ada@example.com": true, "grace@example.com": false} {
+		exists, err := p.userExists(login)
+		if err != nil || exists != want {
+			t.Errorf("userExists(%q) = %v, %v; want %v", login, exists, err, want)
+		}
+	}
+}
+
+func TestNewLDAPProviderGroupMapping(t *testing.T) {
+	cfg := config.LDAPConfig{
+		URL:        "ldap://localhost",
+		BaseDN:     "dc=example,dc=com",
+		RoleGroups: "admin=CN=Admins, OU=Groups,DC=Example,DC=com; support=cn=admins,ou=groups,dc=example,dc=com",
+		OrgGroups:  "acme/developer=cn=Engineers,ou=groups,dc=example,dc=com;acme/admin=cn=Leads,ou=groups,dc=example,dc=com",
+	}
+	p, err := NewLDAPProvider(cfg, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewLDAPProvider: %v", err)
+	}
+
+	wantRoles := map[string][]string{"cn=admins,ou=groups,dc=example,dc=com": {"admin", "support"}}
+	if !reflect.DeepEqual(p.roleGroups, wantRoles) {
+		t.Errorf("roleGroups = %v, want %v", p.roleGroups, wantRoles)
+	}
+	wantOrgs := []ldapOrgGroup{
+		{orgSlug: "acme", role: "developer", groupDN: "cn=engineers,ou=groups,dc=example,dc=com"},
+		{orgSlug: "acme", role: "admin", groupDN: "cn=leads,ou=groups,dc=example,dc=com"},
+	}
+	if !reflect.DeepEqual(p.orgGroups, wantOrgs) {
+		t.Errorf("orgGroups = %+v, want %+v", p.orgGroups, wantOrgs)
+	}
+}
+
+func TestNewLDAPProviderRejectsBadMappings(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.LDAPConfig
+	}{
+		{"bad scheme", config.LDAPConfig{URL: "http://localhost"}},
+		{"role without group", config.LDAPConfig{URL: "ldap://localhost", RoleGroups: "admin="}},
+		{"org without slug", config.LDAPConfig{URL: "ldap://localhost", OrgGroups: "admin=cn=x"}},
+		{"org owner", config.LDAPConfig{URL: "ldap://localhost", OrgGroups: "acme/owner=cn=x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewLDAPProvider(tt.cfg, nil, nil, nil); !errors.Is(err, ErrLDAPConfig) {
+				t.Fatalf("err = %v, want ErrLDAPConfig", err)
+			}
+		})
+	}
+}
+
+func TestNormalizeDN(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"CN=Admins, OU=Groups,DC=Example,DC=com", "cn=admins,ou=groups,dc=example,dc=com"},
+		{"cn=a+uid=b,dc=x", "cn=a+uid=b,dc=x"},
+		{"  Not A DN ", "not a dn"},
+	}
+	for _, tt := range tests {
+		if got := normalizeDN(tt.in); got != tt.want {
+			t.Errorf("normalizeDN(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
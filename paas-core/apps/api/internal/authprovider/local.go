@@ -2,18 +2,21 @@ package authprovider
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
 	"paas-core/apps/api/internal/auth"
+	apiErrors "paas-core/apps/api/internal/errors"
 )
 
 // UserService is the minimal interface the local provider needs from the user domain.
 type UserService interface {
 	RegisterUser(ctx *gin.Context, req auth.RegisterRequest) (*auth.UserResponse, []string, error)
 	AuthenticateUser(ctx *gin.Context, req auth.LoginRequest) (*auth.UserResponse, []string, error)
+	ImportPassword(ctx context.Context, userID uuid.UUID, password string) error
 }
 
 // LocalProvider wraps the existing auth.Service + UserService to implement
@@ -21,13 +24,15 @@ type UserService interface {
 type LocalProvider struct {
 	authService auth.Service
 	userService UserService
+	mfa         auth.MFAChallenger // optional; nil disables the MFA step
 }
 
 // NewLocalProvider creates a local auth provider from the existing services.
-func NewLocalProvider(authService auth.Service, userService UserService) *LocalProvider {
+func NewLocalProvider(authService auth.Service, userService UserService, mfa auth.MFAChallenger) *LocalProvider {
 	return &LocalProvider{
 		authService: authService,
 		userService: userService,
+		mfa:         mfa,
 	}
 }
 
@@ -44,8 +49,11 @@ func (p *LocalProvider) Register(ctx context.Context, req auth.RegisterRequest)
 		return nil, err
 	}
 
-	tokenPair, err := p.authService.GenerateTokenPair(ctx, userResp.ID, userResp.Email, userResp.Name, roles)
+	tokenPair, err := p.authService.GenerateTokenPair(ctx, userResp.ID, userResp.Email, userResp.Name, roles, []string{auth.AMRPassword})
 	if err != nil {
+		if errors.Is(err, auth.ErrSuspended) {
+			return nil, apiErrors.Forbidden("Your account is suspended")
+		}
 		return nil, err
 	}
 
@@ -67,8 +75,30 @@ func (p *LocalProvider) Login(ctx context.Context, req auth.LoginRequest) (*auth
 		return nil, err
 	}
 
-	tokenPair, err := p.authService.GenerateTokenPair(ctx, userResp.ID, userResp.Email, userResp.Name, roles)
+	// Users with MFA enabled get a challenge instead of tokens.
+	if p.mfa != nil {
+		enabled, err := p.mfa.IsEnabled(ctx, userResp.ID)
+		if err != nil {
+			return nil, apiErrors.InternalServerError(err)
+		}
+		if enabled {
+			mfaToken, err := p.mfa.CreateChallenge(ctx, userResp.ID)
+			if err != nil {
+				return nil, apiErrors.InternalServerError(err)
+			}
+			return &auth.AuthResponse{
+				MFARequired: true,
+				MFAToken:    mfaToken,
+				User:        *userResp,
+			}, nil
+		}
+	}
+
+	tokenPair, err := p.authService.GenerateTokenPair(ctx, userResp.ID, userResp.Email, userResp.Name, roles, []string{auth.AMRPassword})
 	if err != nil {
+		if errors.Is(err, auth.ErrSuspended) {
+			return nil, apiErrors.Forbidden("Your account is suspended")
+		}
 		return nil, err
 	}
 
@@ -91,9 +121,23 @@ func (p *LocalProvider) RefreshToken(ctx context.Context, refreshToken string) (
 	return p.authService.RefreshAccessToken(ctx, refreshToken)
 }
 
-// Logout revokes all refresh tokens for the user.
-func (p *LocalProvider) Logout(ctx context.Context, userID uuid.UUID) error {
-	return p.authService.RevokeAllUserTokens(ctx, userID)
+// Logout revokes the current access token and all refresh tokens for the user.
+// Ending an impersonation session only revokes the impersonation token, so
+// the user stays signed in.
+func (p *LocalProvider) Logout(ctx context.Context, claims *auth.Claims) error {
+	if err := p.authService.RevokeAccessToken(ctx, claims); err != nil {
+		return err
+	}
+	if claims.IsImpersonated() {
+		return nil
+	}
+	return p.authService.RevokeAllUserTokens(ctx, claims.UserID)
+}
+
+// ImportPasswordUser gives a user who signed in at another provider a local
+// password. Their users row already exists, created by the webhook sync.
+func (p *LocalProvider) ImportPasswordUser(ctx context.Context, user auth.UserResponse, password string) error {
+	return p.userService.ImportPassword(ctx, user.ID, password)
 }
 
 // newGinContext creates a minimal *gin.Context that wraps a context.Context.
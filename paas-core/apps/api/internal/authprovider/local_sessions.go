@@ -0,0 +1,97 @@
+package authprovider
+
+import (
+	"context"
+	"errors"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"paas-core/apps/api/internal/auth"
+	apiErrors "paas-core/apps/api/internal/errors"
+)
+
+// LocalSessionsProvider puts an external provider (Supabase, OIDC) in charge
+// of sign-up and password login while still honoring the sessions the API
+// signs itself: magic link, OAuth, passkey and MFA logins, re-authentication,
+// impersonation and service account tokens. Tokens are told apart by "iss";
+// locally signed ones carry jwt.issuer, which may be empty. MFA enrolled with
+// the API is enforced on password logins too.
+type LocalSessionsProvider struct {
+	AuthProvider
+	local       *LocalProvider
+	localIssuer string
+}
+
+// WithLocalSessions wraps external so locally signed tokens stay valid.
+func WithLocalSessions(external AuthProvider, local *LocalProvider, localIssuer string) *LocalSessionsProvider {
+	return &LocalSessionsProvider{AuthProvider: external, local: local, localIssuer: localIssuer}
+}
+
+// Login signs the user in at the external provider. Users who enrolled MFA
+// with the API get its challenge instead of the provider's tokens, which are
+// dropped; the locally signed session is issued once the challenge is met.
+func (p *LocalSessionsProvider) Login(ctx context.Context, req auth.LoginRequest) (*auth.AuthResponse, error) {
+	resp, err := p.AuthProvider.Login(ctx, req)
+	if err != nil || resp.MFARequired || p.local.mfa == nil {
+		return resp, err
+	}
+
+	enabled, err := p.local.mfa.IsEnabled(ctx, resp.User.ID)
+	if err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+	if !enabled {
+		return resp, nil
+	}
+	mfaToken, err := p.local.mfa.CreateChallenge(ctx, resp.User.ID)
+	if err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+	return &auth.AuthResponse{
+		MFARequired: true,
+		MFAToken:    mfaToken,
+		User:        resp.User,
+	}, nil
+}
+
+// ValidateToken validates tokens issued by the API locally and hands every
+// other token to the external provider.
+func (p *LocalSessionsProvider) ValidateToken(tokenString string) (*auth.Claims, error) {
+	if p.isLocal(tokenString) {
+		return p.local.ValidateToken(tokenString)
+	}
+	return p.AuthProvider.ValidateToken(tokenString)
+}
+
+// RefreshToken tries the external provider first, then local sessions,
+// since refresh tokens are opaque. A detected token reuse stops the search.
+func (p *LocalSessionsProvider) RefreshToken(ctx context.Context, refreshToken string) (*auth.TokenPair, error) {
+	pair, err := p.AuthProvider.RefreshToken(ctx, refreshToken)
+	if err == nil || errors.Is(err, auth.ErrTokenReuse) {
+		return pair, err
+	}
+	if localPair, localErr := p.local.RefreshToken(ctx, refreshToken); localErr == nil || errors.Is(localErr, auth.ErrTokenReuse) {
+		return localPair, localErr
+	}
+	return nil, err
+}
+
+// Logout ends the session at whichever side issued the token. Signing out
+// of the external provider also ends the user's local sessions.
+func (p *LocalSessionsProvider) Logout(ctx context.Context, claims *auth.Claims) error {
+	if claims.Issuer == p.localIssuer {
+		return p.local.Logout(ctx, claims)
+	}
+	return errors.Join(
+		p.AuthProvider.Logout(ctx, claims),
+		p.local.authService.RevokeAllUserTokens(ctx, claims.UserID),
+	)
+}
+
+func (p *LocalSessionsProvider) isLocal(tokenString string) bool {
+	var unverified jwt.RegisteredClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, &unverified); err != nil {
+		return false
+	}
+	return unverified.Issuer == p.localIssuer
+}
@@ -0,0 +1,59 @@
+package authprovider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"paas-core/apps/api/internal/auth"
+	"paas-core/apps/api/internal/config"
+)
+
+// fakeMFA has MFA enabled for the users in enabled.
+type fakeMFA struct {
+	enabled    map[uuid.UUID]bool
+	challenged []uuid.UUID
+}
+
+func (f *fakeMFA) IsEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	return f.enabled[userID], nil
+}
+
+func (f *fakeMFA) CreateChallenge(ctx context.Context, userID uuid.UUID) (string, error) {
+	f.challenged = append(f.challenged, userID)
+	return "mfa-token", nil
+}
+
+func TestLocalSessionsLoginChallengesMFA(t *testing.T) {
+	iss := newTestIssuer(t)
+	external := iss.provider(t, config.OIDCConfig{PasswordGrant: true})
+	userID := uuid.MustParse(iss.grantSub)
+	req := auth.LoginRequest{Email: "ada@example.com", Password: "secret"}
+
+	mfa := &fakeMFA{enabled: map[uuid.UUID]bool{}}
+	p := WithLocalSessions(external, &LocalProvider{mfa: mfa}, "local-issuer")
+
+	resp, err := p.Login(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if resp.MFARequired || resp.AccessToken == "" {
+		t.Fatalf("without MFA: MFARequired = %v, AccessToken = %q", resp.MFARequired, resp.AccessToken)
+	}
+
+	mfa.enabled[userID] = true
+	resp, err = p.Login(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if !resp.MFARequired || resp.MFAToken != "mfa-token" {
+		t.Fatalf("with MFA: MFARequired = %v, MFAToken = %q", resp.MFARequired, resp.MFAToken)
+	}
+	if resp.AccessToken != "" || resp.RefreshToken != "" {
+		t.Error("external tokens were handed out before the challenge")
+	}
+	if len(mfa.challenged) != 1 || mfa.challenged[0] != userID {
+		t.Errorf("challenged = %v, want [%s]", mfa.challenged, userID)
+	}
+}
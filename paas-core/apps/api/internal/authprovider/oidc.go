@@ -0,0 +1,494 @@
+package authprovider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"paas-core/apps/api/internal/auth"
+	"paas-core/apps/api/internal/config"
+	apiErrors "paas-core/apps/api/internal/errors"
+	"paas-core/apps/api/internal/model"
+)
+
+// Sentinel errors for OIDC operations.
+var (
+	ErrOIDCDiscovery = errors.New("oidc discovery failed")
+	ErrOIDCRequest   = errors.New("oidc request failed")
+)
+
+const defaultOIDCScopes = "openid profile email offline_access"
+
+// oidcUserSyncInterval bounds how long a changed name at the identity
+// provider takes to reach the local users row.
+const oidcUserSyncInterval = 5 * time.Minute
+
+// OIDCProvider implements AuthProvider on top of any OpenID Connect issuer
+// (Keycloak, Auth0, Zitadel, ...). Access tokens are verified against the
+// issuer's JWKS; sign-in uses the password grant when allowed and refresh
+// goes through the token endpoint.
+//
+// Users are created locally, keyed by email, the first time one of their
+// tokens is seen. New users whose subject is not a UUID (e.g. "auth0|abc")
+// get a stable UUID derived from the issuer and subject.
+type OIDCProvider struct {
+	db            *gorm.DB
+	issuer        string
+	clientID      string
+	clientSecret  string
+	audience      string
+	scopes        string
+	emailClaim    string
+	nameClaim     string
+	rolesClaim    string
+	passwordGrant bool
+	tokenEndpoint string
+	keys          *remoteKeySet
+	revocations   *auth.RevocationStore // logout is enforced locally
+	httpClient    *http.Client
+
+	users sync.Map // subject -> provisionedUser, spares a query per request
+}
+
+// provisionedUser is the local user a subject was last synced to.
+type provisionedUser struct {
+	id       uuid.UUID
+	email    string
+	name     string
+	syncedAt time.Time
+}
+
+// oidcDiscovery is the subset of the provider metadata document we use.
+type oidcDiscovery struct {
+	Issuer        string `json:"issuer"`
+	TokenEndpoint string `json:"token_endpoint"`
+	JWKSURI       string `json:"jwks_uri"`
+}
+
+type oidcTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type oidcErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// NewOIDCProvider reads the issuer's discovery document and creates the
+// provider. Logouts are recorded in revocations, since OIDC has no standard
+// way to end every session of a user from the API.
+func NewOIDCProvider(ctx context.Context, cfg config.OIDCConfig, db *gorm.DB, revocations *auth.RevocationStore) (*OIDCProvider, error) {
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+
+	doc, err := discoverOIDC(ctx, httpClient, cfg.Issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &OIDCProvider{
+		db:            db,
+		issuer:        doc.Issuer,
+		clientID:      cfg.ClientID,
+		clientSecret:  cfg.ClientSecret,
+		audience:      cfg.Audience,
+		scopes:        cfg.Scopes,
+		emailClaim:    cfg.EmailClaim,
+		nameClaim:     cfg.NameClaim,
+		rolesClaim:    cfg.RolesClaim,
+		passwordGrant: cfg.PasswordGrant,
+		tokenEndpoint: doc.TokenEndpoint,
+		keys:          newRemoteKeySet(doc.JWKSURI, httpClient),
+		revocations:   revocations,
+		httpClient:    httpClient,
+	}
+	if p.scopes == "" {
+		p.scopes = defaultOIDCScopes
+	}
+	if p.emailClaim == "" {
+		p.emailClaim = "email"
+	}
+	if p.nameClaim == "" {
+		p.nameClaim = "name"
+	}
+	return p, nil
+}
+
+func (p *OIDCProvider) Name() string { return "oidc" }
+
+// Issuer returns the "iss" of the IdP's access tokens.
+func (p *OIDCProvider) Issuer() string { return p.issuer }
+
+// Register is not supported; accounts are created at the identity provider.
+func (p *OIDCProvider) Register(ctx context.Context, req auth.RegisterRequest) (*auth.AuthResponse, error) {
+	return nil, apiErrors.Forbidden("Sign-up is handled by the identity provider")
+}
+
+// Login exchanges the email and password for tokens with the password grant.
+func (p *OIDCProvider) Login(ctx context.Context, req auth.LoginRequest) (*auth.AuthResponse, error) {
+	if !p.passwordGrant {
+		return nil, apiErrors.Forbidden("Password sign-in is disabled; sign in through the identity provider")
+	}
+
+	resp, err := p.requestToken(ctx, url.Values{
+		"grant_type": {"password"},
+		"username":   {req.Email},
+		"password":   {req.Password},
+		"scope":      {p.scopes},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := p.ValidateToken(resp.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: issued access token is not valid: %v", ErrOIDCRequest, err)
+	}
+
+	return &auth.AuthResponse{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		TokenType:    resp.TokenType,
+		ExpiresIn:    resp.ExpiresIn,
+		User: auth.UserResponse{
+			ID:    claims.UserID,
+			Name:  claims.Name,
+			Email: claims.Email,
+			Roles: claims.Roles,
+		},
+	}, nil
+}
+
+// ValidateToken verifies an access token signed by the issuer and maps the
+// configured claims onto auth.Claims.
+func (p *OIDCProvider) ValidateToken(tokenString string) (*auth.Claims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(asymmetricMethods),
+		jwt.WithIssuer(p.issuer),
+		jwt.WithExpirationRequired(),
+	}
+	if p.audience != "" {
+		opts = append(opts, jwt.WithAudience(p.audience))
+	}
+
+	token, err := jwt.Parse(tokenString, p.keys.Keyfunc, opts...)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, auth.ErrExpiredToken
+		}
+		return nil, auth.ErrInvalidToken
+	}
+
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, auth.ErrInvalidToken
+	}
+
+	sub, _ := mapClaims.GetSubject()
+	if sub == "" {
+		return nil, fmt.Errorf("%w: missing subject", auth.ErrInvalidToken)
+	}
+
+	email, _ := claimValue(mapClaims, p.emailClaim).(string)
+	name, _ := claimValue(mapClaims, p.nameClaim).(string)
+	if name == "" {
+		name, _ = mapClaims["preferred_username"].(string)
+	}
+	emailVerified, _ := mapClaims["email_verified"].(bool)
+	userID, err := p.provision(context.Background(), sub, email, name, emailVerified)
+	if err != nil {
+		return nil, err
+	}
+	roles := []string{}
+	if p.rolesClaim != "" {
+		roles = claimStrings(claimValue(mapClaims, p.rolesClaim))
+	}
+
+	iat, _ := mapClaims.GetIssuedAt()
+	exp, _ := mapClaims.GetExpirationTime()
+	jti, _ := mapClaims["jti"].(string)
+	if jti == "" {
+		jti = uuid.New().String()
+	}
+
+	claims := &auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    p.issuer,
+			Subject:   sub,
+			IssuedAt:  iat,
+			ExpiresAt: exp,
+			ID:        jti,
+		},
+		UserID: userID,
+		Email:  email,
+		Name:   name,
+		Roles:  roles,
+	}
+	if amr := claimStrings(mapClaims["amr"]); len(amr) > 0 {
+		claims.AMR = amr
+	}
+	if authTime, ok := mapClaims["auth_time"].(float64); ok {
+		claims.AuthTime = jwt.NewNumericDate(time.Unix(int64(authTime), 0))
+	}
+
+	if p.revocations.IsRevoked(claims) {
+		return nil, auth.ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+// RefreshToken exchanges a refresh token at the issuer's token endpoint.
+func (p *OIDCProvider) RefreshToken(ctx context.Context, refreshToken string) (*auth.TokenPair, error) {
+	resp, err := p.requestToken(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Issuers that do not rotate refresh tokens omit it from the response
+	next := resp.RefreshToken
+	if next == "" {
+		next = refreshToken
+	}
+
+	return &auth.TokenPair{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: next,
+		TokenType:    resp.TokenType,
+		ExpiresIn:    resp.ExpiresIn,
+	}, nil
+}
+
+// Logout rejects every access token issued to the user so far. The session
+// at the identity provider itself is ended by the frontend through the
+// issuer's end_session_endpoint.
+func (p *OIDCProvider) Logout(ctx context.Context, claims *auth.Claims) error {
+	return p.revocations.RevokeAllBefore(ctx, claims.UserID, time.Now())
+}
+
+// provision returns the local user for the subject, creating it on first
+// sight, so memberships, tokens and audit rows have a user to point to. An
+// existing account is only taken over when the issuer has verified the
+// address; users who still have a local password keep their provider.
+func (p *OIDCProvider) provision(ctx context.Context, sub, email, name string, emailVerified bool) (uuid.UUID, error) {
+	if cached, ok := p.users.Load(sub); ok {
+		u := cached.(provisionedUser)
+		if u.email == email && u.name == name && time.Since(u.syncedAt) < oidcUserSyncInterval {
+			return u.id, nil
+		}
+	}
+	if email == "" {
+		return uuid.Nil, fmt.Errorf("%w: missing email", auth.ErrInvalidToken)
+	}
+	if name == "" {
+		name = email
+	}
+
+	var usr model.User
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("email = ?", email).First(&usr).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			usr = model.User{Name: name, Email: email, EmailVerified: emailVerified, AuthProvider: "oidc"}
+			usr.ID = oidcUserID(p.issuer, sub)
+			if err := tx.Create(&usr).Error; err != nil {
+				return fmt.Errorf("failed to create user: %w", err)
+			}
+			slog.Info("Provisioned OIDC user", "user_id", usr.ID)
+			_, err := syncRoles(tx, &usr, []string{model.RoleUser})
+			return err
+		case err != nil:
+			return fmt.Errorf("failed to load user: %w", err)
+		case usr.AuthProvider != "oidc" && !emailVerified:
+			return fmt.Errorf("%w: email is not verified by the identity provider", auth.ErrInvalidToken)
+		}
+
+		updates := map[string]interface{}{}
+		if usr.Name != name {
+			updates["name"] = name
+		}
+		if emailVerified && !usr.EmailVerified {
+			updates["email_verified"] = true
+		}
+		if usr.AuthProvider != "oidc" && !usr.HasPassword() {
+			updates["auth_provider"] = "oidc"
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		if err := tx.Model(&usr).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return uuid.Nil, err
+		}
+		slog.Error("Failed to provision OIDC user", "error", err)
+		return uuid.Nil, auth.ErrInvalidToken
+	}
+
+	p.users.Store(sub, provisionedUser{id: usr.ID, email: email, name: name, syncedAt: time.Now()})
+	return usr.ID, nil
+}
+
+// --- Helpers ---
+
+// oidcUserID keeps UUID subjects and derives a stable UUID from the issuer
+// and subject otherwise.
+func oidcUserID(issuer, sub string) uuid.UUID {
+	if id, err := uuid.Parse(sub); err == nil {
+		return id
+	}
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(issuer+"#"+sub))
+}
+
+// discoverOIDC fetches <issuer>/.well-known/openid-configuration and checks
+// that the document belongs to the configured issuer.
+func discoverOIDC(ctx context.Context, httpClient *http.Client, issuer string) (*oidcDiscovery, error) {
+	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCDiscovery, err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCDiscovery, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s returned HTTP %d", ErrOIDCDiscovery, wellKnown, resp.StatusCode)
+	}
+
+	var doc oidcDiscovery
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: failed to parse discovery document: %v", ErrOIDCDiscovery, err)
+	}
+	if strings.TrimSuffix(doc.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return nil, fmt.Errorf("%w: discovery issuer %q does not match %q", ErrOIDCDiscovery, doc.Issuer, issuer)
+	}
+	if doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: discovery document lacks token_endpoint or jwks_uri", ErrOIDCDiscovery)
+	}
+	return &doc, nil
+}
+
+// requestToken posts a grant to the token endpoint. Grant errors reported by
+// the issuer become 401s.
+func (p *OIDCProvider) requestToken(ctx context.Context, form url.Values) (*oidcTokenResponse, error) {
+	form.Set("client_id", p.clientID)
+	if p.clientSecret != "" {
+		form.Set("client_secret", p.clientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to create request: %v", ErrOIDCRequest, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCRequest, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read response: %v", ErrOIDCRequest, err)
+	}
+
+	if resp.StatusCode >= 400 {
+		var errResp oidcErrorResponse
+		_ = json.Unmarshal(respBody, &errResp)
+		if resp.StatusCode < 500 && (errResp.Error == "invalid_grant" || errResp.Error == "unauthorized_client" || resp.StatusCode == http.StatusUnauthorized) {
+			if form.Get("grant_type") == "password" {
+				return nil, apiErrors.Unauthorized("Invalid email or password")
+			}
+			return nil, apiErrors.Unauthorized("Refresh token rejected by the identity provider")
+		}
+		msg := errResp.ErrorDescription
+		if msg == "" {
+			msg = errResp.Error
+		}
+		if msg == "" {
+			msg = fmt.Sprintf("HTTP %d", resp.StatusCode)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrOIDCRequest, msg)
+	}
+
+	var tokenResp oidcTokenResponse
+	if err := json.Unmarshal(respBody, &tokenResp); err != nil {
+		return nil, fmt.Errorf("%w: failed to parse response: %v", ErrOIDCRequest, err)
+	}
+	if tokenResp.AccessToken == "" {
+		return nil, fmt.Errorf("%w: token response has no access_token", ErrOIDCRequest)
+	}
+	if tokenResp.TokenType == "" {
+		tokenResp.TokenType = "Bearer"
+	}
+	return &tokenResp, nil
+}
+
+// claimValue looks up a claim by name or, failing that, by a dot-separated
+// path into nested objects ("realm_access.roles"). Whole names are tried
+// first because namespaced claims such as "https://example.com/roles" contain
+// dots themselves.
+func claimValue(claims map[string]interface{}, path string) interface{} {
+	if v, ok := claims[path]; ok {
+		return v
+	}
+	var cur interface{} = claims
+	for _, part := range strings.Split(path, ".") {
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur = obj[part]
+	}
+	return cur
+}
+
+// claimStrings flattens a roles-like claim: a list of strings, a
+// space-separated string, or an object whose keys are the values (Zitadel).
+func claimStrings(v interface{}) []string {
+	out := []string{}
+	switch val := v.(type) {
+	case []interface{}:
+		for _, item := range val {
+			if s, ok := item.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+	case string:
+		out = append(out, strings.Fields(val)...)
+	case map[string]interface{}:
+		for key := range val {
+			out = append(out, key)
+		}
+		sort.Strings(out)
+	}
+	return out
+}
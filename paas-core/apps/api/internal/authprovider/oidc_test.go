@@ -0,0 +1,340 @@
+package authprovider
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"paas-core/apps/api/internal/auth"
+	"paas-core/apps/api/internal/config"
+	"paas-core/apps/api/internal/database/dbtest"
+	apiErrors "paas-core/apps/api/internal/errors"
+	"paas-core/apps/api/internal/model"
+	"paas-core/apps/api/internal/org"
+)
+
+// testIssuer is an OpenID Connect issuer served by httptest: discovery, a
+// JWKS with one Ed25519 key and a token endpoint for the password grant.
+type testIssuer struct {
+	server    *httptest.Server
+	db        *gorm.DB // local users are provisioned here
+	key       ed25519.PrivateKey
+	kid       string
+	docIssuer string // "issuer" in the discovery document; defaults to the server URL
+	password  string // accepted by the password grant
+	grantSub  string // subject of tokens issued by the password grant
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iss := &testIssuer{
+		db:       dbtest.Open(t, &model.User{}, &model.Role{}, &model.UserRole{}, &model.Org{}, &model.Membership{}),
+		key:      private,
+		kid:      "test-key",
+		password: "secret",
+		grantSub: uuid.NewString(),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		issuer := iss.docIssuer
+		if issuer == "" {
+			issuer = iss.server.URL
+		}
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":         issuer,
+			"token_endpoint": iss.server.URL + "/token",
+			"jwks_uri":       iss.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, auth.JWKS{Keys: []auth.JWK{{
+			Kty: "OKP",
+			Kid: iss.kid,
+			Use: "sig",
+			Alg: "EdDSA",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(public),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("grant_type") != "password" || r.PostFormValue("password") != iss.password {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"access_token":  iss.sign(t, jwt.MapClaims{"sub": iss.grantSub, "email": r.PostFormValue("username")}),
+			"refresh_token": "refresh",
+			"expires_in":    300,
+		})
+	})
+	iss.server = httptest.NewServer(mux)
+	t.Cleanup(iss.server.Close)
+	return iss
+}
+
+// sign issues a token from the test issuer. iss and exp are filled in unless
+// claims sets them.
+func (i *testIssuer) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	if _, ok := claims["iss"]; !ok {
+		claims["iss"] = i.server.URL
+	}
+	if _, ok := claims["exp"]; !ok {
+		claims["exp"] = time.Now().Add(time.Minute).Unix()
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = i.kid
+	signed, err := token.SignedString(i.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func (i *testIssuer) provider(t *testing.T, cfg config.OIDCConfig) *OIDCProvider {
+	t.Helper()
+	cfg.Issuer = i.server.URL
+	p, err := NewOIDCProvider(context.Background(), cfg, i.db, &auth.RevocationStore{})
+	if err != nil {
+		t.Fatalf("NewOIDCProvider: %v", err)
+	}
+	return p
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestNewOIDCProviderRejectsForeignIssuer(t *testing.T) {
+	iss := newTestIssuer(t)
+	iss.docIssuer = "https://attacker.example.com"
+
+	_, err := NewOIDCProvider(context.Background(), config.OIDCConfig{Issuer: iss.server.URL}, iss.db, &auth.RevocationStore{})
+	if !errors.Is(err, ErrOIDCDiscovery) {
+		t.Fatalf("err = %v, want ErrOIDCDiscovery", err)
+	}
+}
+
+func TestOIDCValidateTokenMapsClaims(t *testing.T) {
+	iss := newTestIssuer(t)
+	p := iss.provider(t, config.OIDCConfig{RolesClaim: "realm_access.roles"})
+
+	token := iss.sign(t, jwt.MapClaims{
+		"sub":                "auth0|abc",
+		"email":              "ada@example.com",
+		"preferred_username": "ada",
+		"realm_access":       map[string]interface{}{"roles": []string{"admin", "user"}},
+		"amr":                []string{"pwd"},
+	})
+
+	claims, err := p.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	wantID := uuid.NewSHA1(uuid.NameSpaceURL, []byte(iss.server.URL+"#auth0|abc"))
+	if claims.UserID != wantID {
+		t.Errorf("UserID = %s, want %s", claims.UserID, wantID)
+	}
+	if claims.Email != "ada@example.com" || claims.Name != "ada" {
+		t.Errorf("Email, Name = %q, %q", claims.Email, claims.Name)
+	}
+	if !reflect.DeepEqual(claims.Roles, []string{"admin", "user"}) {
+		t.Errorf("Roles = %v", claims.Roles)
+	}
+	if !reflect.DeepEqual(claims.AMR, []string{"pwd"}) {
+		t.Errorf("AMR = %v", claims.AMR)
+	}
+}
+
+func TestOIDCValidateTokenKeepsUUIDSubjects(t *testing.T) {
+	iss := newTestIssuer(t)
+	p := iss.provider(t, config.OIDCConfig{})
+
+	sub := uuid.New()
+	claims, err := p.ValidateToken(iss.sign(t, jwt.MapClaims{"sub": sub.String(), "email": "ada@example.com"}))
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if claims.UserID != sub {
+		t.Errorf("UserID = %s, want %s", claims.UserID, sub)
+	}
+}
+
+func TestOIDCValidateTokenRejects(t *testing.T) {
+	iss := newTestIssuer(t)
+	p := iss.provider(t, config.OIDCConfig{Audience: "api"})
+
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	foreign := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{
+		"sub": "user", "email": "ada@example.com", "aud": "api", "iss": iss.server.URL, "exp": time.Now().Add(time.Minute).Unix(),
+	})
+	foreign.Header["kid"] = iss.kid
+	foreignSigned, _ := foreign.SignedString(otherKey)
+
+	hmacSigned, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "user", "aud": "api", "iss": iss.server.URL, "exp": time.Now().Add(time.Minute).Unix(),
+	}).SignedString([]byte("secret"))
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"expired", iss.sign(t, jwt.MapClaims{"sub": "user", "aud": "api", "exp": time.Now().Add(-time.Minute).Unix()}), auth.ErrExpiredToken},
+		{"other issuer", iss.sign(t, jwt.MapClaims{"sub": "user", "aud": "api", "iss": "https://other.example.com"}), auth.ErrInvalidToken},
+		{"wrong audience", iss.sign(t, jwt.MapClaims{"sub": "user", "aud": "other"}), auth.ErrInvalidToken},
+		{"no subject", iss.sign(t, jwt.MapClaims{"aud": "api"}), auth.ErrInvalidToken},
+		{"no expiry", iss.sign(t, jwt.MapClaims{"sub": "user", "aud": "api", "exp": nil}), auth.ErrInvalidToken},
+		{"no email", iss.sign(t, jwt.MapClaims{"sub": "user", "aud": "api"}), auth.ErrInvalidToken},
+		{"bad signature", foreignSigned, auth.ErrInvalidToken},
+		{"symmetric algorithm", hmacSigned, auth.ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := p.ValidateToken(tt.token); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOIDCValidateTokenProvisionsUser(t *testing.T) {
+	iss := newTestIssuer(t)
+	p := iss.provider(t, config.OIDCConfig{})
+
+	token := iss.sign(t, jwt.MapClaims{"sub": "auth0|abc", "email": "ada@example.com", "email_verified": true, "name": "Ada"})
+	claims, err := p.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+
+	var usr model.User
+	if err := iss.db.First(&usr, "id = ?", claims.UserID).Error; err != nil {
+		t.Fatalf("user not provisioned: %v", err)
+	}
+	if usr.Email != "ada@example.com" || usr.Name != "Ada" || usr.AuthProvider != "oidc" || !usr.EmailVerified {
+		t.Errorf("user = %+v", usr)
+	}
+
+	// A second token for the subject maps to the same user
+	again, err := p.ValidateToken(iss.sign(t, jwt.MapClaims{"sub": "auth0|abc", "email": "ada@example.com", "name": "Ada"}))
+	if err != nil || again.UserID != claims.UserID {
+		t.Fatalf("UserID = %v, %v; want %s", again, err, claims.UserID)
+	}
+	var count int64
+	iss.db.Model(&model.User{}).Count(&count)
+	if count != 1 {
+		t.Errorf("users = %d, want 1", count)
+	}
+}
+
+func TestOIDCValidateTokenLinksExistingUserByEmail(t *testing.T) {
+	iss := newTestIssuer(t)
+	p := iss.provider(t, config.OIDCConfig{})
+
+	existing := model.User{Name: "Ada", Email: "ada@example.com"}
+	if err := iss.db.Create(&existing).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	unverified := iss.sign(t, jwt.MapClaims{"sub": "auth0|abc", "email": "ada@example.com"})
+	if _, err := p.ValidateToken(unverified); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatalf("unverified email: err = %v, want ErrInvalidToken", err)
+	}
+
+	claims, err := p.ValidateToken(iss.sign(t, jwt.MapClaims{"sub": "auth0|abc", "email": "ada@example.com", "email_verified": true}))
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if claims.UserID != existing.ID {
+		t.Errorf("UserID = %s, want existing user %s", claims.UserID, existing.ID)
+	}
+}
+
+func TestOIDCUserCanCreateOrg(t *testing.T) {
+	iss := newTestIssuer(t)
+	p := iss.provider(t, config.OIDCConfig{})
+
+	claims, err := p.ValidateToken(iss.sign(t, jwt.MapClaims{"sub": "auth0|abc", "email": "ada@example.com"}))
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+
+	orgs := org.NewService(org.NewRepository(iss.db))
+	created, err := orgs.CreateOrg(context.Background(), claims.UserID, org.CreateOrgRequest{Name: "Acme", Slug: "acme"})
+	if err != nil {
+		t.Fatalf("CreateOrg: %v", err)
+	}
+	var m model.Membership
+	if err := iss.db.First(&m, "org_id = ? AND user_id = ?", created.ID, claims.UserID).Error; err != nil {
+		t.Fatalf("owner membership: %v", err)
+	}
+	if m.Role != model.RoleOwner {
+		t.Errorf("Role = %q, want %q", m.Role, model.RoleOwner)
+	}
+}
+
+func TestOIDCLoginPasswordGrant(t *testing.T) {
+	iss := newTestIssuer(t)
+
+	if _, err := iss.provider(t, config.OIDCConfig{}).Login(context.Background(), auth.LoginRequest{Email: "ada@example.com", Password: "secret"}); err == nil {
+		t.Fatal("Login succeeded with the password grant disabled")
+	}
+
+	p := iss.provider(t, config.OIDCConfig{PasswordGrant: true})
+	resp, err := p.Login(context.Background(), auth.LoginRequest{Email: "ada@example.com", Password: "secret"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if resp.User.ID.String() != iss.grantSub || resp.User.Email != "ada@example.com" {
+		t.Errorf("User = %+v", resp.User)
+	}
+	if resp.TokenType != "Bearer" || resp.RefreshToken != "refresh" {
+		t.Errorf("TokenType, RefreshToken = %q, %q", resp.TokenType, resp.RefreshToken)
+	}
+
+	_, err = p.Login(context.Background(), auth.LoginRequest{Email: "ada@example.com", Password: "wrong"})
+	var apiErr *apiErrors.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("err = %v, want 401", err)
+	}
+}
+
+func TestClaimStrings(t *testing.T) {
+	tests := []struct {
+		name string
+		in   interface{}
+		want []string
+	}{
+		{"list", []interface{}{"a", "", "b", 1}, []string{"a", "b"}},
+		{"space separated", "a b", []string{"a", "b"}},
+		{"object keys", map[string]interface{}{"b": 1, "a": 2}, []string{"a", "b"}},
+		{"missing", nil, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := claimStrings(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("claimStrings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
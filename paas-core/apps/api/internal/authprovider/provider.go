@@ -3,13 +3,12 @@ package authprovider
 import (
 	"context"
 
-	"github.com/google/uuid"
-
 	"paas-core/apps/api/internal/auth"
 )
 
-// AuthProvider abstracts authentication so the API can use either the built-in
-// local auth (bcrypt + HS256 JWT) or Supabase GoTrue without changing handlers.
+// AuthProvider abstracts authentication so the API can use the built-in
+// local auth (argon2id/bcrypt + HS256 JWT), Supabase GoTrue, an external OIDC
+// issuer or an LDAP directory without changing handlers.
 type AuthProvider interface {
 	// Register creates a new user account and returns tokens.
 	Register(ctx context.Context, req auth.RegisterRequest) (*auth.AuthResponse, error)
@@ -23,9 +22,10 @@ type AuthProvider interface {
 	// RefreshToken exchanges a refresh token for a new token pair.
 	RefreshToken(ctx context.Context, refreshToken string) (*auth.TokenPair, error)
 
-	// Logout invalidates all sessions / refresh tokens for the user.
-	Logout(ctx context.Context, userID uuid.UUID) error
+	// Logout invalidates the presented access token and all sessions /
+	// refresh tokens for the user.
+	Logout(ctx context.Context, claims *auth.Claims) error
 
-	// Name returns the provider identifier ("local" or "supabase").
+	// Name returns the provider identifier ("local", "supabase", "oidc" or "ldap").
 	Name() string
 }
@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -26,26 +28,50 @@ var (
 
 // SupabaseProvider implements AuthProvider by delegating to Supabase GoTrue.
 type SupabaseProvider struct {
-	baseURL    string // e.g. https://xyz.supabase.co or http://localhost:8000
-	anonKey    string
-	serviceKey string
-	jwtSecret  string
-	httpClient *http.Client
+	baseURL     string // e.g. https://xyz.supabase.co or http://localhost:8000
+	anonKey     string
+	serviceKey  string
+	jwtSecret   string
+	issuer      string
+	audience    string
+	httpClient  *http.Client
+	keys        *remoteKeySet
+	revocations *auth.RevocationStore
 }
 
-// NewSupabaseProvider creates a Supabase auth provider.
-func NewSupabaseProvider(cfg config.SupabaseConfig) *SupabaseProvider {
+// NewSupabaseProvider creates a Supabase auth provider. Tokens signed with
+// the project's asymmetric keys are verified against its JWKS; legacy HS256
+// tokens need the JWT secret.
+func NewSupabaseProvider(cfg config.SupabaseConfig, revocations *auth.RevocationStore) *SupabaseProvider {
+	baseURL := strings.TrimSuffix(cfg.URL, "/")
+	issuer := cfg.Issuer
+	if issuer == "" {
+		issuer = baseURL + "/auth/v1"
+	}
+	audience := cfg.Audience
+	if audience == "" {
+		audience = "authenticated"
+	}
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+
 	return &SupabaseProvider{
-		baseURL:    cfg.URL,
-		anonKey:    cfg.AnonKey,
-		serviceKey: cfg.ServiceKey,
-		jwtSecret:  cfg.JWTSecret,
-		httpClient: &http.Client{Timeout: 10 * time.Second},
+		baseURL:     baseURL,
+		anonKey:     cfg.AnonKey,
+		serviceKey:  cfg.ServiceKey,
+		jwtSecret:   cfg.JWTSecret,
+		issuer:      issuer,
+		audience:    audience,
+		httpClient:  httpClient,
+		keys:        newRemoteKeySet(baseURL+"/auth/v1/.well-known/jwks.json", httpClient),
+		revocations: revocations,
 	}
 }
 
 func (p *SupabaseProvider) Name() string { return "supabase" }
 
+// Issuer returns the "iss" of the project's access tokens.
+func (p *SupabaseProvider) Issuer() string { return p.issuer }
+
 // --- GoTrue REST API types ---
 
 type gotrueSignUpRequest struct {
@@ -76,6 +102,28 @@ type gotrueUser struct {
 	CreatedAt    string                 `json:"created_at"`
 }
 
+type gotrueAdminUserRequest struct {
+	ID           string                 `json:"id,omitempty"`
+	Email        string                 `json:"email,omitempty"`
+	Password     string                 `json:"password"`
+	EmailConfirm bool                   `json:"email_confirm,omitempty"`
+	UserMetadata map[string]interface{} `json:"user_metadata,omitempty"`
+}
+
+type gotrueAdminUser struct {
+	ID               string                 `json:"id"`
+	Email            string                 `json:"email"`
+	EmailConfirmedAt string                 `json:"email_confirmed_at"`
+	BannedUntil      string                 `json:"banned_until"`
+	UserMetadata     map[string]interface{} `json:"user_metadata"`
+	AppMetadata      map[string]interface{} `json:"app_metadata"`
+	CreatedAt        string                 `json:"created_at"`
+}
+
+type gotrueAdminUserList struct {
+	Users []gotrueAdminUser `json:"users"`
+}
+
 type gotrueErrorResponse struct {
 	Error            string `json:"error"`
 	ErrorDescription string `json:"error_description"`
@@ -116,19 +164,16 @@ func (p *SupabaseProvider) Login(ctx context.Context, req auth.LoginRequest) (*a
 	return p.tokenResponseToAuthResponse(resp), nil
 }
 
-// ValidateToken verifies a Supabase JWT using the configured JWT secret (HS256).
+// ValidateToken verifies a Supabase JWT, either against the project's JWKS
+// or, for HS256 tokens, the configured JWT secret. Issuer, audience and
+// expiry are always checked.
 func (p *SupabaseProvider) ValidateToken(tokenString string) (*auth.Claims, error) {
-	if p.jwtSecret == "" {
-		return nil, fmt.Errorf("%w: JWT secret not configured", ErrSupabaseToken)
-	}
-
-	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
-		// Supabase uses HS256 by default
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-		}
-		return []byte(p.jwtSecret), nil
-	})
+	token, err := jwt.Parse(tokenString, p.keyfunc,
+		jwt.WithValidMethods(append([]string{"HS256"}, asymmetricMethods...)),
+		jwt.WithIssuer(p.issuer),
+		jwt.WithAudience(p.audience),
+		jwt.WithExpirationRequired(),
+	)
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
 			return nil, auth.ErrExpiredToken
@@ -181,6 +226,7 @@ func (p *SupabaseProvider) ValidateToken(tokenString string) (*auth.Claims, erro
 
 	claims := &auth.Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    p.issuer,
 			Subject:   sub,
 			IssuedAt:  iat,
 			ExpiresAt: exp,
@@ -191,10 +237,71 @@ func (p *SupabaseProvider) ValidateToken(tokenString string) (*auth.Claims, erro
 		Name:   name,
 		Roles:  roles,
 	}
+	// Routes behind recentAuth need to know when the user last signed in;
+	// refreshes keep the session's amr, so iat cannot be used
+	claims.AuthTime, claims.AMR = supabaseAMR(mapClaims["amr"])
 
+	if p.revocations.IsRevoked(claims) {
+		return nil, auth.ErrTokenRevoked
+	}
 	return claims, nil
 }
 
+// supabaseAMRMethods maps GoTrue authentication methods to RFC 8176 values.
+var supabaseAMRMethods = map[string]string{
+	"password":  auth.AMRPassword,
+	"otp":       auth.AMRMagicLink,
+	"magiclink": auth.AMRMagicLink,
+	"oauth":     auth.AMRFederated,
+	"sso/saml":  auth.AMRFederated,
+	"totp":      auth.AMROTP,
+	"mfa/totp":  auth.AMROTP,
+	"webauthn":  auth.AMRPasskey,
+}
+
+// supabaseAMR reads GoTrue's amr claim, a list of {"method", "timestamp"}
+// objects, and returns the latest authentication time with the methods used.
+// Sessions that completed a second factor also get "mfa".
+func supabaseAMR(raw interface{}) (*jwt.NumericDate, []string) {
+	entries, _ := raw.([]interface{})
+	var latest float64
+	var amr []string
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		obj, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if ts, ok := obj["timestamp"].(float64); ok && ts > latest {
+			latest = ts
+		}
+		method, _ := obj["method"].(string)
+		if mapped, ok := supabaseAMRMethods[method]; ok && !seen[mapped] {
+			seen[mapped] = true
+			amr = append(amr, mapped)
+		}
+	}
+	if seen[auth.AMROTP] && len(amr) > 1 {
+		amr = append(amr, auth.AMRMFA)
+	}
+	if latest == 0 {
+		return nil, amr
+	}
+	return jwt.NewNumericDate(time.Unix(int64(latest), 0)), amr
+}
+
+// keyfunc picks the verification key by algorithm. Projects that have not
+// migrated to asymmetric signing keys still issue HS256 tokens.
+func (p *SupabaseProvider) keyfunc(t *jwt.Token) (interface{}, error) {
+	if _, ok := t.Method.(*jwt.SigningMethodHMAC); ok {
+		if p.jwtSecret == "" {
+			return nil, fmt.Errorf("%w: JWT secret not configured", ErrSupabaseToken)
+		}
+		return []byte(p.jwtSecret), nil
+	}
+	return p.keys.Keyfunc(t)
+}
+
 // RefreshToken exchanges a refresh token via POST /auth/v1/token?grant_type=refresh_token.
 func (p *SupabaseProvider) RefreshToken(ctx context.Context, refreshToken string) (*auth.TokenPair, error) {
 	body := gotrueTokenRequest{
@@ -214,18 +321,26 @@ func (p *SupabaseProvider) RefreshToken(ctx context.Context, refreshToken string
 	}, nil
 }
 
-// Logout invalidates the user's session via POST /auth/v1/logout.
-func (p *SupabaseProvider) Logout(ctx context.Context, userID uuid.UUID) error {
-	// Supabase logout requires the user's access token, not the user ID.
-	// We use the service key to call the admin API to revoke sessions.
-	url := fmt.Sprintf("%s/auth/v1/admin/users/%s/factors", p.baseURL, userID.String())
+// Logout rejects every access token issued to the user so far and ends all
+// of the user's GoTrue sessions via POST /auth/v1/logout?scope=global, which
+// revokes their refresh tokens.
+func (p *SupabaseProvider) Logout(ctx context.Context, claims *auth.Claims) error {
+	if err := p.revocations.RevokeAllBefore(ctx, claims.UserID, time.Now()); err != nil {
+		return err
+	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
+	// GoTrue identifies the session by the user's own access token
+	accessToken := auth.AccessTokenFromContext(ctx)
+	if accessToken == "" {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/auth/v1/logout?scope=global", nil)
 	if err != nil {
 		return fmt.Errorf("%w: %v", ErrSupabaseRequest, err)
 	}
-	req.Header.Set("apikey", p.serviceKey)
-	req.Header.Set("Authorization", "Bearer "+p.serviceKey)
+	req.Header.Set("apikey", p.anonKey)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
 
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
@@ -233,7 +348,7 @@ func (p *SupabaseProvider) Logout(ctx context.Context, userID uuid.UUID) error {
 	}
 	defer resp.Body.Close()
 
-	// Logout is best-effort; 404 means user has no active factors, which is fine
+	// 401 and 404 mean the session is already gone, which is the goal
 	if resp.StatusCode >= 500 {
 		return fmt.Errorf("%w: server error %d", ErrSupabaseRequest, resp.StatusCode)
 	}
@@ -241,8 +356,103 @@ func (p *SupabaseProvider) Logout(ctx context.Context, userID uuid.UUID) error {
 	return nil
 }
 
+// ImportPasswordUser creates the user in GoTrue with the same ID and
+// password via POST /auth/v1/admin/users, so existing rows keep pointing at
+// them. A user that already exists in GoTrue just gets the password set.
+func (p *SupabaseProvider) ImportPasswordUser(ctx context.Context, user auth.UserResponse, password string) error {
+	status, _, err := p.doAdminRequest(ctx, http.MethodPost, "/auth/v1/admin/users", gotrueAdminUserRequest{
+		ID:           user.ID.String(),
+		Email:        user.Email,
+		Password:     password,
+		EmailConfirm: true,
+		UserMetadata: map[string]interface{}{"name": user.Name},
+	}, nil)
+	if status != http.StatusUnprocessableEntity {
+		return err
+	}
+
+	_, _, err = p.doAdminRequest(ctx, http.MethodPut, "/auth/v1/admin/users/"+user.ID.String(), gotrueAdminUserRequest{
+		Password: password,
+	}, nil)
+	return err
+}
+
+// ListUsers returns one page of users from GET /auth/v1/admin/users and the
+// total user count GoTrue reports, or -1 when it reports none. Pages start at
+// 1. GoTrue may cap perPage, so only an empty page marks the end.
+func (p *SupabaseProvider) ListUsers(ctx context.Context, page, perPage int) ([]SupabaseAuthUser, int, error) {
+	var list gotrueAdminUserList
+	path := fmt.Sprintf("/auth/v1/admin/users?page=%d&per_page=%d", page, perPage)
+	_, header, err := p.doAdminRequest(ctx, http.MethodGet, path, nil, &list)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := strconv.Atoi(header.Get("X-Total-Count"))
+	if err != nil {
+		total = -1
+	}
+
+	users := make([]SupabaseAuthUser, 0, len(list.Users))
+	for _, u := range list.Users {
+		users = append(users, SupabaseAuthUser{
+			ID:               u.ID,
+			Email:            u.Email,
+			UserMetadata:     u.UserMetadata,
+			AppMetadata:      u.AppMetadata,
+			EmailConfirmedAt: u.EmailConfirmedAt,
+			BannedUntil:      u.BannedUntil,
+			CreatedAt:        u.CreatedAt,
+		})
+	}
+	return users, total, nil
+}
+
 // --- Helpers ---
 
+// doAdminRequest calls a GoTrue admin endpoint with the service key and
+// returns the HTTP status and response headers alongside any error. A JSON
+// response is decoded into out when it is not nil.
+func (p *SupabaseProvider) doAdminRequest(ctx context.Context, method, path string, body, out interface{}) (int, http.Header, error) {
+	var reqBody io.Reader
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return 0, nil, fmt.Errorf("%w: failed to marshal request: %v", ErrSupabaseRequest, err)
+		}
+		reqBody = bytes.NewReader(jsonBody)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, reqBody)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: failed to create request: %v", ErrSupabaseRequest, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("apikey", p.serviceKey)
+	req.Header.Set("Authorization", "Bearer "+p.serviceKey)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: %v", ErrSupabaseRequest, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var errResp gotrueErrorResponse
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&errResp)
+		msg := errResp.Message
+		if msg == "" {
+			msg = fmt.Sprintf("HTTP %d", resp.StatusCode)
+		}
+		return resp.StatusCode, resp.Header, fmt.Errorf("%w: %s", ErrSupabaseRequest, msg)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, resp.Header, fmt.Errorf("%w: failed to parse response: %v", ErrSupabaseRequest, err)
+		}
+	}
+	return resp.StatusCode, resp.Header, nil
+}
+
 // doGoTrueRequest makes an HTTP request to the Supabase GoTrue API.
 func (p *SupabaseProvider) doGoTrueRequest(ctx context.Context, method, path string, body interface{}, useServiceKey bool) (*gotrueTokenResponse, error) {
 	jsonBody, err := json.Marshal(body)
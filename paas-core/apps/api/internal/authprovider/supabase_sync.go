@@ -0,0 +1,370 @@
+package authprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"paas-core/apps/api/internal/auth"
+	"paas-core/apps/api/internal/model"
+)
+
+// ErrInvalidSupabaseUser is returned for auth.users records that cannot be
+// mapped to a local user.
+var ErrInvalidSupabaseUser = errors.New("invalid supabase user record")
+
+// errDryRun rolls back the changes of a reconciliation dry run.
+var errDryRun = errors.New("dry run")
+
+// SupabaseUserSync mirrors Supabase auth.users into the local users table:
+// email, name, email verification, roles from app_metadata.roles and bans.
+// Only roles that exist locally are assigned.
+type SupabaseUserSync struct {
+	db          *gorm.DB
+	revocations *auth.RevocationStore
+}
+
+// NewSupabaseUserSync creates a sync that revokes the access tokens of users
+// as soon as Supabase bans them.
+func NewSupabaseUserSync(db *gorm.DB, revocations *auth.RevocationStore) *SupabaseUserSync {
+	return &SupabaseUserSync{db: db, revocations: revocations}
+}
+
+// syncResult describes what applying one auth.users record changed.
+type syncResult struct {
+	userID    uuid.UUID
+	created   bool
+	changed   bool
+	suspended bool // the user was banned by this change
+}
+
+// ReconcileResult counts what a reconciliation run found. In a dry run the
+// counts are what would have been fixed.
+type ReconcileResult struct {
+	Checked int
+	Created int
+	Updated int
+	Deleted int
+}
+
+// BackfillSupabaseUsers marks users the webhook created before
+// users.auth_provider existed, who got the "local" default. They have no
+// local password and no OAuth link; their credentials live in Supabase.
+func BackfillSupabaseUsers(ctx context.Context, db *gorm.DB) (int64, error) {
+	res := db.WithContext(ctx).Model(&model.User{}).
+		Where("auth_provider = ? AND (password_hash = '' OR password_hash IS NULL) AND is_service_account = ?", "local", false).
+		Where("NOT EXISTS (SELECT 1 FROM oauth_accounts WHERE oauth_accounts.user_id = users.id)").
+		Update("auth_provider", "supabase")
+	return res.RowsAffected, res.Error
+}
+
+// Upsert applies a single auth.users record.
+func (s *SupabaseUserSync) Upsert(ctx context.Context, supaUser SupabaseAuthUser) error {
+	var res syncResult
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		res, err = s.apply(tx, supaUser)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.afterApply(ctx, res)
+	return nil
+}
+
+// Reconcile pages through every GoTrue user with the admin API and fixes
+// local rows that drifted, e.g. after missed webhook deliveries. Local
+// Supabase users that no longer exist in GoTrue are deleted only when every
+// page was fetched and the listing covered the total GoTrue reported.
+func (s *SupabaseUserSync) Reconcile(ctx context.Context, provider *SupabaseProvider, perPage int, dryRun bool) (*ReconcileResult, error) {
+	result := &ReconcileResult{}
+	seen := map[uuid.UUID]bool{}
+	listed, total := 0, -1
+
+	for page := 1; ; page++ {
+		users, pageTotal, err := provider.ListUsers(ctx, page, perPage)
+		if err != nil {
+			return result, fmt.Errorf("failed to list users (page %d), nothing was deleted: %w", page, err)
+		}
+		if pageTotal >= 0 {
+			total = pageTotal
+		}
+		if len(users) == 0 {
+			break
+		}
+		listed += len(users)
+
+		for _, supaUser := range users {
+			// Users that cannot be applied still exist in GoTrue
+			if id, err := uuid.Parse(supaUser.ID); err == nil {
+				seen[id] = true
+			}
+
+			var res syncResult
+			err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				var err error
+				if res, err = s.apply(tx, supaUser); err != nil {
+					return err
+				}
+				if dryRun {
+					return errDryRun
+				}
+				return nil
+			})
+			if err != nil && !errors.Is(err, errDryRun) {
+				if errors.Is(err, ErrInvalidSupabaseUser) {
+					slog.Warn("Skipping Supabase user", "supabase_id", supaUser.ID, "error", err)
+					continue
+				}
+				return result, err
+			}
+
+			result.Checked++
+			switch {
+			case res.created:
+				result.Created++
+			case res.changed:
+				result.Updated++
+			}
+			if !dryRun {
+				s.afterApply(ctx, res)
+			}
+		}
+
+		if total >= 0 && listed >= total {
+			break
+		}
+	}
+
+	// An empty listing more likely means a misconfigured project than a
+	// project without users, so nothing is deleted
+	if result.Checked == 0 {
+		return result, nil
+	}
+	// Users deleted in GoTrue while paging shift later pages, so some users
+	// may have been skipped
+	if total >= 0 && listed < total {
+		slog.Warn("Supabase user listing was incomplete, nothing was deleted", "listed", listed, "total", total)
+		return result, nil
+	}
+
+	var localIDs []uuid.UUID
+	if err := s.db.WithContext(ctx).Model(&model.User{}).
+		Where("auth_provider = ?", "supabase").Pluck("id", &localIDs).Error; err != nil {
+		return result, fmt.Errorf("failed to list local users: %w", err)
+	}
+	for _, id := range localIDs {
+		if seen[id] {
+			continue
+		}
+		result.Deleted++
+		if dryRun {
+			continue
+		}
+		if err := s.db.WithContext(ctx).Where("id = ?", id).Delete(&model.User{}).Error; err != nil {
+			return result, fmt.Errorf("failed to delete user %s: %w", id, err)
+		}
+		slog.Info("Deleted user missing from Supabase", "user_id", id)
+	}
+
+	return result, nil
+}
+
+// apply creates or updates the local user for an auth.users record in tx.
+func (s *SupabaseUserSync) apply(tx *gorm.DB, supaUser SupabaseAuthUser) (syncResult, error) {
+	userID, err := uuid.Parse(supaUser.ID)
+	if err != nil {
+		return syncResult{}, fmt.Errorf("%w: invalid user ID", ErrInvalidSupabaseUser)
+	}
+	res := syncResult{userID: userID}
+
+	name := ""
+	if supaUser.UserMetadata != nil {
+		name, _ = supaUser.UserMetadata["name"].(string)
+	}
+	verified := supaUser.EmailConfirmedAt != ""
+	bannedUntil := parseSupabaseTime(supaUser.BannedUntil)
+	if bannedUntil != nil && !bannedUntil.After(time.Now()) {
+		bannedUntil = nil
+	}
+	roleNames, hasRoles := supabaseRoles(supaUser.AppMetadata)
+
+	var usr model.User
+	err = tx.Preload("Roles").Where("id = ?", userID).First(&usr).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		usr = model.User{
+			Name:           name,
+			Email:          supaUser.Email,
+			EmailVerified:  verified,
+			AuthProvider:   "supabase",
+			SuspendedUntil: bannedUntil,
+		}
+		usr.ID = userID
+		if usr.Name == "" {
+			usr.Name = supaUser.Email
+		}
+		if err := tx.Create(&usr).Error; err != nil {
+			return res, fmt.Errorf("failed to create user: %w", err)
+		}
+		if !hasRoles {
+			roleNames = []string{model.RoleUser}
+		}
+		if _, err := syncRoles(tx, &usr, roleNames); err != nil {
+			return res, err
+		}
+		res.created, res.changed = true, true
+		res.suspended = usr.IsSuspended()
+		return res, nil
+	}
+	if err != nil {
+		return res, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	wasSuspended := usr.IsSuspended()
+	updates := map[string]interface{}{}
+	if supaUser.Email != "" && usr.Email != supaUser.Email {
+		updates["email"] = supaUser.Email
+	}
+	if name != "" && usr.Name != name {
+		updates["name"] = name
+	}
+	if usr.EmailVerified != verified {
+		updates["email_verified"] = verified
+	}
+	if !sameTime(usr.SuspendedUntil, bannedUntil) {
+		updates["suspended_until"] = bannedUntil
+	}
+	if len(updates) > 0 {
+		if err := tx.Model(&model.User{}).Where("id = ?", userID).Updates(updates).Error; err != nil {
+			return res, fmt.Errorf("failed to update user: %w", err)
+		}
+		res.changed = true
+	}
+
+	if hasRoles {
+		changed, err := syncRoles(tx, &usr, roleNames)
+		if err != nil {
+			return res, err
+		}
+		res.changed = res.changed || changed
+	}
+
+	res.suspended = !wasSuspended && bannedUntil != nil
+	return res, nil
+}
+
+// delete removes the local user of a deleted auth.users record.
+func (s *SupabaseUserSync) delete(tx *gorm.DB, supaUser SupabaseAuthUser) (syncResult, error) {
+	userID, err := uuid.Parse(supaUser.ID)
+	if err != nil {
+		return syncResult{}, fmt.Errorf("%w: invalid user ID", ErrInvalidSupabaseUser)
+	}
+
+	// Soft-delete (GORM default with DeletedAt field)
+	result := tx.Where("id = ?", userID).Delete(&model.User{})
+	if result.Error != nil {
+		return syncResult{userID: userID}, fmt.Errorf("failed to delete user: %w", result.Error)
+	}
+	return syncResult{userID: userID, changed: result.RowsAffected > 0}, nil
+}
+
+// afterApply signs a newly banned user out. It runs after the transaction
+// commits so a rollback never leaves tokens revoked for nothing.
+func (s *SupabaseUserSync) afterApply(ctx context.Context, res syncResult) {
+	if !res.suspended || s.revocations == nil {
+		return
+	}
+	if err := s.revocations.RevokeAllBefore(ctx, res.userID, time.Now()); err != nil {
+		slog.Error("Failed to revoke tokens of suspended user", "user_id", res.userID, "error", err)
+	}
+}
+
+// syncRoles makes the user's roles exactly the named ones that exist
+// locally and reports whether they changed.
+func syncRoles(tx *gorm.DB, usr *model.User, names []string) (bool, error) {
+	var roles []model.Role
+	if len(names) > 0 {
+		if err := tx.Where("name IN ?", names).Find(&roles).Error; err != nil {
+			return false, fmt.Errorf("failed to load roles: %w", err)
+		}
+	}
+	if len(roles) < len(names) {
+		slog.Warn("Ignoring unknown Supabase roles", "user_id", usr.ID, "roles", names)
+	}
+
+	if sameRoles(usr.Roles, roles) {
+		return false, nil
+	}
+	var err error
+	if len(roles) == 0 {
+		err = tx.Model(usr).Association("Roles").Clear()
+	} else {
+		err = tx.Model(usr).Association("Roles").Replace(roles)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update roles: %w", err)
+	}
+	return true, nil
+}
+
+// supabaseRoles reads app_metadata.roles. The second result is false when
+// the claim is absent, in which case roles are left alone.
+func supabaseRoles(appMetadata map[string]interface{}) ([]string, bool) {
+	raw, ok := appMetadata["roles"]
+	if !ok {
+		return nil, false
+	}
+	return claimStrings(raw), true
+}
+
+func sameRoles(a, b []model.Role) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	names := func(roles []model.Role) []string {
+		out := make([]string, len(roles))
+		for i, r := range roles {
+			out[i] = r.Name
+		}
+		sort.Strings(out)
+		return out
+	}
+	an, bn := names(a), names(b)
+	for i := range an {
+		if an[i] != bn[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// parseSupabaseTime parses a timestamp as GoTrue's API and Postgres' JSON
+// encoding write it. Empty or unparsable values yield nil.
+func parseSupabaseTime(s string) *time.Time {
+	if s == "" {
+		return nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999-07", "2006-01-02T15:04:05.999999"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t
+		}
+	}
+	slog.Warn("Unparsable Supabase timestamp", "value", s)
+	return nil
+}
+
+// sameTime compares optional timestamps at the microsecond precision
+// Postgres stores.
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Truncate(time.Microsecond).Equal(b.Truncate(time.Microsecond))
+}
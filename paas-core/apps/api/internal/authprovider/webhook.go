@@ -3,19 +3,24 @@ package authprovider
 import (
 	"crypto/hmac"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 
 	apiErrors "paas-core/apps/api/internal/errors"
 	"paas-core/apps/api/internal/model"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // WebhookPayload represents the incoming Supabase auth webhook event.
@@ -28,28 +33,43 @@ type WebhookPayload struct {
 
 // SupabaseAuthUser maps to auth.users from Supabase.
 type SupabaseAuthUser struct {
-	ID           string                 `json:"id"`
-	Email        string                 `json:"email"`
-	UserMetadata map[string]interface{} `json:"raw_user_meta_data"`
-	CreatedAt    string                 `json:"created_at"`
+	ID               string                 `json:"id"`
+	Email            string                 `json:"email"`
+	UserMetadata     map[string]interface{} `json:"raw_user_meta_data"`
+	AppMetadata      map[string]interface{} `json:"raw_app_meta_data"`
+	EmailConfirmedAt string                 `json:"email_confirmed_at"`
+	BannedUntil      string                 `json:"banned_until"`
+	CreatedAt        string                 `json:"created_at"`
 }
 
+// webhookSource identifies Supabase auth events in processed_webhook_events.
+const webhookSource = "supabase_auth"
+
+// webhookTolerance bounds the age of a Standard Webhooks delivery, so a
+// captured request cannot be replayed once its id has been forgotten.
+const webhookTolerance = 5 * time.Minute
+
 // WebhookHandler processes Supabase auth webhook events to sync users
 // into the local users table. Register at POST /api/v1/webhooks/supabase/auth.
 type WebhookHandler struct {
 	db            *gorm.DB
+	sync          *SupabaseUserSync
 	webhookSecret string
 }
 
 // NewWebhookHandler creates a handler for Supabase auth webhooks.
-func NewWebhookHandler(db *gorm.DB, webhookSecret string) *WebhookHandler {
+func NewWebhookHandler(db *gorm.DB, sync *SupabaseUserSync, webhookSecret string) *WebhookHandler {
 	return &WebhookHandler{
 		db:            db,
+		sync:          sync,
 		webhookSecret: webhookSecret,
 	}
 }
 
-// HandleAuthWebhook processes POST /api/v1/webhooks/supabase/auth.
+// HandleAuthWebhook processes POST /api/v1/webhooks/supabase/auth. Each event
+// is applied once. Deliveries signed per Standard Webhooks are identified by
+// their signed "webhook-id"; anything else by a hash of the signed body, so
+// an old payload cannot be replayed under a new id.
 func (h *WebhookHandler) HandleAuthWebhook(c *gin.Context) {
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
@@ -58,13 +78,24 @@ func (h *WebhookHandler) HandleAuthWebhook(c *gin.Context) {
 	}
 	defer c.Request.Body.Close()
 
-	// Verify webhook signature if secret is configured
-	if h.webhookSecret != "" {
-		sig := c.GetHeader("X-Supabase-Webhook-Signature")
-		if !verifyWebhookSignature(body, sig, h.webhookSecret) {
-			_ = c.Error(apiErrors.Unauthorized("invalid webhook signature"))
+	sum := sha256.Sum256(body)
+	eventID := hex.EncodeToString(sum[:])
+
+	// Events set roles, bans and verification, so unsigned ones are never applied
+	if h.webhookSecret == "" {
+		_ = c.Error(apiErrors.Unauthorized("webhook secret is not configured"))
+		return
+	}
+	if c.GetHeader("webhook-signature") != "" {
+		id := c.GetHeader("webhook-id")
+		if err := verifyStandardWebhook(id, c.GetHeader("webhook-timestamp"), c.GetHeader("webhook-signature"), body, h.webhookSecret, time.Now()); err != nil {
+			_ = c.Error(apiErrors.Unauthorized(err.Error()))
 			return
 		}
+		eventID = id
+	} else if !verifyWebhookSignature(body, c.GetHeader("X-Supabase-Webhook-Signature"), h.webhookSecret) {
+		_ = c.Error(apiErrors.Unauthorized("invalid webhook signature"))
+		return
 	}
 
 	var payload WebhookPayload
@@ -85,105 +116,59 @@ func (h *WebhookHandler) HandleAuthWebhook(c *gin.Context) {
 		return
 	}
 
+	var apply func(tx *gorm.DB, supaUser SupabaseAuthUser) (syncResult, error)
+	status := "synced"
 	switch payload.Type {
-	case "INSERT":
-		h.handleUserInsert(c, supaUser)
-	case "UPDATE":
-		h.handleUserUpdate(c, supaUser)
+	case "INSERT", "UPDATE":
+		apply = h.sync.apply
 	case "DELETE":
-		h.handleUserDelete(c, supaUser)
+		apply = h.sync.delete
+		status = "deleted"
 	default:
 		c.JSON(http.StatusOK, gin.H{"status": "ignored", "reason": "unknown event type"})
-	}
-}
-
-func (h *WebhookHandler) handleUserInsert(c *gin.Context, supaUser SupabaseAuthUser) {
-	userID, err := uuid.Parse(supaUser.ID)
-	if err != nil {
-		_ = c.Error(apiErrors.BadRequest("invalid user ID"))
 		return
 	}
 
-	name := ""
-	if supaUser.UserMetadata != nil {
-		name, _ = supaUser.UserMetadata["name"].(string)
-	}
-	if name == "" {
-		name = supaUser.Email
-	}
-
-	user := model.User{
-		Name:  name,
-		Email: supaUser.Email,
-	}
-	user.ID = userID
-
-	if err := h.db.Where("id = ?", userID).FirstOrCreate(&user).Error; err != nil {
-		slog.Error("Failed to sync Supabase user", "error", err, "supabase_id", supaUser.ID)
-		_ = c.Error(apiErrors.InternalServerError(err))
-		return
-	}
-
-	slog.Info("Synced Supabase user",
-		"user_id", userID,
-		"email", supaUser.Email,
-		"action", "insert",
-	)
-
-	c.JSON(http.StatusOK, gin.H{"status": "synced", "user_id": userID.String()})
-}
-
-func (h *WebhookHandler) handleUserUpdate(c *gin.Context, supaUser SupabaseAuthUser) {
-	userID, err := uuid.Parse(supaUser.ID)
+	var res syncResult
+	duplicate := false
+	err = h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		// The marker commits with the change, so a failed event is retried
+		marker := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.ProcessedWebhookEvent{Source: webhookSource, EventID: eventID})
+		if marker.Error != nil {
+			return marker.Error
+		}
+		if marker.RowsAffected == 0 {
+			duplicate = true
+			return nil
+		}
+		res, err = apply(tx, supaUser)
+		return err
+	})
 	if err != nil {
-		_ = c.Error(apiErrors.BadRequest("invalid user ID"))
-		return
-	}
-
-	updates := map[string]interface{}{
-		"email": supaUser.Email,
-	}
-	if supaUser.UserMetadata != nil {
-		if name, ok := supaUser.UserMetadata["name"].(string); ok && name != "" {
-			updates["name"] = name
+		if errors.Is(err, ErrInvalidSupabaseUser) {
+			_ = c.Error(apiErrors.BadRequest("invalid user ID"))
+			return
 		}
-	}
-
-	if err := h.db.Model(&model.User{}).Where("id = ?", userID).Updates(updates).Error; err != nil {
-		slog.Error("Failed to update Supabase user", "error", err, "supabase_id", supaUser.ID)
+		slog.Error("Failed to sync Supabase user", "error", err, "supabase_id", supaUser.ID, "event", payload.Type)
 		_ = c.Error(apiErrors.InternalServerError(err))
 		return
 	}
 
-	slog.Info("Updated Supabase user",
-		"user_id", userID,
-		"email", supaUser.Email,
-		"action", "update",
-	)
-
-	c.JSON(http.StatusOK, gin.H{"status": "updated", "user_id": userID.String()})
-}
-
-func (h *WebhookHandler) handleUserDelete(c *gin.Context, supaUser SupabaseAuthUser) {
-	userID, err := uuid.Parse(supaUser.ID)
-	if err != nil {
-		_ = c.Error(apiErrors.BadRequest("invalid user ID"))
-		return
-	}
-
-	// Soft-delete (GORM default with DeletedAt field)
-	if err := h.db.Where("id = ?", userID).Delete(&model.User{}).Error; err != nil {
-		slog.Error("Failed to delete Supabase user", "error", err, "supabase_id", supaUser.ID)
-		_ = c.Error(apiErrors.InternalServerError(err))
+	if duplicate {
+		c.JSON(http.StatusOK, gin.H{"status": "duplicate", "event_id": eventID})
 		return
 	}
+	h.sync.afterApply(c.Request.Context(), res)
 
-	slog.Info("Deleted Supabase user",
-		"user_id", userID,
-		"action", "delete",
+	slog.Info("Synced Supabase user",
+		"user_id", res.userID,
+		"email", supaUser.Email,
+		"action", strings.ToLower(payload.Type),
+		"changed", res.changed,
 	)
 
-	c.JSON(http.StatusOK, gin.H{"status": "deleted", "user_id": userID.String()})
+	c.JSON(http.StatusOK, gin.H{"status": status, "user_id": res.userID.String()})
 }
 
 // verifyWebhookSignature verifies the HMAC-SHA256 signature of the webhook payload.
@@ -198,3 +183,39 @@ func verifyWebhookSignature(payload []byte, signature, secret string) bool {
 
 	return hmac.Equal([]byte(expected), []byte(signature))
 }
+
+// verifyStandardWebhook checks a Standard Webhooks signature, which covers
+// the id, the timestamp and the body. secret is either raw or in the
+// "v1,whsec_<base64>" / "whsec_<base64>" form Supabase hands out.
+func verifyStandardWebhook(id, timestamp, signatures string, body []byte, secret string, now time.Time) error {
+	if id == "" || timestamp == "" {
+		return errors.New("missing webhook id or timestamp")
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid webhook timestamp")
+	}
+	if sent := time.Unix(ts, 0); now.Sub(sent) > webhookTolerance || sent.Sub(now) > webhookTolerance {
+		return errors.New("stale webhook timestamp")
+	}
+
+	key := []byte(secret)
+	if i := strings.Index(secret, "whsec_"); i >= 0 {
+		if decoded, err := base64.StdEncoding.DecodeString(secret[i+len("whsec_"):]); err == nil {
+			key = decoded
+		}
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "." + timestamp + "."))
+	mac.Write(body)
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	// The header lists space-separated "v1,<base64>" signatures during key rotation
+	for _, sig := range strings.Fields(signatures) {
+		version, value, ok := strings.Cut(sig, ",")
+		if ok && version == "v1" && hmac.Equal([]byte(value), []byte(expected)) {
+			return nil
+		}
+	}
+	return errors.New("invalid webhook signature")
+}
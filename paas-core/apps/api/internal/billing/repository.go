@@ -7,6 +7,7 @@ import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"paas-core/apps/api/internal/config"
 	"paas-core/apps/api/internal/model"
 )
 
@@ -36,12 +37,13 @@ type Repository interface {
 }
 
 type repository struct {
-	db *gorm.DB
+	db                   *gorm.DB
+	countServiceAccounts bool
 }
 
 // NewRepository creates a new billing repository.
-func NewRepository(db *gorm.DB) Repository {
-	return &repository{db: db}
+func NewRepository(db *gorm.DB, cfg config.BillingConfig) Repository {
+	return &repository{db: db, countServiceAccounts: cfg.CountServiceAccounts}
 }
 
 // --- Plans ---
@@ -157,9 +159,12 @@ func (r *repository) CountDeploymentsByOrg(ctx context.Context, orgID uuid.UUID)
 
 func (r *repository) CountMembersByOrg(ctx context.Context, orgID uuid.UUID) (int, error) {
 	var count int64
-	err := r.db.WithContext(ctx).
+	q := r.db.WithContext(ctx).
 		Model(&model.Membership{}).
-		Where("org_id = ?", orgID).
-		Count(&count).Error
+		Where("memberships.org_id = ?", orgID)
+	if !r.countServiceAccounts {
+		q = q.Scopes(model.HumanMembers)
+	}
+	err := q.Count(&count).Error
 	return int(count), err
 }
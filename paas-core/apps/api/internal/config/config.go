@@ -3,7 +3,9 @@ package config
 import (
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
+	"regexp"
 	"strings"
 	"time"
 
@@ -12,20 +14,29 @@ import (
 
 // Config holds the entire application configuration.
 type Config struct {
-	App        AppConfig        `mapstructure:"app" yaml:"app"`
-	Database   DatabaseConfig   `mapstructure:"database" yaml:"database"`
-	JWT        JWTConfig        `mapstructure:"jwt" yaml:"jwt"`
-	Server     ServerConfig     `mapstructure:"server" yaml:"server"`
-	Logging    LoggingConfig    `mapstructure:"logging" yaml:"logging"`
-	Ratelimit  RateLimitConfig  `mapstructure:"ratelimit" yaml:"ratelimit"`
-	Migrations MigrationsConfig `mapstructure:"migrations" yaml:"migrations"`
-	Health     HealthConfig     `mapstructure:"health" yaml:"health"`
-	Xendit     XenditConfig     `mapstructure:"xendit" yaml:"xendit"`
-	CORS       CORSConfig       `mapstructure:"cors" yaml:"cors"`
-	Email      EmailConfig      `mapstructure:"email" yaml:"email"`
-	Storage    StorageConfig    `mapstructure:"storage" yaml:"storage"`
-	OAuth      OAuthConfig      `mapstructure:"oauth" yaml:"oauth"`
-	Supabase   SupabaseConfig   `mapstructure:"supabase" yaml:"supabase"`
+	App           AppConfig           `mapstructure:"app" yaml:"app"`
+	Database      DatabaseConfig      `mapstructure:"database" yaml:"database"`
+	JWT           JWTConfig           `mapstructure:"jwt" yaml:"jwt"`
+	Server        ServerConfig        `mapstructure:"server" yaml:"server"`
+	Logging       LoggingConfig       `mapstructure:"logging" yaml:"logging"`
+	Ratelimit     RateLimitConfig     `mapstructure:"ratelimit" yaml:"ratelimit"`
+	Migrations    MigrationsConfig    `mapstructure:"migrations" yaml:"migrations"`
+	Health        HealthConfig        `mapstructure:"health" yaml:"health"`
+	Xendit        XenditConfig        `mapstructure:"xendit" yaml:"xendit"`
+	CORS          CORSConfig          `mapstructure:"cors" yaml:"cors"`
+	Email         EmailConfig         `mapstructure:"email" yaml:"email"`
+	Storage       StorageConfig       `mapstructure:"storage" yaml:"storage"`
+	OAuth         OAuthConfig         `mapstructure:"oauth" yaml:"oauth"`
+	Supabase      SupabaseConfig      `mapstructure:"supabase" yaml:"supabase"`
+	OIDC          OIDCConfig          `mapstructure:"oidc" yaml:"oidc"`
+	LDAP          LDAPConfig          `mapstructure:"ldap" yaml:"ldap"`
+	WebAuthn      WebAuthnConfig      `mapstructure:"webauthn" yaml:"webauthn"`
+	MFA           MFAConfig           `mapstructure:"mfa" yaml:"mfa"`
+	Billing       BillingConfig       `mapstructure:"billing" yaml:"billing"`
+	Session       SessionConfig       `mapstructure:"session" yaml:"session"`
+	IDP           IDPConfig           `mapstructure:"idp" yaml:"idp"`
+	Password      PasswordConfig      `mapstructure:"password" yaml:"password"`
+	AuthMigration AuthMigrationConfig `mapstructure:"auth_migration" yaml:"auth_migration"`
 }
 
 type AppConfig struct {
@@ -53,9 +64,14 @@ func (d *DatabaseConfig) DSN() string {
 }
 
 type JWTConfig struct {
-	Secret          string        `mapstructure:"secret" yaml:"secret"`
-	AccessTokenTTL  time.Duration `mapstructure:"access_token_ttl" yaml:"access_token_ttl"`
-	RefreshTokenTTL time.Duration `mapstructure:"refresh_token_ttl" yaml:"refresh_token_ttl"`
+	Secret           string        `mapstructure:"secret" yaml:"secret"`       // only used when algorithm is HS256
+	Algorithm        string        `mapstructure:"algorithm" yaml:"algorithm"` // "RS256" (default), "EdDSA", or legacy "HS256"
+	Issuer           string        `mapstructure:"issuer" yaml:"issuer"`       // "iss" claim, e.g. "https://api.example.com"
+	AccessTokenTTL   time.Duration `mapstructure:"access_token_ttl" yaml:"access_token_ttl"`
+	RefreshTokenTTL  time.Duration `mapstructure:"refresh_token_ttl" yaml:"refresh_token_ttl"`
+	RotationInterval time.Duration `mapstructure:"rotation_interval" yaml:"rotation_interval"`   // how often a new signing key is generated
+	PreviousKeyTTL   time.Duration `mapstructure:"previous_key_ttl" yaml:"previous_key_ttl"`     // how long a rotated-out key still verifies tokens
+	KeyEncryptionKey string        `mapstructure:"key_encryption_key" yaml:"key_encryption_key"` // encrypts stored private signing keys; empty stores them in plaintext
 }
 
 type ServerConfig struct {
@@ -88,6 +104,44 @@ type HealthConfig struct {
 	DatabaseCheckEnabled bool `mapstructure:"database_check_enabled" yaml:"database_check_enabled"`
 }
 
+// BillingConfig controls how plan quotas are counted.
+type BillingConfig struct {
+	CountServiceAccounts bool `mapstructure:"count_service_accounts" yaml:"count_service_accounts"` // count service accounts toward the "members" quota
+}
+
+// Session transport modes.
+const (
+	SessionTransportBearer = "bearer" // tokens are returned in JSON bodies only
+	SessionTransportCookie = "cookie" // tokens are set as HttpOnly cookies only
+	SessionTransportBoth   = "both"   // cookies plus JSON bodies, for mixed clients
+)
+
+// SessionConfig controls how login, refresh and logout hand tokens to clients.
+type SessionConfig struct {
+	Transport    string        `mapstructure:"transport" yaml:"transport"`           // "bearer" (default), "cookie", or "both"
+	CookieDomain string        `mapstructure:"cookie_domain" yaml:"cookie_domain"`   // e.g. ".example.com"; empty means host-only
+	SameSite     string        `mapstructure:"same_site" yaml:"same_site"`           // "lax" (default), "strict", or "none"
+	Secure       bool          `mapstructure:"secure" yaml:"secure"`                 // always set Secure; implied in production and by SameSite=None
+	ReauthMaxAge time.Duration `mapstructure:"reauth_max_age" yaml:"reauth_max_age"` // how recent a sign-in sensitive routes require (default 10m)
+}
+
+// UsesCookies reports whether tokens are delivered as cookies.
+func (s *SessionConfig) UsesCookies() bool {
+	return s.Transport == SessionTransportCookie || s.Transport == SessionTransportBoth
+}
+
+// SameSiteMode returns the SameSite attribute for session and CSRF cookies.
+func (s *SessionConfig) SameSiteMode() http.SameSite {
+	switch strings.ToLower(s.SameSite) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
 type XenditConfig struct {
 	SecretKey    string `mapstructure:"secret_key" yaml:"secret_key"`
 	WebhookToken string `mapstructure:"webhook_token" yaml:"webhook_token"`
@@ -121,18 +175,122 @@ type StorageConfig struct {
 	PublicURL       string `mapstructure:"public_url" yaml:"public_url"`               // optional CDN/custom URL prefix
 }
 
-// OAuthConfig configures external OAuth identity providers.
+// OAuthConfig configures external OAuth identity providers. Each entry of
+// Providers adds a login button; Google and GitHub are shorthands kept for the
+// OAUTH_GOOGLE_* and OAUTH_GITHUB_* environment variables.
 type OAuthConfig struct {
-	Google      OAuthProviderConfig `mapstructure:"google" yaml:"google"`
-	GitHub      OAuthProviderConfig `mapstructure:"github" yaml:"github"`
-	FrontendURL string              `mapstructure:"frontend_url" yaml:"frontend_url"` // redirect target after callback
+	Providers   []OAuthProviderConfig `mapstructure:"providers" yaml:"providers"`
+	Google      OAuthProviderConfig   `mapstructure:"google" yaml:"google"`
+	GitHub      OAuthProviderConfig   `mapstructure:"github" yaml:"github"`
+	FrontendURL string                `mapstructure:"frontend_url" yaml:"frontend_url"` // redirect target after callback; redirect_to must stay on this origin
+	StateSecret string                `mapstructure:"state_secret" yaml:"state_secret"` // seals the state of login round trips; shared by all API instances
 }
 
-// OAuthProviderConfig holds credentials for a single OAuth provider.
+// OAuthProviderConfig configures a single OAuth provider.
 type OAuthProviderConfig struct {
-	ClientID     string `mapstructure:"client_id" yaml:"client_id"`
-	ClientSecret string `mapstructure:"client_secret" yaml:"client_secret"`
-	Enabled      bool   `mapstructure:"enabled" yaml:"enabled"`
+	Name         string   `mapstructure:"name" yaml:"name"`                 // URL segment and account link key (default: type); never rename once users linked accounts
+	Type         string   `mapstructure:"type" yaml:"type"`                 // "google", "github", "gitlab", "microsoft", "bitbucket" or "oidc"
+	DisplayName  string   `mapstructure:"display_name" yaml:"display_name"` // button label (default derived from type)
+	ClientID     string   `mapstructure:"client_id" yaml:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret" yaml:"client_secret"`
+	Enabled      bool     `mapstructure:"enabled" yaml:"enabled"`
+	Scopes       []string `mapstructure:"scopes" yaml:"scopes"`             // overrides the type's default scopes
+	BaseURL      string   `mapstructure:"base_url" yaml:"base_url"`         // gitlab: self-hosted instance (default https://gitlab.com)
+	Tenant       string   `mapstructure:"tenant" yaml:"tenant"`             // microsoft: tenant ID, "organizations", "consumers" or "common" (default)
+	Issuer       string   `mapstructure:"issuer" yaml:"issuer"`             // oidc: endpoints are discovered from <issuer>/.well-known/openid-configuration; ID tokens must carry it as "iss"
+	AuthURL      string   `mapstructure:"auth_url" yaml:"auth_url"`         // oidc: authorization endpoint, overrides discovery
+	TokenURL     string   `mapstructure:"token_url" yaml:"token_url"`       // oidc: token endpoint, overrides discovery
+	UserInfoURL  string   `mapstructure:"userinfo_url" yaml:"userinfo_url"` // oidc: userinfo endpoint, overrides discovery
+}
+
+// oauthProviderNamePattern keeps provider names usable as URL segments and
+// within the oauth_accounts.provider column. "providers" is taken by the
+// listing route.
+var oauthProviderNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+
+// EnabledProviders returns the enabled providers with Name and Type filled
+// in, the Google and GitHub shorthands included unless Providers already has
+// an entry of that name.
+func (o *OAuthConfig) EnabledProviders() []OAuthProviderConfig {
+	var out []OAuthProviderConfig
+	names := map[string]bool{}
+	for _, p := range o.Providers {
+		if p.Name == "" {
+			p.Name = p.Type
+		}
+		names[p.Name] = true
+		if p.Enabled {
+			out = append(out, p)
+		}
+	}
+	google, github := o.Google, o.GitHub
+	google.Name, google.Type = "google", "google"
+	github.Name, github.Type = "github", "github"
+	for _, p := range []OAuthProviderConfig{google, github} {
+		if p.Enabled && !names[p.Name] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// validate checks the enabled providers.
+func (o *OAuthConfig) validate() error {
+	seen := map[string]bool{}
+	for _, p := range o.EnabledProviders() {
+		if !oauthProviderNamePattern.MatchString(p.Name) || p.Name == "providers" {
+			return fmt.Errorf("invalid oauth provider name %q: use lowercase letters, digits, '-' or '_' (\"providers\" is reserved)", p.Name)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("oauth provider %q is configured twice", p.Name)
+		}
+		seen[p.Name] = true
+		if p.ClientID == "" {
+			return fmt.Errorf("oauth provider %q needs a client_id", p.Name)
+		}
+		switch p.Type {
+		case "google", "github", "gitlab", "microsoft", "bitbucket":
+		case "oidc":
+			if p.Issuer == "" && (p.AuthURL == "" || p.TokenURL == "" || p.UserInfoURL == "") {
+				return fmt.Errorf("oauth provider %q needs an issuer, or auth_url, token_url and userinfo_url", p.Name)
+			}
+		default:
+			return fmt.Errorf("oauth provider %q has unsupported type %q", p.Name, p.Type)
+		}
+	}
+	return nil
+}
+
+// WebAuthnConfig configures passkey (WebAuthn) login. Passkeys are disabled
+// when RPID is empty.
+type WebAuthnConfig struct {
+	RPID          string   `mapstructure:"rp_id" yaml:"rp_id"`                     // relying party ID, e.g. "example.com"
+	RPDisplayName string   `mapstructure:"rp_display_name" yaml:"rp_display_name"` // shown by the authenticator
+	RPOrigins     []string `mapstructure:"rp_origins" yaml:"rp_origins"`           // e.g. ["https://app.example.com"]
+}
+
+// MFAConfig configures two-factor authentication.
+type MFAConfig struct {
+	EncryptionKey string `mapstructure:"encryption_key" yaml:"encryption_key"` // encrypts TOTP secrets at rest; never change once factors are enrolled
+}
+
+// IDPConfig configures the built-in OpenID Connect provider that lets apps
+// hosted on the platform sign users in with their platform account.
+type IDPConfig struct {
+	Enabled    bool   `mapstructure:"enabled" yaml:"enabled"`
+	Issuer     string `mapstructure:"issuer" yaml:"issuer"`           // public URL of this API, e.g. "https://api.example.com"; defaults to jwt.issuer
+	ConsentURL string `mapstructure:"consent_url" yaml:"consent_url"` // frontend login/consent page; defaults to oauth.frontend_url + "/oauth2/consent"
+}
+
+// PasswordConfig selects the algorithm and cost of new password hashes.
+// Hashes made with other settings still verify and are upgraded on login.
+type PasswordConfig struct {
+	Algorithm         string `mapstructure:"algorithm" yaml:"algorithm"`                   // "argon2id" (default) or "bcrypt"
+	Argon2Memory      uint32 `mapstructure:"argon2_memory" yaml:"argon2_memory"`           // KiB (default 65536 = 64 MiB)
+	Argon2Iterations  uint32 `mapstructure:"argon2_iterations" yaml:"argon2_iterations"`   // default 3
+	Argon2Parallelism uint8  `mapstructure:"argon2_parallelism" yaml:"argon2_parallelism"` // default 2
+	BcryptCost        int    `mapstructure:"bcrypt_cost" yaml:"bcrypt_cost"`               // default 12
+	BreachFilter      string `mapstructure:"breach_filter" yaml:"breach_filter"`           // path to a filter built with cmd/breachfilter; empty disables screening
 }
 
 // SupabaseConfig configures Supabase integration (cloud or community on-prem).
@@ -141,14 +299,74 @@ type SupabaseConfig struct {
 	URL           string `mapstructure:"url" yaml:"url"`                       // e.g. https://xyz.supabase.co or http://localhost:8000
 	AnonKey       string `mapstructure:"anon_key" yaml:"anon_key"`             // public anon key
 	ServiceKey    string `mapstructure:"service_key" yaml:"service_key"`       // service_role key (admin operations)
-	JWTSecret     string `mapstructure:"jwt_secret" yaml:"jwt_secret"`         // for HS256 JWT validation
+	JWTSecret     string `mapstructure:"jwt_secret" yaml:"jwt_secret"`         // for HS256 JWT validation; asymmetric keys come from the project's JWKS
+	Issuer        string `mapstructure:"issuer" yaml:"issuer"`                 // expected "iss" (default <url>/auth/v1)
+	Audience      string `mapstructure:"audience" yaml:"audience"`             // expected "aud" (default "authenticated")
 	DBHost        string `mapstructure:"db_host" yaml:"db_host"`               // direct DB host (optional, for GORM)
 	DBPort        int    `mapstructure:"db_port" yaml:"db_port"`               // direct DB port (default 5432)
 	DBName        string `mapstructure:"db_name" yaml:"db_name"`               // database name (default "postgres")
 	DBUser        string `mapstructure:"db_user" yaml:"db_user"`               // database user (default "postgres")
 	DBPassword    string `mapstructure:"db_password" yaml:"db_password"`       // database password
 	DBSSLMode     string `mapstructure:"db_sslmode" yaml:"db_sslmode"`         // SSL mode (default "disable" for local)
-	WebhookSecret string `mapstructure:"webhook_secret" yaml:"webhook_secret"` // secret to verify Supabase webhook payloads
+	WebhookSecret string `mapstructure:"webhook_secret" yaml:"webhook_secret"` // secret to verify Supabase webhook payloads; the webhook is not registered without it
+}
+
+// OIDCConfig configures an external OpenID Connect identity provider, such as
+// Keycloak, Auth0 or Zitadel, as the auth provider. Endpoints and signing keys
+// are found through discovery on the issuer; users are created locally the
+// first time one of their tokens is seen.
+type OIDCConfig struct {
+	Enabled       bool   `mapstructure:"enabled" yaml:"enabled"`               // use the IdP instead of local auth; exclusive with supabase.enabled
+	Issuer        string `mapstructure:"issuer" yaml:"issuer"`                 // e.g. https://keycloak.example.com/realms/main
+	ClientID      string `mapstructure:"client_id" yaml:"client_id"`           // client used for the password and refresh grants
+	ClientSecret  string `mapstructure:"client_secret" yaml:"client_secret"`   // empty for public clients
+	Audience      string `mapstructure:"audience" yaml:"audience"`             // required "aud" of access tokens; empty skips the check
+	Scopes        string `mapstructure:"scopes" yaml:"scopes"`                 // space-separated (default "openid profile email offline_access")
+	EmailClaim    string `mapstructure:"email_claim" yaml:"email_claim"`       // default "email"
+	NameClaim     string `mapstructure:"name_claim" yaml:"name_claim"`         // default "name"
+	RolesClaim    string `mapstructure:"roles_claim" yaml:"roles_claim"`       // dot path, e.g. "realm_access.roles"; empty maps no roles
+	PasswordGrant bool   `mapstructure:"password_grant" yaml:"password_grant"` // allow /auth/login through the IdP's password grant
+}
+
+// LDAPConfig configures an LDAP or Active Directory server as the auth
+// provider. Users are found with a search, authenticated by binding as
+// themselves and created locally on their first sign-in.
+type LDAPConfig struct {
+	Enabled      bool   `mapstructure:"enabled" yaml:"enabled"`             // use the directory instead of local auth; exclusive with supabase.enabled and oidc.enabled
+	URL          string `mapstructure:"url" yaml:"url"`                     // ldap://host:389 or ldaps://host:636
+	StartTLS     bool   `mapstructure:"start_tls" yaml:"start_tls"`         // upgrade ldap:// connections with StartTLS
+	CACertFile   string `mapstructure:"ca_cert_file" yaml:"ca_cert_file"`   // PEM bundle the server certificate is checked against; empty uses the system roots
+	BindDN       string `mapstructure:"bind_dn" yaml:"bind_dn"`             // service account used for searches; empty binds anonymously
+	BindPassword string `mapstructure:"bind_password" yaml:"bind_password"` // password of bind_dn
+	BaseDN       string `mapstructure:"base_dn" yaml:"base_dn"`             // e.g. dc=example,dc=com
+	UserFilter   string `mapstructure:"user_filter" yaml:"user_filter"`     // %s is the escaped email (default "(mail=%s)"); AD: "(&(userPrincipalName=%s)(!(userAccountControl:1.2.840.113556.1.4.803:=2)))"
+	EmailAttr    string `mapstructure:"email_attr" yaml:"email_attr"`       // default "mail"
+	NameAttr     string `mapstructure:"name_attr" yaml:"name_attr"`         // default "cn"; AD: "displayName"
+	GroupFilter  string `mapstructure:"group_filter" yaml:"group_filter"`   // %s is the escaped user DN, e.g. "(member=%s)"; empty reads the memberOf attribute
+	GroupBaseDN  string `mapstructure:"group_base_dn" yaml:"group_base_dn"` // default base_dn
+	RoleGroups   string `mapstructure:"role_groups" yaml:"role_groups"`     // "role=group DN" pairs separated by ";"; empty leaves system roles alone
+	OrgGroups    string `mapstructure:"org_groups" yaml:"org_groups"`       // "org-slug/org-role=group DN" pairs separated by ";"
+}
+
+// AuthMigrationConfig keeps a previous auth provider's sessions valid while
+// users move to the current one, see authprovider.CompositeProvider.
+type AuthMigrationConfig struct {
+	From string `mapstructure:"from" yaml:"from"` // "local", "supabase", "oidc" or "ldap"; empty disables the migration mode
+}
+
+// AuthProvider returns the name of the auth provider new sessions are
+// issued by: "supabase", "oidc", "ldap" or "local".
+func (c *Config) AuthProvider() string {
+	switch {
+	case c.Supabase.Enabled:
+		return "supabase"
+	case c.OIDC.Enabled:
+		return "oidc"
+	case c.LDAP.Enabled:
+		return "ldap"
+	default:
+		return "local"
+	}
 }
 
 // DSN returns the Supabase PostgreSQL connection string.
@@ -254,11 +472,69 @@ func (c *Config) Validate() error {
 	if c.Database.Name == "" {
 		return fmt.Errorf("database name is required")
 	}
-	if c.JWT.Secret == "" || c.JWT.Secret == "change-me-in-production" {
-		if c.App.Environment == "production" {
-			return fmt.Errorf("JWT secret must be set in production")
+	switch c.JWT.Algorithm {
+	case "", "RS256", "EdDSA":
+	case "HS256":
+		if c.JWT.Secret == "" || c.JWT.Secret == "change-me-in-production" {
+			if c.App.Environment == "production" {
+				return fmt.Errorf("JWT secret must be set in production")
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported JWT algorithm %q", c.JWT.Algorithm)
+	}
+	if c.JWT.Algorithm != "HS256" && (c.JWT.KeyEncryptionKey == "" || c.JWT.KeyEncryptionKey == "change-me-in-production") && c.App.Environment == "production" {
+		return fmt.Errorf("JWT key encryption key must be set in production")
+	}
+	if (c.MFA.EncryptionKey == "" || c.MFA.EncryptionKey == "change-me-in-production") && c.App.Environment == "production" {
+		return fmt.Errorf("MFA encryption key must be set in production")
+	}
+	switch c.Session.Transport {
+	case "", SessionTransportBearer, SessionTransportCookie, SessionTransportBoth:
+	default:
+		return fmt.Errorf("unsupported session transport %q", c.Session.Transport)
+	}
+	switch strings.ToLower(c.Session.SameSite) {
+	case "", "lax", "strict", "none":
+	default:
+		return fmt.Errorf("unsupported session same_site %q", c.Session.SameSite)
+	}
+	if c.OIDC.Enabled || c.AuthMigration.From == "oidc" {
+		if c.Supabase.Enabled && c.OIDC.Enabled {
+			return fmt.Errorf("oidc and supabase auth providers cannot both be enabled")
+		}
+		if c.OIDC.Issuer == "" || c.OIDC.ClientID == "" {
+			return fmt.Errorf("oidc issuer and client_id are required")
+		}
+	}
+	if c.LDAP.Enabled || c.AuthMigration.From == "ldap" {
+		if c.LDAP.Enabled && (c.Supabase.Enabled || c.OIDC.Enabled) {
+			return fmt.Errorf("ldap cannot be enabled together with the supabase or oidc auth provider")
+		}
+		if c.LDAP.URL == "" || c.LDAP.BaseDN == "" {
+			return fmt.Errorf("ldap url and base_dn are required")
 		}
 	}
+	switch c.AuthMigration.From {
+	case "":
+	case "local", "supabase", "oidc", "ldap":
+		if c.AuthMigration.From == c.AuthProvider() {
+			return fmt.Errorf("auth_migration.from must differ from the active auth provider %q", c.AuthProvider())
+		}
+		if c.AuthMigration.From == "supabase" && c.Supabase.URL == "" {
+			return fmt.Errorf("supabase url is required to migrate from supabase")
+		}
+	default:
+		return fmt.Errorf("unsupported auth_migration.from %q", c.AuthMigration.From)
+	}
+	if err := c.OAuth.validate(); err != nil {
+		return err
+	}
+	switch c.Password.Algorithm {
+	case "", "argon2id", "bcrypt":
+	default:
+		return fmt.Errorf("unsupported password algorithm %q", c.Password.Algorithm)
+	}
 	return nil
 }
 
@@ -277,6 +553,11 @@ func bindEnvVariables(v *viper.Viper) {
 		"jwt.secret":                    "JWT_SECRET",
 		"jwt.access_token_ttl":          "JWT_ACCESS_TOKEN_TTL",
 		"jwt.refresh_token_ttl":         "JWT_REFRESH_TOKEN_TTL",
+		"jwt.algorithm":                 "JWT_ALGORITHM",
+		"jwt.issuer":                    "JWT_ISSUER",
+		"jwt.rotation_interval":         "JWT_ROTATION_INTERVAL",
+		"jwt.previous_key_ttl":          "JWT_PREVIOUS_KEY_TTL",
+		"jwt.key_encryption_key":        "JWT_KEY_ENCRYPTION_KEY",
 		"server.port":                   "SERVER_PORT",
 		"server.readtimeout":            "SERVER_READTIMEOUT",
 		"server.writetimeout":           "SERVER_WRITETIMEOUT",
@@ -298,12 +579,19 @@ func bindEnvVariables(v *viper.Viper) {
 		"oauth.github.client_secret":    "OAUTH_GITHUB_CLIENT_SECRET",
 		"oauth.github.enabled":          "OAUTH_GITHUB_ENABLED",
 		"oauth.frontend_url":            "OAUTH_FRONTEND_URL",
+		"oauth.state_secret":            "OAUTH_STATE_SECRET",
+		"webauthn.rp_id":                "WEBAUTHN_RP_ID",
+		"webauthn.rp_display_name":      "WEBAUTHN_RP_DISPLAY_NAME",
+		"webauthn.rp_origins":           "WEBAUTHN_RP_ORIGINS",
+		"mfa.encryption_key":            "MFA_ENCRYPTION_KEY",
 		// Supabase
 		"supabase.enabled":        "SUPABASE_ENABLED",
 		"supabase.url":            "SUPABASE_URL",
 		"supabase.anon_key":       "SUPABASE_ANON_KEY",
 		"supabase.service_key":    "SUPABASE_SERVICE_KEY",
 		"supabase.jwt_secret":     "SUPABASE_JWT_SECRET",
+		"supabase.issuer":         "SUPABASE_ISSUER",
+		"supabase.audience":       "SUPABASE_AUDIENCE",
 		"supabase.db_host":        "SUPABASE_DB_HOST",
 		"supabase.db_port":        "SUPABASE_DB_PORT",
 		"supabase.db_name":        "SUPABASE_DB_NAME",
@@ -311,6 +599,53 @@ func bindEnvVariables(v *viper.Viper) {
 		"supabase.db_password":    "SUPABASE_DB_PASSWORD",
 		"supabase.db_sslmode":     "SUPABASE_DB_SSLMODE",
 		"supabase.webhook_secret": "SUPABASE_WEBHOOK_SECRET",
+		// External OpenID Connect auth provider
+		"oidc.enabled":        "OIDC_ENABLED",
+		"oidc.issuer":         "OIDC_ISSUER",
+		"oidc.client_id":      "OIDC_CLIENT_ID",
+		"oidc.client_secret":  "OIDC_CLIENT_SECRET",
+		"oidc.audience":       "OIDC_AUDIENCE",
+		"oidc.scopes":         "OIDC_SCOPES",
+		"oidc.email_claim":    "OIDC_EMAIL_CLAIM",
+		"oidc.name_claim":     "OIDC_NAME_CLAIM",
+		"oidc.roles_claim":    "OIDC_ROLES_CLAIM",
+		"oidc.password_grant": "OIDC_PASSWORD_GRANT",
+		// LDAP / Active Directory auth provider
+		"ldap.enabled":       "LDAP_ENABLED",
+		"ldap.url":           "LDAP_URL",
+		"ldap.start_tls":     "LDAP_START_TLS",
+		"ldap.ca_cert_file":  "LDAP_CA_CERT_FILE",
+		"ldap.bind_dn":       "LDAP_BIND_DN",
+		"ldap.bind_password": "LDAP_BIND_PASSWORD",
+		"ldap.base_dn":       "LDAP_BASE_DN",
+		"ldap.user_filter":   "LDAP_USER_FILTER",
+		"ldap.email_attr":    "LDAP_EMAIL_ATTR",
+		"ldap.name_attr":     "LDAP_NAME_ATTR",
+		"ldap.group_filter":  "LDAP_GROUP_FILTER",
+		"ldap.group_base_dn": "LDAP_GROUP_BASE_DN",
+		"ldap.role_groups":   "LDAP_ROLE_GROUPS",
+		"ldap.org_groups":    "LDAP_ORG_GROUPS",
+		// Auth provider migration
+		"auth_migration.from": "AUTH_MIGRATION_FROM",
+		// Billing
+		"billing.count_service_accounts": "BILLING_COUNT_SERVICE_ACCOUNTS",
+		// Session cookies
+		"session.transport":      "SESSION_TRANSPORT",
+		"session.cookie_domain":  "SESSION_COOKIE_DOMAIN",
+		"session.same_site":      "SESSION_SAME_SITE",
+		"session.secure":         "SESSION_SECURE",
+		"session.reauth_max_age": "SESSION_REAUTH_MAX_AGE",
+		// OpenID Connect provider
+		"idp.enabled":     "IDP_ENABLED",
+		"idp.issuer":      "IDP_ISSUER",
+		"idp.consent_url": "IDP_CONSENT_URL",
+		// Password hashing
+		"password.algorithm":          "PASSWORD_ALGORITHM",
+		"password.argon2_memory":      "PASSWORD_ARGON2_MEMORY",
+		"password.argon2_iterations":  "PASSWORD_ARGON2_ITERATIONS",
+		"password.argon2_parallelism": "PASSWORD_ARGON2_PARALLELISM",
+		"password.bcrypt_cost":        "PASSWORD_BCRYPT_COST",
+		"password.breach_filter":      "PASSWORD_BREACH_FILTER",
 	}
 	for key, env := range envBindings {
 		_ = v.BindEnv(key, env)
@@ -363,9 +698,19 @@ func (c *Config) LogSafeConfig(logger *slog.Logger) {
 	logger.Info("Loaded Configuration:")
 	logger.Info("App", "Name", c.App.Name, "Environment", c.App.Environment, "Debug", c.App.Debug)
 	logger.Info("Database", "Host", c.Database.Host, "Port", c.Database.Port, "Name", c.Database.Name, "SSLMode", c.Database.SSLMode)
-	logger.Info("JWT", "Secret", "<redacted>", "AccessTokenTTL", c.JWT.AccessTokenTTL, "RefreshTokenTTL", c.JWT.RefreshTokenTTL)
+	logger.Info("JWT", "Algorithm", c.JWT.Algorithm, "Secret", "<redacted>", "AccessTokenTTL", c.JWT.AccessTokenTTL, "RefreshTokenTTL", c.JWT.RefreshTokenTTL, "RotationInterval", c.JWT.RotationInterval, "KeyEncryptionKey", "<redacted>")
 	logger.Info("Server", "Port", c.Server.Port, "ReadTimeout", c.Server.ReadTimeout, "WriteTimeout", c.Server.WriteTimeout)
 	logger.Info("RateLimit", "Enabled", c.Ratelimit.Enabled, "Requests", c.Ratelimit.Requests, "Window", c.Ratelimit.Window)
-	logger.Info("OAuth", "GoogleEnabled", c.OAuth.Google.Enabled, "GitHubEnabled", c.OAuth.GitHub.Enabled, "FrontendURL", c.OAuth.FrontendURL)
+	oauthProviders := []string{}
+	for _, p := range c.OAuth.EnabledProviders() {
+		oauthProviders = append(oauthProviders, p.Name)
+	}
+	logger.Info("OAuth", "Providers", oauthProviders, "FrontendURL", c.OAuth.FrontendURL, "StateSecret", "<redacted>")
+	logger.Info("MFA", "EncryptionKey", "<redacted>")
+	logger.Info("Session", "Transport", c.Session.Transport, "CookieDomain", c.Session.CookieDomain, "SameSite", c.Session.SameSite)
+	logger.Info("IDP", "Enabled", c.IDP.Enabled, "Issuer", c.IDP.Issuer)
 	logger.Info("Supabase", "Enabled", c.Supabase.Enabled, "URL", c.Supabase.URL, "AnonKey", "<redacted>", "ServiceKey", "<redacted>")
+	logger.Info("OIDC", "Enabled", c.OIDC.Enabled, "Issuer", c.OIDC.Issuer, "ClientID", c.OIDC.ClientID, "ClientSecret", "<redacted>")
+	logger.Info("LDAP", "Enabled", c.LDAP.Enabled, "URL", c.LDAP.URL, "StartTLS", c.LDAP.StartTLS, "BindDN", c.LDAP.BindDN, "BindPassword", "<redacted>", "BaseDN", c.LDAP.BaseDN)
+	logger.Info("AuthMigration", "Provider", c.AuthProvider(), "From", c.AuthMigration.From)
 }
@@ -3,15 +3,15 @@ package database
 import (
 	"log/slog"
 
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 
 	"paas-core/apps/api/internal/model"
+	"paas-core/apps/api/internal/password"
 )
 
 // SeedDevUsers creates default dev/test users if they don't already exist.
 // Only call this in non-production environments.
-func SeedDevUsers(db *gorm.DB) {
+func SeedDevUsers(db *gorm.DB, hasher password.Hasher) {
 	// Ensure required roles exist
 	seedRoles(db)
 
@@ -34,7 +34,7 @@ func SeedDevUsers(db *gorm.DB) {
 			continue
 		}
 
-		hash, err := bcrypt.GenerateFromPassword([]byte(su.Password), bcrypt.DefaultCost)
+		hash, err := hasher.Hash(su.Password)
 		if err != nil {
 			slog.Error("Failed to hash password for dev user", "email", su.Email, "error", err)
 			continue
@@ -43,7 +43,7 @@ func SeedDevUsers(db *gorm.DB) {
 		user := model.User{
 			Name:          su.Name,
 			Email:         su.Email,
-			PasswordHash:  string(hash),
+			PasswordHash:  hash,
 			EmailVerified: true,
 		}
 
@@ -29,4 +29,8 @@ type TemplateData struct {
 	Token     string
 	Link      string
 	ExpiresIn string // human-readable, e.g. "15 minutes"
+	NewEmail  string // email change notices: the requested address
+	Device    string // security alerts: user agent of the device involved
+	IPAddress string // security alerts: IP address of the device involved
+	Detail    string // security alerts: what changed, e.g. "Two-factor authentication was turned off"
 }
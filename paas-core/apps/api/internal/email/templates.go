@@ -57,3 +57,269 @@ func RenderPasswordResetEmail(data TemplateData) Message {
 		TextBody: text,
 	}
 }
+
+// RenderAccountLockedEmail returns the HTML body for an account lockout notice
+// with an unlock link.
+func RenderAccountLockedEmail(data TemplateData) Message {
+	html := fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"></head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; max-width: 600px; margin: 0 auto; padding: 40px 20px; color: #1a1a1a;">
+  <h1 style="font-size: 24px; margin-bottom: 24px;">Your account has been locked</h1>
+  <p>Hi %s,</p>
+  <p>We temporarily locked your <strong>%s</strong> account after too many failed sign-in attempts. If this was you, click the button below to unlock it now.</p>
+  <div style="text-align: center; margin: 32px 0;">
+    <a href="%s" style="display: inline-block; padding: 12px 32px; background: #0070f3; color: #fff; text-decoration: none; border-radius: 6px; font-weight: 600;">Unlock Account</a>
+  </div>
+  <p style="font-size: 14px; color: #666;">This link expires in %s. If you didn't try to sign in, someone may be guessing your password — we recommend resetting it.</p>
+  <hr style="border: none; border-top: 1px solid #eee; margin: 32px 0;">
+  <p style="font-size: 12px; color: #999;">%s</p>
+</body>
+</html>`, data.UserName, data.AppName, data.Link, data.ExpiresIn, data.AppName)
+
+	text := fmt.Sprintf("Hi %s,\n\nYour %s account was locked after too many failed sign-in attempts. Unlock it: %s\n\nThis link expires in %s. If this wasn't you, reset your password.", data.UserName, data.AppName, data.Link, data.ExpiresIn)
+
+	return Message{
+		To:       data.UserEmail,
+		Subject:  fmt.Sprintf("Your account has been locked — %s", data.AppName),
+		HTMLBody: html,
+		TextBody: text,
+	}
+}
+
+// RenderMagicLinkEmail returns the HTML body for a passwordless sign-in link.
+func RenderMagicLinkEmail(data TemplateData) Message {
+	html := fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"></head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; max-width: 600px; margin: 0 auto; padding: 40px 20px; color: #1a1a1a;">
+  <h1 style="font-size: 24px; margin-bottom: 24px;">Sign in to %s</h1>
+  <p>Hi %s,</p>
+  <p>Click the button below to sign in to <strong>%s</strong>. No password needed.</p>
+  <div style="text-align: center; margin: 32px 0;">
+    <a href="%s" style="display: inline-block; padding: 12px 32px; background: #0070f3; color: #fff; text-decoration: none; border-radius: 6px; font-weight: 600;">Sign In</a>
+  </div>
+  <p style="font-size: 14px; color: #666;">This link expires in %s and can be used once. If you didn't request it, you can safely ignore this email.</p>
+  <hr style="border: none; border-top: 1px solid #eee; margin: 32px 0;">
+  <p style="font-size: 12px; color: #999;">%s</p>
+</body>
+</html>`, data.AppName, data.UserName, data.AppName, data.Link, data.ExpiresIn, data.AppName)
+
+	text := fmt.Sprintf("Hi %s,\n\nSign in to %s: %s\n\nThis link expires in %s and can be used once.", data.UserName, data.AppName, data.Link, data.ExpiresIn)
+
+	return Message{
+		To:       data.UserEmail,
+		Subject:  fmt.Sprintf("Your sign-in link — %s", data.AppName),
+		HTMLBody: html,
+		TextBody: text,
+	}
+}
+
+// RenderEmailChangeConfirmEmail returns the HTML body for confirming a new
+// email address. It is sent to the new address.
+func RenderEmailChangeConfirmEmail(data TemplateData) Message {
+	html := fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"></head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; max-width: 600px; margin: 0 auto; padding: 40px 20px; color: #1a1a1a;">
+  <h1 style="font-size: 24px; margin-bottom: 24px;">Confirm your new email</h1>
+  <p>Hi %s,</p>
+  <p>You asked to use <strong>%s</strong> as the email address for your <strong>%s</strong> account. Click the button below to confirm the change.</p>
+  <div style="text-align: center; margin: 32px 0;">
+    <a href="%s" style="display: inline-block; padding: 12px 32px; background: #0070f3; color: #fff; text-decoration: none; border-radius: 6px; font-weight: 600;">Confirm Email</a>
+  </div>
+  <p style="font-size: 14px; color: #666;">This link expires in %s. Your email address won't change until you confirm. If you didn't request this, you can safely ignore this email.</p>
+  <hr style="border: none; border-top: 1px solid #eee; margin: 32px 0;">
+  <p style="font-size: 12px; color: #999;">%s</p>
+</body>
+</html>`, data.UserName, data.NewEmail, data.AppName, data.Link, data.ExpiresIn, data.AppName)
+
+	text := fmt.Sprintf("Hi %s,\n\nConfirm %s as the email address for your %s account: %s\n\nThis link expires in %s.", data.UserName, data.NewEmail, data.AppName, data.Link, data.ExpiresIn)
+
+	return Message{
+		To:       data.NewEmail,
+		Subject:  fmt.Sprintf("Confirm your new email — %s", data.AppName),
+		HTMLBody: html,
+		TextBody: text,
+	}
+}
+
+// RenderEmailChangeNoticeEmail returns the HTML body for the notice sent to
+// the current address when an email change is requested, with a cancel link.
+func RenderEmailChangeNoticeEmail(data TemplateData) Message {
+	html := fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"></head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; max-width: 600px; margin: 0 auto; padding: 40px 20px; color: #1a1a1a;">
+  <h1 style="font-size: 24px; margin-bottom: 24px;">Your email address is being changed</h1>
+  <p>Hi %s,</p>
+  <p>Someone asked to change the email address of your <strong>%s</strong> account to <strong>%s</strong>. The change takes effect once the new address is confirmed.</p>
+  <p>If this wasn't you, cancel the change now and reset your password.</p>
+  <div style="text-align: center; margin: 32px 0;">
+    <a href="%s" style="display: inline-block; padding: 12px 32px; background: #e00; color: #fff; text-decoration: none; border-radius: 6px; font-weight: 600;">Cancel Change</a>
+  </div>
+  <p style="font-size: 14px; color: #666;">This link expires in %s. If you made this request, no action is needed.</p>
+  <hr style="border: none; border-top: 1px solid #eee; margin: 32px 0;">
+  <p style="font-size: 12px; color: #999;">%s</p>
+</body>
+</html>`, data.UserName, data.AppName, data.NewEmail, data.Link, data.ExpiresIn, data.AppName)
+
+	text := fmt.Sprintf("Hi %s,\n\nSomeone asked to change the email address of your %s account to %s. If this wasn't you, cancel the change: %s\n\nThis link expires in %s.", data.UserName, data.AppName, data.NewEmail, data.Link, data.ExpiresIn)
+
+	return Message{
+		To:       data.UserEmail,
+		Subject:  fmt.Sprintf("Your email address is being changed — %s", data.AppName),
+		HTMLBody: html,
+		TextBody: text,
+	}
+}
+
+// RenderTokenReuseAlertEmail returns the HTML body for the alert sent when a
+// refresh token is used twice, which usually means it was stolen.
+func RenderTokenReuseAlertEmail(data TemplateData) Message {
+	html := fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"></head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; max-width: 600px; margin: 0 auto; padding: 40px 20px; color: #1a1a1a;">
+  <h1 style="font-size: 24px; margin-bottom: 24px;">Suspicious activity on your account</h1>
+  <p>Hi %s,</p>
+  <p>A sign-in token for your <strong>%s</strong> account was used after it had already been replaced. This usually means the token was copied from one of your devices. We signed that session out.</p>
+  <p>Last seen from <strong>%s</strong> (%s). If you don't recognize this, sign out everywhere and reset your password.</p>
+  <div style="text-align: center; margin: 32px 0;">
+    <a href="%s" style="display: inline-block; padding: 12px 32px; background: #e00; color: #fff; text-decoration: none; border-radius: 6px; font-weight: 600;">This Wasn't Me</a>
+  </div>
+  <p style="font-size: 14px; color: #666;">The button signs you out on every device. It expires in %s. If this was you, no action is needed.</p>
+  <hr style="border: none; border-top: 1px solid #eee; margin: 32px 0;">
+  <p style="font-size: 12px; color: #999;">%s</p>
+</body>
+</html>`, data.UserName, data.AppName, data.IPAddress, data.Device, data.Link, data.ExpiresIn, data.AppName)
+
+	text := fmt.Sprintf("Hi %s,\n\nA sign-in token for your %s account was reused from %s (%s), which usually means it was stolen. We signed that session out. If this wasn't you, sign out everywhere: %s\n\nThis link expires in %s.", data.UserName, data.AppName, data.IPAddress, data.Device, data.Link, data.ExpiresIn)
+
+	return Message{
+		To:       data.UserEmail,
+		Subject:  fmt.Sprintf("Suspicious activity on your account — %s", data.AppName),
+		HTMLBody: html,
+		TextBody: text,
+	}
+}
+
+// RenderNewSignInAlertEmail returns the HTML body for the alert sent when the
+// account is signed in from a new device or IP address.
+func RenderNewSignInAlertEmail(data TemplateData) Message {
+	html := fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"></head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; max-width: 600px; margin: 0 auto; padding: 40px 20px; color: #1a1a1a;">
+  <h1 style="font-size: 24px; margin-bottom: 24px;">New sign-in to your account</h1>
+  <p>Hi %s,</p>
+  <p>Your <strong>%s</strong> account was just signed in from a device or location we haven't seen before.</p>
+  <p>IP address: <strong>%s</strong><br>Device: %s</p>
+  <div style="text-align: center; margin: 32px 0;">
+    <a href="%s" style="display: inline-block; padding: 12px 32px; background: #e00; color: #fff; text-decoration: none; border-radius: 6px; font-weight: 600;">This Wasn't Me</a>
+  </div>
+  <p style="font-size: 14px; color: #666;">The button signs you out on every device. It expires in %s. If this was you, no action is needed.</p>
+  <hr style="border: none; border-top: 1px solid #eee; margin: 32px 0;">
+  <p style="font-size: 12px; color: #999;">%s</p>
+</body>
+</html>`, data.UserName, data.AppName, data.IPAddress, data.Device, data.Link, data.ExpiresIn, data.AppName)
+
+	text := fmt.Sprintf("Hi %s,\n\nYour %s account was signed in from a new device or location: %s (%s). If this wasn't you, sign out everywhere: %s\n\nThis link expires in %s.", data.UserName, data.AppName, data.IPAddress, data.Device, data.Link, data.ExpiresIn)
+
+	return Message{
+		To:       data.UserEmail,
+		Subject:  fmt.Sprintf("New sign-in to your account — %s", data.AppName),
+		HTMLBody: html,
+		TextBody: text,
+	}
+}
+
+// RenderPasswordChangedAlertEmail returns the HTML body for the alert sent after the
+// account password was changed or reset.
+func RenderPasswordChangedAlertEmail(data TemplateData) Message {
+	html := fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"></head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; max-width: 600px; margin: 0 auto; padding: 40px 20px; color: #1a1a1a;">
+  <h1 style="font-size: 24px; margin-bottom: 24px;">Your password was changed</h1>
+  <p>Hi %s,</p>
+  <p>The password of your <strong>%s</strong> account was just changed from <strong>%s</strong> (%s).</p>
+  <p>If this wasn't you, sign out everywhere and reset your password right away.</p>
+  <div style="text-align: center; margin: 32px 0;">
+    <a href="%s" style="display: inline-block; padding: 12px 32px; background: #e00; color: #fff; text-decoration: none; border-radius: 6px; font-weight: 600;">This Wasn't Me</a>
+  </div>
+  <p style="font-size: 14px; color: #666;">The button signs you out on every device. It expires in %s. If this was you, no action is needed.</p>
+  <hr style="border: none; border-top: 1px solid #eee; margin: 32px 0;">
+  <p style="font-size: 12px; color: #999;">%s</p>
+</body>
+</html>`, data.UserName, data.AppName, data.IPAddress, data.Device, data.Link, data.ExpiresIn, data.AppName)
+
+	text := fmt.Sprintf("Hi %s,\n\nThe password of your %s account was changed from %s (%s). If this wasn't you, sign out everywhere: %s\n\nThis link expires in %s.", data.UserName, data.AppName, data.IPAddress, data.Device, data.Link, data.ExpiresIn)
+
+	return Message{
+		To:       data.UserEmail,
+		Subject:  fmt.Sprintf("Your password was changed — %s", data.AppName),
+		HTMLBody: html,
+		TextBody: text,
+	}
+}
+
+// RenderMFAChangedAlertEmail returns the HTML body for the alert sent when the
+// account's two-factor authentication or passkeys change.
+func RenderMFAChangedAlertEmail(data TemplateData) Message {
+	html := fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"></head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; max-width: 600px; margin: 0 auto; padding: 40px 20px; color: #1a1a1a;">
+  <h1 style="font-size: 24px; margin-bottom: 24px;">Your sign-in security settings changed</h1>
+  <p>Hi %s,</p>
+  <p>%s on your <strong>%s</strong> account, from <strong>%s</strong> (%s).</p>
+  <p>If this wasn't you, sign out everywhere and reset your password.</p>
+  <div style="text-align: center; margin: 32px 0;">
+    <a href="%s" style="display: inline-block; padding: 12px 32px; background: #e00; color: #fff; text-decoration: none; border-radius: 6px; font-weight: 600;">This Wasn't Me</a>
+  </div>
+  <p style="font-size: 14px; color: #666;">The button signs you out on every device. It expires in %s. If this was you, no action is needed.</p>
+  <hr style="border: none; border-top: 1px solid #eee; margin: 32px 0;">
+  <p style="font-size: 12px; color: #999;">%s</p>
+</body>
+</html>`, data.UserName, data.Detail, data.AppName, data.IPAddress, data.Device, data.Link, data.ExpiresIn, data.AppName)
+
+	text := fmt.Sprintf("Hi %s,\n\n%s on your %s account, from %s (%s). If this wasn't you, sign out everywhere: %s\n\nThis link expires in %s.", data.UserName, data.Detail, data.AppName, data.IPAddress, data.Device, data.Link, data.ExpiresIn)
+
+	return Message{
+		To:       data.UserEmail,
+		Subject:  fmt.Sprintf("Your sign-in security settings changed — %s", data.AppName),
+		HTMLBody: html,
+		TextBody: text,
+	}
+}
+
+// RenderOAuthLinkedAlertEmail returns the HTML body for the alert sent when a
+// sign-in provider is linked to the account.
+func RenderOAuthLinkedAlertEmail(data TemplateData) Message {
+	html := fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"></head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; max-width: 600px; margin: 0 auto; padding: 40px 20px; color: #1a1a1a;">
+  <h1 style="font-size: 24px; margin-bottom: 24px;">A sign-in provider was linked</h1>
+  <p>Hi %s,</p>
+  <p>%s can now be used to sign in to your <strong>%s</strong> account. It was linked from <strong>%s</strong> (%s).</p>
+  <p>If this wasn't you, sign out everywhere and unlink the provider.</p>
+  <div style="text-align: center; margin: 32px 0;">
+    <a href="%s" style="display: inline-block; padding: 12px 32px; background: #e00; color: #fff; text-decoration: none; border-radius: 6px; font-weight: 600;">This Wasn't Me</a>
+  </div>
+  <p style="font-size: 14px; color: #666;">The button signs you out on every device. It expires in %s. If this was you, no action is needed.</p>
+  <hr style="border: none; border-top: 1px solid #eee; margin: 32px 0;">
+  <p style="font-size: 12px; color: #999;">%s</p>
+</body>
+</html>`, data.UserName, data.Detail, data.AppName, data.IPAddress, data.Device, data.Link, data.ExpiresIn, data.AppName)
+
+	text := fmt.Sprintf("Hi %s,\n\n%s can now be used to sign in to your %s account. It was linked from %s (%s). If this wasn't you, sign out everywhere: %s\n\nThis link expires in %s.", data.UserName, data.Detail, data.AppName, data.IPAddress, data.Device, data.Link, data.ExpiresIn)
+
+	return Message{
+		To:       data.UserEmail,
+		Subject:  fmt.Sprintf("A sign-in provider was linked — %s", data.AppName),
+		HTMLBody: html,
+		TextBody: text,
+	}
+}
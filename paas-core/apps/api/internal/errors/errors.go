@@ -3,6 +3,7 @@ package errors
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -116,6 +117,17 @@ func RateLimitExceeded(retryAfter int) *APIError {
 	}
 }
 
+// ReauthRequired is returned by routes that need a recent sign-in. Clients
+// should call /auth/reauthenticate and retry with the new access token.
+func ReauthRequired(maxAgeSeconds int) *APIError {
+	return &APIError{
+		StatusCode: http.StatusForbidden,
+		Code:       "REAUTH_REQUIRED",
+		Message:    "Please confirm your identity to continue",
+		Details:    map[string]string{"max_age": strconv.Itoa(maxAgeSeconds)},
+	}
+}
+
 // FromGinValidation converts Gin's binding validation errors into a structured APIError.
 func FromGinValidation(err error) *APIError {
 	details := make(map[string]string)
@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"paas-core/apps/api/internal/config"
 	apiErrors "paas-core/apps/api/internal/errors"
 	"paas-core/apps/api/internal/model"
 
@@ -15,12 +16,13 @@ import (
 
 // GateService checks subscription quotas and feature flags.
 type GateService struct {
-	db *gorm.DB
+	db                   *gorm.DB
+	countServiceAccounts bool
 }
 
 // NewGateService creates a new feature gate service.
-func NewGateService(db *gorm.DB) *GateService {
-	return &GateService{db: db}
+func NewGateService(db *gorm.DB, cfg config.BillingConfig) *GateService {
+	return &GateService{db: db, countServiceAccounts: cfg.CountServiceAccounts}
 }
 
 // PlanLimits holds resolved limits for an org.
@@ -93,7 +95,11 @@ func (g *GateService) CheckQuota(orgID uuid.UUID, resource string) error {
 	case "members":
 		max = limits.MaxMembers
 		var count int64
-		if err := g.db.Model(&model.Membership{}).Where("org_id = ?", orgID).Count(&count).Error; err != nil {
+		q := g.db.Model(&model.Membership{}).Where("memberships.org_id = ?", orgID)
+		if !g.countServiceAccounts {
+			q = q.Scopes(model.HumanMembers)
+		}
+		if err := q.Count(&count).Error; err != nil {
 			return apiErrors.InternalServerError(err)
 		}
 		current = int(count)
@@ -0,0 +1,153 @@
+package idp
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Scopes a client can request.
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// SupportedScopes lists every scope the provider understands.
+var SupportedScopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail}
+
+// GrantTypeAuthorizationCode is the only grant accepted by the token endpoint.
+const GrantTypeAuthorizationCode = "authorization_code"
+
+// --- Client management ---
+
+// CreateClientRequest is the DTO for registering an OIDC client.
+type CreateClientRequest struct {
+	Name         string   `json:"name" binding:"required,min=1,max=100"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required,min=1,dive,url"`
+	LogoURL      string   `json:"logo_url" binding:"omitempty,url"`
+	Public       bool     `json:"public"` // SPAs and mobile apps that cannot keep a secret
+}
+
+// UpdateClientRequest is the DTO for updating an OIDC client. Omitted fields
+// are left unchanged.
+type UpdateClientRequest struct {
+	Name         *string  `json:"name" binding:"omitempty,min=1,max=100"`
+	RedirectURIs []string `json:"redirect_uris" binding:"omitempty,min=1,dive,url"`
+	LogoURL      *string  `json:"logo_url" binding:"omitempty,url"`
+}
+
+// ClientResponse is the public DTO for an OIDC client.
+type ClientResponse struct {
+	ID           uuid.UUID  `json:"id"`
+	Name         string     `json:"name"`
+	ClientID     string     `json:"client_id"`
+	RedirectURIs []string   `json:"redirect_uris"`
+	LogoURL      string     `json:"logo_url,omitempty"`
+	Public       bool       `json:"public"`
+	CreatedByID  *uuid.UUID `json:"created_by_id,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// ClientCredentialsResponse includes the client secret, which is shown only
+// once. Public clients have no secret.
+type ClientCredentialsResponse struct {
+	ClientResponse
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+// --- Authorization flow ---
+
+// AuthorizeParams are the authorization request parameters. The frontend
+// consent page passes them through unchanged.
+type AuthorizeParams struct {
+	ResponseType        string `json:"response_type" form:"response_type"`
+	ClientID            string `json:"client_id" form:"client_id"`
+	RedirectURI         string `json:"redirect_uri" form:"redirect_uri"`
+	Scope               string `json:"scope" form:"scope"`
+	State               string `json:"state" form:"state"`
+	Nonce               string `json:"nonce" form:"nonce"`
+	CodeChallenge       string `json:"code_challenge" form:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method" form:"code_challenge_method"`
+}
+
+// ConsentRequest approves or denies an authorization request.
+type ConsentRequest struct {
+	AuthorizeParams
+	Approve bool `json:"approve"`
+}
+
+// ConsentInfoResponse describes an authorization request for the consent
+// screen.
+type ConsentInfoResponse struct {
+	ClientName string   `json:"client_name"`
+	LogoURL    string   `json:"logo_url,omitempty"`
+	OrgName    string   `json:"org_name"`
+	Scopes     []string `json:"scopes"`
+	Granted    bool     `json:"granted"` // the user already consented to these scopes
+}
+
+// ConsentResponse tells the frontend where to send the browser next.
+type ConsentResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// TokenRequest is the authorization code grant. Confidential clients may
+// authenticate with HTTP Basic auth instead of the body.
+type TokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	CodeVerifier string `form:"code_verifier"`
+}
+
+// TokenResponse is returned by the token endpoint.
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	IDToken     string `json:"id_token"`
+	Scope       string `json:"scope"`
+}
+
+// StandardClaims are the user claims released for the granted scopes.
+type StandardClaims struct {
+	Name          string `json:"name,omitempty"`
+	Picture       string `json:"picture,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+}
+
+// UserInfo is the userinfo endpoint response.
+type UserInfo struct {
+	Subject string `json:"sub"`
+	StandardClaims
+}
+
+// IDTokenClaims are the claims of an ID token.
+type IDTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce    string `json:"nonce,omitempty"`
+	AuthTime int64  `json:"auth_time"`
+	StandardClaims
+}
+
+// Discovery is the OpenID Provider metadata document.
+type Discovery struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
@@ -0,0 +1,64 @@
+package idp
+
+import "net/http"
+
+// Error is an OAuth 2.0 error response (RFC 6749 section 5.2). Protocol
+// endpoints return these instead of the API error envelope so that standard
+// client libraries understand them.
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+
+	status int
+	// redirectable errors are reported to the client's redirect URI; the
+	// others mean the redirect URI itself cannot be trusted.
+	redirectable bool
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func errInvalidRequest(desc string) *Error {
+	return &Error{Code: "invalid_request", Description: desc, status: http.StatusBadRequest, redirectable: true}
+}
+
+func errInvalidScope(desc string) *Error {
+	return &Error{Code: "invalid_scope", Description: desc, status: http.StatusBadRequest, redirectable: true}
+}
+
+func errUnsupportedResponseType() *Error {
+	return &Error{Code: "unsupported_response_type", Description: "Only the code response type is supported", status: http.StatusBadRequest, redirectable: true}
+}
+
+func errAccessDenied() *Error {
+	return &Error{Code: "access_denied", Description: "The user denied the request", status: http.StatusForbidden, redirectable: true}
+}
+
+func errInvalidClient(desc string) *Error {
+	return &Error{Code: "invalid_client", Description: desc, status: http.StatusUnauthorized}
+}
+
+func errUnknownClient() *Error {
+	return &Error{Code: "invalid_request", Description: "Unknown client_id", status: http.StatusBadRequest}
+}
+
+func errInvalidRedirectURI() *Error {
+	return &Error{Code: "invalid_request", Description: "redirect_uri is not registered for this client", status: http.StatusBadRequest}
+}
+
+func errInvalidGrant(desc string) *Error {
+	return &Error{Code: "invalid_grant", Description: desc, status: http.StatusBadRequest}
+}
+
+func errUnsupportedGrantType() *Error {
+	return &Error{Code: "unsupported_grant_type", Description: "Only the authorization_code grant is supported", status: http.StatusBadRequest}
+}
+
+func errInvalidToken() *Error {
+	return &Error{Code: "invalid_token", Description: "The access token is invalid or expired", status: http.StatusUnauthorized}
+}
+
+func errServer() *Error {
+	return &Error{Code: "server_error", Description: "The request could not be completed", status: http.StatusInternalServerError}
+}
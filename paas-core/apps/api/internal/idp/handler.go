@@ -0,0 +1,367 @@
+package idp
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"paas-core/apps/api/internal/auth"
+	apiErrors "paas-core/apps/api/internal/errors"
+)
+
+// Handler serves the OpenID Connect protocol endpoints, the consent API and
+// client management.
+type Handler struct {
+	service  *Service
+	provider *Provider
+}
+
+// NewHandler creates a new OIDC provider handler.
+func NewHandler(service *Service, provider *Provider) *Handler {
+	return &Handler{service: service, provider: provider}
+}
+
+// --- Protocol endpoints ---
+
+// Discovery godoc
+// @Summary OpenID Provider metadata
+// @Tags oidc
+// @Produce json
+// @Success 200 {object} Discovery
+// @Router /.well-known/openid-configuration [get]
+func (h *Handler) Discovery(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.JSON(http.StatusOK, h.provider.Discovery())
+}
+
+// Authorize godoc
+// @Summary Start an authorization code flow
+// @Description Validates the request and redirects to the consent page. Only response_type=code with PKCE (S256) is supported.
+// @Tags oidc
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string true "Registered redirect URI"
+// @Param response_type query string true "Must be code"
+// @Param scope query string true "Must include openid"
+// @Param state query string false "Opaque client state"
+// @Param nonce query string false "Echoed in the ID token"
+// @Param code_challenge query string true "PKCE challenge"
+// @Param code_challenge_method query string true "Must be S256"
+// @Success 302 "Redirect to the consent page or back to the client with an error"
+// @Failure 400 {object} Error "Unknown client or redirect URI"
+// @Router /oauth2/authorize [get]
+func (h *Handler) Authorize(c *gin.Context) {
+	var params AuthorizeParams
+	_ = c.ShouldBindQuery(&params)
+
+	location, err := h.provider.Authorize(c.Request.Context(), params)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, location)
+}
+
+// Token godoc
+// @Summary Exchange an authorization code for tokens
+// @Description Authorization code grant with PKCE. Confidential clients send client_id and client_secret with HTTP Basic auth or in the body.
+// @Tags oidc
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} Error
+// @Failure 401 {object} Error "Client authentication failed"
+// @Router /oauth2/token [post]
+func (h *Handler) Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var req TokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		writeError(c, errInvalidRequest("Malformed token request"))
+		return
+	}
+
+	usingBasic := false
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		// Basic credentials are form-encoded (RFC 6749 section 2.3.1)
+		id, idErr := url.QueryUnescape(id)
+		secret, secretErr := url.QueryUnescape(secret)
+		if idErr != nil || secretErr != nil {
+			writeError(c, errInvalidClient("Malformed client credentials"))
+			return
+		}
+		req.ClientID, req.ClientSecret = id, secret
+		usingBasic = true
+	}
+
+	resp, err := h.provider.Token(c.Request.Context(), req)
+	if err != nil {
+		var oauthErr *Error
+		if usingBasic && errors.As(err, &oauthErr) && oauthErr.Code == "invalid_client" {
+			c.Header("WWW-Authenticate", `Basic realm="oauth2"`)
+		}
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// UserInfo godoc
+// @Summary Get claims about the signed-in user
+// @Description Requires an access token issued by the token endpoint.
+// @Tags oidc
+// @Produce json
+// @Param Authorization header string true "Bearer access token"
+// @Success 200 {object} UserInfo
+// @Failure 401 {object} Error "Invalid or expired access token"
+// @Router /oauth2/userinfo [get]
+func (h *Handler) UserInfo(c *gin.Context) {
+	token, _ := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+
+	info, err := h.provider.UserInfo(c.Request.Context(), strings.TrimSpace(token))
+	if err != nil {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		writeError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, info)
+}
+
+// --- Consent API ---
+
+// ConsentInfo godoc
+// @Summary Describe an authorization request
+// @Description Called by the consent page with the query parameters it received from /oauth2/authorize.
+// @Tags oidc
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} errors.Response{data=ConsentInfoResponse}
+// @Failure 400 {object} errors.Response "Invalid authorization request"
+// @Router /api/v1/oauth2/consent [get]
+func (h *Handler) ConsentInfo(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var params AuthorizeParams
+	_ = c.ShouldBindQuery(&params)
+
+	info, err := h.provider.ConsentInfo(c.Request.Context(), claims.UserID, params)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(info))
+}
+
+// Consent godoc
+// @Summary Approve or deny an authorization request
+// @Description Returns the URL to send the browser back to the client with, carrying an authorization code or an access_denied error.
+// @Tags oidc
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body ConsentRequest true "Authorization request and decision"
+// @Success 200 {object} errors.Response{data=ConsentResponse}
+// @Failure 400 {object} errors.Response "Invalid authorization request"
+// @Router /api/v1/oauth2/consent [post]
+func (h *Handler) Consent(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var req ConsentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	resp, err := h.provider.Consent(c.Request.Context(), claims, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(resp))
+}
+
+// --- Client management ---
+
+// ListClients godoc
+// @Summary List OIDC clients
+// @Tags oidc
+// @Security BearerAuth
+// @Param orgId path string true "Organization ID"
+// @Success 200 {object} errors.Response{data=[]ClientResponse}
+// @Router /api/v1/orgs/{orgId}/oidc-clients [get]
+func (h *Handler) ListClients(c *gin.Context) {
+	orgID := c.MustGet("org_id").(uuid.UUID)
+
+	clients, err := h.service.List(c.Request.Context(), orgID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(clients))
+}
+
+// CreateClient godoc
+// @Summary Register an OIDC client
+// @Description Registers an app that signs users in with the platform. The client secret of a confidential client is only returned once.
+// @Tags oidc
+// @Security BearerAuth
+// @Param orgId path string true "Organization ID"
+// @Param request body CreateClientRequest true "Client"
+// @Success 201 {object} errors.Response{data=ClientCredentialsResponse}
+// @Router /api/v1/orgs/{orgId}/oidc-clients [post]
+func (h *Handler) CreateClient(c *gin.Context) {
+	orgID := c.MustGet("org_id").(uuid.UUID)
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var req CreateClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	client, err := h.service.Create(c.Request.Context(), orgID, claims.UserID, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, apiErrors.Success(client))
+}
+
+// GetClient godoc
+// @Summary Get an OIDC client
+// @Tags oidc
+// @Security BearerAuth
+// @Param orgId path string true "Organization ID"
+// @Param clientId path string true "Client ID"
+// @Success 200 {object} errors.Response{data=ClientResponse}
+// @Failure 404 {object} errors.Response "OIDC client not found"
+// @Router /api/v1/orgs/{orgId}/oidc-clients/{clientId} [get]
+func (h *Handler) GetClient(c *gin.Context) {
+	orgID := c.MustGet("org_id").(uuid.UUID)
+	id, ok := clientID(c)
+	if !ok {
+		return
+	}
+
+	client, err := h.service.Get(c.Request.Context(), orgID, id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(client))
+}
+
+// UpdateClient godoc
+// @Summary Update an OIDC client
+// @Tags oidc
+// @Security BearerAuth
+// @Param orgId path string true "Organization ID"
+// @Param clientId path string true "Client ID"
+// @Param request body UpdateClientRequest true "Changes"
+// @Success 200 {object} errors.Response{data=ClientResponse}
+// @Router /api/v1/orgs/{orgId}/oidc-clients/{clientId} [put]
+func (h *Handler) UpdateClient(c *gin.Context) {
+	orgID := c.MustGet("org_id").(uuid.UUID)
+	claims := c.MustGet("claims").(*auth.Claims)
+	id, ok := clientID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	client, err := h.service.Update(c.Request.Context(), orgID, claims.UserID, id, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(client))
+}
+
+// RotateClientSecret godoc
+// @Summary Rotate an OIDC client's secret
+// @Tags oidc
+// @Security BearerAuth
+// @Param orgId path string true "Organization ID"
+// @Param clientId path string true "Client ID"
+// @Success 200 {object} errors.Response{data=ClientCredentialsResponse}
+// @Failure 400 {object} errors.Response "Public clients have no secret"
+// @Router /api/v1/orgs/{orgId}/oidc-clients/{clientId}/secret [post]
+func (h *Handler) RotateClientSecret(c *gin.Context) {
+	orgID := c.MustGet("org_id").(uuid.UUID)
+	claims := c.MustGet("claims").(*auth.Claims)
+	id, ok := clientID(c)
+	if !ok {
+		return
+	}
+
+	client, err := h.service.RotateSecret(c.Request.Context(), orgID, claims.UserID, id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(client))
+}
+
+// DeleteClient godoc
+// @Summary Delete an OIDC client
+// @Description Also removes user consents and revokes the client's access tokens.
+// @Tags oidc
+// @Security BearerAuth
+// @Param orgId path string true "Organization ID"
+// @Param clientId path string true "Client ID"
+// @Success 200 {object} errors.Response "OIDC client deleted"
+// @Router /api/v1/orgs/{orgId}/oidc-clients/{clientId} [delete]
+func (h *Handler) DeleteClient(c *gin.Context) {
+	orgID := c.MustGet("org_id").(uuid.UUID)
+	claims := c.MustGet("claims").(*auth.Claims)
+	id, ok := clientID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), orgID, claims.UserID, id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(gin.H{"message": "OIDC client deleted"}))
+}
+
+func clientID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("clientId"))
+	if err != nil {
+		_ = c.Error(apiErrors.BadRequest("Invalid client ID"))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// writeError writes a protocol error as an RFC 6749 error response.
+func writeError(c *gin.Context, err error) {
+	var oauthErr *Error
+	if !errors.As(err, &oauthErr) {
+		_ = c.Error(err)
+		return
+	}
+	c.AbortWithStatusJSON(oauthErr.status, oauthErr)
+}
@@ -0,0 +1,504 @@
+package idp
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"paas-core/apps/api/internal/auth"
+	apiErrors "paas-core/apps/api/internal/errors"
+	"paas-core/apps/api/internal/model"
+)
+
+const (
+	codeTTL        = 5 * time.Minute
+	accessTokenTTL = time.Hour
+	idTokenTTL     = time.Hour
+
+	accessTokenPrefix = "oidca_"
+)
+
+// Provider implements the authorization code flow with PKCE on top of the
+// platform's user store and signing keys.
+type Provider struct {
+	db         *gorm.DB
+	keyring    *auth.Keyring
+	issuer     string
+	consentURL string
+}
+
+// NewProvider creates an OpenID Connect provider. The keyring must use an
+// asymmetric algorithm so clients can verify ID tokens against the JWKS.
+func NewProvider(db *gorm.DB, keyring *auth.Keyring, issuer, consentURL string) *Provider {
+	return &Provider{
+		db:         db,
+		keyring:    keyring,
+		issuer:     strings.TrimRight(issuer, "/"),
+		consentURL: consentURL,
+	}
+}
+
+// Discovery returns the provider metadata document.
+func (p *Provider) Discovery() *Discovery {
+	return &Discovery{
+		Issuer:                            p.issuer,
+		AuthorizationEndpoint:             p.issuer + "/oauth2/authorize",
+		TokenEndpoint:                     p.issuer + "/oauth2/token",
+		UserinfoEndpoint:                  p.issuer + "/oauth2/userinfo",
+		JWKSURI:                           p.issuer + "/.well-known/jwks.json",
+		ScopesSupported:                   SupportedScopes,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{GrantTypeAuthorizationCode},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{p.keyring.Algorithm()},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported: []string{
+			"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce",
+			"name", "picture", "email", "email_verified",
+		},
+	}
+}
+
+// Authorize validates an authorization request and returns the URL to send
+// the browser to: the consent page, or the client's redirect URI with an
+// error. Errors that make the redirect URI untrustworthy are returned instead.
+func (p *Provider) Authorize(ctx context.Context, params AuthorizeParams) (string, error) {
+	if _, _, err := p.validate(ctx, params); err != nil {
+		var oauthErr *Error
+		if errors.As(err, &oauthErr) && oauthErr.redirectable {
+			return errorRedirect(params, oauthErr), nil
+		}
+		return "", err
+	}
+	return p.consentURL + "?" + params.values().Encode(), nil
+}
+
+// ConsentInfo describes a pending authorization request for the consent
+// screen.
+func (p *Provider) ConsentInfo(ctx context.Context, userID uuid.UUID, params AuthorizeParams) (*ConsentInfoResponse, error) {
+	client, scopes, err := p.validate(ctx, params)
+	if err != nil {
+		return nil, toAPIError(err)
+	}
+
+	var org model.Org
+	if err := p.db.WithContext(ctx).Select("name").Where("id = ?", client.OrgID).First(&org).Error; err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	granted, err := p.grantedScopes(ctx, userID, client.ID)
+	if err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	return &ConsentInfoResponse{
+		ClientName: client.Name,
+		LogoURL:    client.LogoURL,
+		OrgName:    org.Name,
+		Scopes:     scopes,
+		Granted:    containsAll(granted, scopes),
+	}, nil
+}
+
+// Consent records the user's decision and returns the redirect back to the
+// client: an authorization code when approved, access_denied otherwise.
+func (p *Provider) Consent(ctx context.Context, claims *auth.Claims, req ConsentRequest) (*ConsentResponse, error) {
+	client, scopes, err := p.validate(ctx, req.AuthorizeParams)
+	if err != nil {
+		return nil, toAPIError(err)
+	}
+
+	if !req.Approve {
+		return &ConsentResponse{RedirectTo: errorRedirect(req.AuthorizeParams, errAccessDenied())}, nil
+	}
+
+	rawCode, err := randomString("", 32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	authTime := time.Now()
+	if claims.IssuedAt != nil {
+		authTime = claims.IssuedAt.Time
+	}
+
+	err = p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		granted, err := p.grantedScopes(ctx, claims.UserID, client.ID)
+		if err != nil {
+			return err
+		}
+		consent := &model.OIDCConsent{
+			UserID:   claims.UserID,
+			ClientID: client.ID,
+			Scope:    strings.Join(union(granted, scopes), " "),
+		}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "client_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"scope", "updated_at"}),
+		}).Create(consent).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&model.OIDCAuthorizationCode{
+			CodeHash:      hashSecret(rawCode),
+			ClientID:      client.ID,
+			UserID:        claims.UserID,
+			RedirectURI:   req.RedirectURI,
+			Scope:         strings.Join(scopes, " "),
+			Nonce:         req.Nonce,
+			CodeChallenge: req.CodeChallenge,
+			AuthTime:      authTime,
+			ExpiresAt:     time.Now().Add(codeTTL),
+		}).Error
+	})
+	if err != nil {
+		return nil, apiErrors.InternalServerError(fmt.Errorf("failed to issue authorization code: %w", err))
+	}
+
+	query := url.Values{"code": {rawCode}}
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+	return &ConsentResponse{RedirectTo: appendQuery(req.RedirectURI, query)}, nil
+}
+
+// Token redeems an authorization code for an access token and ID token.
+// Confidential clients must present their secret; public clients only the
+// PKCE verifier.
+func (p *Provider) Token(ctx context.Context, req TokenRequest) (*TokenResponse, error) {
+	if req.GrantType != GrantTypeAuthorizationCode {
+		return nil, errUnsupportedGrantType()
+	}
+	if req.Code == "" || req.CodeVerifier == "" {
+		return nil, errInvalidRequest("code and code_verifier are required")
+	}
+
+	client, err := p.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	rawToken, err := randomString(accessTokenPrefix, 32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, errServer()
+	}
+
+	var code model.OIDCAuthorizationCode
+	var grantErr *Error
+	err = p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("code_hash = ?", hashSecret(req.Code)).
+			First(&code).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				grantErr = errInvalidGrant("Invalid authorization code")
+				return nil
+			}
+			return err
+		}
+
+		if code.UsedAt != nil {
+			// A replayed code may have been stolen: revoke everything issued
+			// from it (RFC 6749 section 4.1.2).
+			slog.Warn("OIDC authorization code replayed", "client_id", client.ClientID, "user_id", code.UserID)
+			grantErr = errInvalidGrant("Authorization code has already been used")
+			return tx.Model(&model.OIDCAccessToken{}).
+				Where("code_id = ? AND revoked_at IS NULL", code.ID).
+				Update("revoked_at", now).Error
+		}
+		switch {
+		case now.After(code.ExpiresAt):
+			grantErr = errInvalidGrant("Authorization code has expired")
+		case code.ClientID != client.ID:
+			grantErr = errInvalidGrant("Authorization code was issued to another client")
+		case code.RedirectURI != req.RedirectURI:
+			grantErr = errInvalidGrant("redirect_uri does not match the authorization request")
+		case !verifyPKCE(req.CodeVerifier, code.CodeChallenge):
+			grantErr = errInvalidGrant("code_verifier does not match the code challenge")
+		}
+		if grantErr != nil {
+			return nil
+		}
+
+		if err := tx.Model(&code).Update("used_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.OIDCAccessToken{
+			TokenHash: hashSecret(rawToken),
+			ClientID:  client.ID,
+			UserID:    code.UserID,
+			CodeID:    code.ID,
+			Scope:     code.Scope,
+			ExpiresAt: now.Add(accessTokenTTL),
+		}).Error
+	})
+	if err != nil {
+		slog.Error("Failed to redeem OIDC authorization code", "error", err)
+		return nil, errServer()
+	}
+	if grantErr != nil {
+		return nil, grantErr
+	}
+
+	var usr model.User
+	if err := p.db.WithContext(ctx).Where("id = ?", code.UserID).First(&usr).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errInvalidGrant("The user no longer exists")
+		}
+		return nil, errServer()
+	}
+
+	scopes := strings.Fields(code.Scope)
+	idToken, err := p.keyring.Sign(&IDTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    p.issuer,
+			Subject:   usr.ID.String(),
+			Audience:  jwt.ClaimStrings{client.ClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(idTokenTTL)),
+		},
+		Nonce:          code.Nonce,
+		AuthTime:       code.AuthTime.Unix(),
+		StandardClaims: standardClaims(&usr, scopes),
+	})
+	if err != nil {
+		slog.Error("Failed to sign ID token", "error", err)
+		return nil, errServer()
+	}
+
+	return &TokenResponse{
+		AccessToken: rawToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(accessTokenTTL.Seconds()),
+		IDToken:     idToken,
+		Scope:       code.Scope,
+	}, nil
+}
+
+// UserInfo returns the claims granted to an access token.
+func (p *Provider) UserInfo(ctx context.Context, rawToken string) (*UserInfo, error) {
+	if rawToken == "" {
+		return nil, errInvalidToken()
+	}
+
+	var token model.OIDCAccessToken
+	if err := p.db.WithContext(ctx).
+		Where("token_hash = ? AND expires_at > ? AND revoked_at IS NULL", hashSecret(rawToken), time.Now()).
+		First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errInvalidToken()
+		}
+		return nil, errServer()
+	}
+
+	var usr model.User
+	if err := p.db.WithContext(ctx).Where("id = ?", token.UserID).First(&usr).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errInvalidToken()
+		}
+		return nil, errServer()
+	}
+
+	return &UserInfo{
+		Subject:        usr.ID.String(),
+		StandardClaims: standardClaims(&usr, strings.Fields(token.Scope)),
+	}, nil
+}
+
+// validate checks an authorization request against the registered client and
+// returns the client with the requested scopes.
+func (p *Provider) validate(ctx context.Context, params AuthorizeParams) (*model.OIDCClient, []string, error) {
+	if params.ClientID == "" {
+		return nil, nil, errUnknownClient()
+	}
+
+	var client model.OIDCClient
+	if err := p.db.WithContext(ctx).Where("client_id = ?", params.ClientID).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errUnknownClient()
+		}
+		return nil, nil, apiErrors.InternalServerError(err)
+	}
+	if !contains(strings.Fields(client.RedirectURIs), params.RedirectURI) {
+		return nil, nil, errInvalidRedirectURI()
+	}
+
+	// From here on errors can be reported to the redirect URI
+	if params.ResponseType != "code" {
+		return nil, nil, errUnsupportedResponseType()
+	}
+
+	var scopes []string
+	for _, scope := range strings.Fields(params.Scope) {
+		if !contains(SupportedScopes, scope) {
+			return nil, nil, errInvalidScope(fmt.Sprintf("Unsupported scope %s", scope))
+		}
+		if !contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if !contains(scopes, ScopeOpenID) {
+		return nil, nil, errInvalidScope("The openid scope is required")
+	}
+
+	if params.CodeChallenge == "" {
+		return nil, nil, errInvalidRequest("code_challenge is required")
+	}
+	if params.CodeChallengeMethod != "S256" {
+		return nil, nil, errInvalidRequest("code_challenge_method must be S256")
+	}
+	if len(params.CodeChallenge) != base64.RawURLEncoding.EncodedLen(sha256.Size) {
+		return nil, nil, errInvalidRequest("Invalid code_challenge")
+	}
+
+	return &client, scopes, nil
+}
+
+// authenticateClient checks the client credentials. Public clients have no
+// secret and are bound to the code by PKCE alone.
+func (p *Provider) authenticateClient(ctx context.Context, clientID, secret string) (*model.OIDCClient, error) {
+	if clientID == "" {
+		return nil, errInvalidClient("Client authentication failed")
+	}
+
+	var client model.OIDCClient
+	if err := p.db.WithContext(ctx).Where("client_id = ?", clientID).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errInvalidClient("Client authentication failed")
+		}
+		return nil, errServer()
+	}
+
+	if client.SecretHash != "" &&
+		subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(client.SecretHash)) != 1 {
+		return nil, errInvalidClient("Client authentication failed")
+	}
+	return &client, nil
+}
+
+func (p *Provider) grantedScopes(ctx context.Context, userID, clientID uuid.UUID) ([]string, error) {
+	var consent model.OIDCConsent
+	err := p.db.WithContext(ctx).Where("user_id = ? AND client_id = ?", userID, clientID).First(&consent).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(consent.Scope), nil
+}
+
+// standardClaims releases the user's claims for the granted scopes.
+func standardClaims(usr *model.User, scopes []string) StandardClaims {
+	var c StandardClaims
+	if contains(scopes, ScopeProfile) {
+		c.Name = usr.Name
+		c.Picture = usr.AvatarURL
+	}
+	if contains(scopes, ScopeEmail) {
+		verified := usr.EmailVerified
+		c.Email = usr.Email
+		c.EmailVerified = &verified
+	}
+	return c
+}
+
+func verifyPKCE(verifier, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	h := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(h[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// values encodes the parameters for the consent page, omitting empty ones.
+func (a AuthorizeParams) values() url.Values {
+	v := url.Values{}
+	for key, value := range map[string]string{
+		"response_type":         a.ResponseType,
+		"client_id":             a.ClientID,
+		"redirect_uri":          a.RedirectURI,
+		"scope":                 a.Scope,
+		"state":                 a.State,
+		"nonce":                 a.Nonce,
+		"code_challenge":        a.CodeChallenge,
+		"code_challenge_method": a.CodeChallengeMethod,
+	} {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+	return v
+}
+
+// errorRedirect reports an error to the client's redirect URI.
+func errorRedirect(params AuthorizeParams, e *Error) string {
+	query := url.Values{"error": {e.Code}}
+	if e.Description != "" {
+		query.Set("error_description", e.Description)
+	}
+	if params.State != "" {
+		query.Set("state", params.State)
+	}
+	return appendQuery(params.RedirectURI, query)
+}
+
+func appendQuery(rawURL string, query url.Values) string {
+	sep := "?"
+	if strings.Contains(rawURL, "?") {
+		sep = "&"
+	}
+	return rawURL + sep + query.Encode()
+}
+
+// toAPIError converts protocol errors for the consent API, which is called
+// by our own frontend and uses the standard error envelope.
+func toAPIError(err error) error {
+	var oauthErr *Error
+	if errors.As(err, &oauthErr) {
+		return apiErrors.BadRequest(oauthErr.Description)
+	}
+	return err
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAll(list, values []string) bool {
+	for _, v := range values {
+		if !contains(list, v) {
+			return false
+		}
+	}
+	return true
+}
+
+func union(a, b []string) []string {
+	result := append([]string{}, a...)
+	for _, v := range b {
+		if !contains(result, v) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
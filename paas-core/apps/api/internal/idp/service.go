@@ -0,0 +1,256 @@
+package idp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"paas-core/apps/api/internal/audit"
+	apiErrors "paas-core/apps/api/internal/errors"
+	"paas-core/apps/api/internal/model"
+)
+
+// Audit actions recorded for OIDC clients.
+const (
+	ActionClientCreated       = "oidc_client.created"
+	ActionClientUpdated       = "oidc_client.updated"
+	ActionClientSecretRotated = "oidc_client.secret_rotated"
+	ActionClientDeleted       = "oidc_client.deleted"
+)
+
+const (
+	clientIDPrefix     = "oidc_"
+	clientSecretPrefix = "oidcs_"
+)
+
+// Service manages the OIDC clients registered by each org.
+type Service struct {
+	db    *gorm.DB
+	audit *audit.Service
+}
+
+// NewService creates a new OIDC client service.
+func NewService(db *gorm.DB, auditService *audit.Service) *Service {
+	return &Service{db: db, audit: auditService}
+}
+
+// Create registers a client for the org. Confidential clients get a secret,
+// which is only returned once.
+func (s *Service) Create(ctx context.Context, orgID, actorID uuid.UUID, req CreateClientRequest) (*ClientCredentialsResponse, error) {
+	redirectURIs, err := normalizeRedirectURIs(req.RedirectURIs)
+	if err != nil {
+		return nil, err
+	}
+
+	clientID, err := randomString(clientIDPrefix, 12, hex.EncodeToString)
+	if err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	client := &model.OIDCClient{
+		OrgID:        orgID,
+		Name:         req.Name,
+		ClientID:     clientID,
+		RedirectURIs: strings.Join(redirectURIs, " "),
+		LogoURL:      req.LogoURL,
+		CreatedByID:  &actorID,
+	}
+
+	var secret string
+	if !req.Public {
+		if secret, err = randomString(clientSecretPrefix, 32, base64.RawURLEncoding.EncodeToString); err != nil {
+			return nil, apiErrors.InternalServerError(err)
+		}
+		client.SecretHash = hashSecret(secret)
+	}
+
+	if err := s.db.WithContext(ctx).Create(client).Error; err != nil {
+		return nil, apiErrors.InternalServerError(fmt.Errorf("failed to create oidc client: %w", err))
+	}
+
+	s.audit.Record(ctx, orgID, actorID, ActionClientCreated, resourceName(client.ID), map[string]any{
+		"name":          client.Name,
+		"redirect_uris": redirectURIs,
+		"public":        req.Public,
+	})
+
+	return &ClientCredentialsResponse{ClientResponse: toClientResponse(client), ClientSecret: secret}, nil
+}
+
+// List returns the org's clients.
+func (s *Service) List(ctx context.Context, orgID uuid.UUID) ([]ClientResponse, error) {
+	var clients []model.OIDCClient
+	if err := s.db.WithContext(ctx).Where("org_id = ?", orgID).Order("created_at ASC").Find(&clients).Error; err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	result := make([]ClientResponse, len(clients))
+	for i := range clients {
+		result[i] = toClientResponse(&clients[i])
+	}
+	return result, nil
+}
+
+// Get returns a single client.
+func (s *Service) Get(ctx context.Context, orgID, id uuid.UUID) (*ClientResponse, error) {
+	client, err := s.find(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	resp := toClientResponse(client)
+	return &resp, nil
+}
+
+// Update changes a client's name, redirect URIs or logo.
+func (s *Service) Update(ctx context.Context, orgID, actorID, id uuid.UUID, req UpdateClientRequest) (*ClientResponse, error) {
+	client, err := s.find(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		client.Name = *req.Name
+	}
+	if req.RedirectURIs != nil {
+		redirectURIs, err := normalizeRedirectURIs(req.RedirectURIs)
+		if err != nil {
+			return nil, err
+		}
+		client.RedirectURIs = strings.Join(redirectURIs, " ")
+	}
+	if req.LogoURL != nil {
+		client.LogoURL = *req.LogoURL
+	}
+
+	if err := s.db.WithContext(ctx).Save(client).Error; err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	s.audit.Record(ctx, orgID, actorID, ActionClientUpdated, resourceName(client.ID), req)
+
+	resp := toClientResponse(client)
+	return &resp, nil
+}
+
+// RotateSecret replaces a confidential client's secret. The old secret stops
+// working immediately.
+func (s *Service) RotateSecret(ctx context.Context, orgID, actorID, id uuid.UUID) (*ClientCredentialsResponse, error) {
+	client, err := s.find(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if client.SecretHash == "" {
+		return nil, apiErrors.BadRequest("Public clients have no secret")
+	}
+
+	secret, err := randomString(clientSecretPrefix, 32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	client.SecretHash = hashSecret(secret)
+	if err := s.db.WithContext(ctx).Model(client).Update("secret_hash", client.SecretHash).Error; err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	s.audit.Record(ctx, orgID, actorID, ActionClientSecretRotated, resourceName(client.ID), nil)
+
+	return &ClientCredentialsResponse{ClientResponse: toClientResponse(client), ClientSecret: secret}, nil
+}
+
+// Delete removes a client along with its consents, and revokes the access
+// tokens it still holds.
+func (s *Service) Delete(ctx context.Context, orgID, actorID, id uuid.UUID) error {
+	client, err := s.find(ctx, orgID, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(client).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("client_id = ?", client.ID).Delete(&model.OIDCConsent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("client_id = ? AND used_at IS NULL", client.ID).Delete(&model.OIDCAuthorizationCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.OIDCAccessToken{}).
+			Where("client_id = ? AND revoked_at IS NULL", client.ID).
+			Update("revoked_at", time.Now()).Error
+	})
+	if err != nil {
+		return apiErrors.InternalServerError(fmt.Errorf("failed to delete oidc client: %w", err))
+	}
+
+	s.audit.Record(ctx, orgID, actorID, ActionClientDeleted, resourceName(client.ID), map[string]any{"name": client.Name})
+	return nil
+}
+
+func (s *Service) find(ctx context.Context, orgID, id uuid.UUID) (*model.OIDCClient, error) {
+	var client model.OIDCClient
+	if err := s.db.WithContext(ctx).Where("id = ? AND org_id = ?", id, orgID).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apiErrors.NotFound("OIDC client not found")
+		}
+		return nil, apiErrors.InternalServerError(err)
+	}
+	return &client, nil
+}
+
+// normalizeRedirectURIs rejects URIs that cannot be matched safely and drops
+// duplicates.
+func normalizeRedirectURIs(uris []string) ([]string, error) {
+	seen := make(map[string]bool, len(uris))
+	result := make([]string, 0, len(uris))
+	for _, uri := range uris {
+		if strings.ContainsAny(uri, " #") {
+			return nil, apiErrors.BadRequest(fmt.Sprintf("Invalid redirect URI %q: must not contain spaces or a fragment", uri))
+		}
+		if !seen[uri] {
+			seen[uri] = true
+			result = append(result, uri)
+		}
+	}
+	return result, nil
+}
+
+func toClientResponse(c *model.OIDCClient) ClientResponse {
+	return ClientResponse{
+		ID:           c.ID,
+		Name:         c.Name,
+		ClientID:     c.ClientID,
+		RedirectURIs: strings.Fields(c.RedirectURIs),
+		LogoURL:      c.LogoURL,
+		Public:       c.SecretHash == "",
+		CreatedByID:  c.CreatedByID,
+		CreatedAt:    c.CreatedAt,
+	}
+}
+
+func resourceName(id uuid.UUID) string {
+	return "oidc_client:" + id.String()
+}
+
+func randomString(prefix string, n int, encode func([]byte) string) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate credentials: %w", err)
+	}
+	return prefix + encode(buf), nil
+}
+
+func hashSecret(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
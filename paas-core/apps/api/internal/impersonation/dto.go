@@ -0,0 +1,26 @@
+package impersonation
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"paas-core/apps/api/internal/auth"
+)
+
+// StartRequest is the DTO for starting an impersonation session.
+type StartRequest struct {
+	Reason string `json:"reason" binding:"required,min=3,max=500"` // e.g. the support ticket being worked on
+}
+
+// StartResponse carries the impersonation token. It is only returned in the
+// body and never set as a cookie, so the admin's own session is untouched.
+type StartResponse struct {
+	AccessToken  string            `json:"access_token"`
+	TokenType    string            `json:"token_type"`
+	ExpiresIn    int64             `json:"expires_in"`
+	ExpiresAt    time.Time         `json:"expires_at"`
+	SessionID    string            `json:"session_id"` // token jti, referenced by the audit trail
+	User         auth.UserResponse `json:"user"`
+	Impersonator uuid.UUID         `json:"impersonator_id"`
+}
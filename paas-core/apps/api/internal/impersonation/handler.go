@@ -0,0 +1,75 @@
+package impersonation
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"paas-core/apps/api/internal/auth"
+	apiErrors "paas-core/apps/api/internal/errors"
+)
+
+// Handler handles impersonation HTTP routes.
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new impersonation handler.
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// Start godoc
+// @Summary Impersonate a user (super admin only)
+// @Description Issues a short-lived access token that acts as the user and carries an "act" claim naming the admin. Billing changes, org deletion and credential changes are blocked under impersonation, and the session is recorded in the audit log.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body StartRequest true "Reason for impersonating"
+// @Success 200 {object} errors.Response{data=StartResponse}
+// @Failure 403 {object} errors.Response "Target cannot be impersonated"
+// @Failure 404 {object} errors.Response "User not found"
+// @Router /api/v1/users/{id}/impersonate [post]
+func (h *Handler) Start(c *gin.Context) {
+	targetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		_ = c.Error(apiErrors.BadRequest("Invalid user ID"))
+		return
+	}
+
+	var req StartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	claims := c.MustGet("claims").(*auth.Claims)
+	resp, err := h.service.Start(c.Request.Context(), claims, targetID, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(resp))
+}
+
+// End godoc
+// @Summary End an impersonation session
+// @Description Revokes the impersonation token used for this request. The impersonated user stays signed in.
+// @Tags auth
+// @Security BearerAuth
+// @Success 200 {object} errors.Response "Impersonation ended"
+// @Failure 400 {object} errors.Response "Not an impersonation session"
+// @Router /api/v1/auth/impersonation/end [post]
+func (h *Handler) End(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+	if err := h.service.End(c.Request.Context(), claims); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(gin.H{"message": "Impersonation ended"}))
+}
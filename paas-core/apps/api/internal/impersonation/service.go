@@ -0,0 +1,117 @@
+package impersonation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"paas-core/apps/api/internal/audit"
+	"paas-core/apps/api/internal/auth"
+	apiErrors "paas-core/apps/api/internal/errors"
+	"paas-core/apps/api/internal/model"
+)
+
+// Audit actions recorded for impersonation sessions.
+const (
+	ActionStarted = "impersonation.started"
+	ActionEnded   = "impersonation.ended"
+	ActionRequest = "impersonation.request"
+)
+
+// tokenTTL bounds an impersonation session; there is no refresh token.
+const tokenTTL = 15 * time.Minute
+
+// Service starts and ends impersonation sessions for super admins.
+type Service struct {
+	db          *gorm.DB
+	authService auth.Service
+	audit       *audit.Service
+}
+
+// NewService creates a new impersonation service.
+func NewService(db *gorm.DB, authService auth.Service, auditService *audit.Service) *Service {
+	return &Service{db: db, authService: authService, audit: auditService}
+}
+
+// Start issues a token that lets the admin act as the target user.
+func (s *Service) Start(ctx context.Context, admin *auth.Claims, targetID uuid.UUID, req StartRequest) (*StartResponse, error) {
+	if admin.IsImpersonated() {
+		return nil, apiErrors.Forbidden("Impersonation sessions cannot be nested")
+	}
+	if admin.UserID == targetID {
+		return nil, apiErrors.BadRequest("You cannot impersonate yourself")
+	}
+
+	var target model.User
+	if err := s.db.WithContext(ctx).Preload("Roles").First(&target, "id = ?", targetID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apiErrors.NotFound("User not found")
+		}
+		return nil, apiErrors.InternalServerError(err)
+	}
+	if target.IsServiceAccount {
+		return nil, apiErrors.BadRequest("Service accounts cannot be impersonated")
+	}
+
+	roles := make([]string, len(target.Roles))
+	for i, r := range target.Roles {
+		if r.Name == model.RoleSuperAdmin {
+			return nil, apiErrors.Forbidden("Super admins cannot be impersonated")
+		}
+		roles[i] = r.Name
+	}
+
+	actor := &auth.Actor{Subject: admin.Subject, UserID: admin.UserID, Email: admin.Email}
+	token, claims, err := s.authService.GenerateImpersonationToken(ctx, target.ID, target.Email, target.Name, roles, actor, tokenTTL)
+	if err != nil {
+		return nil, apiErrors.InternalServerError(fmt.Errorf("failed to generate impersonation token: %w", err))
+	}
+
+	s.audit.RecordGlobal(ctx, admin.UserID, ActionStarted, resourceName(target.ID), map[string]any{
+		"session_id":   claims.ID,
+		"target_email": target.Email,
+		"reason":       req.Reason,
+		"expires_at":   claims.ExpiresAt.Time,
+	})
+
+	return &StartResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(claims.ExpiresAt.Sub(claims.IssuedAt.Time).Seconds()),
+		ExpiresAt:   claims.ExpiresAt.Time,
+		SessionID:   claims.ID,
+		User: auth.UserResponse{
+			ID:        target.ID,
+			Name:      target.Name,
+			Email:     target.Email,
+			AvatarURL: target.AvatarURL,
+			Roles:     roles,
+			CreatedAt: target.CreatedAt,
+		},
+		Impersonator: admin.UserID,
+	}, nil
+}
+
+// End revokes the impersonation token in use.
+func (s *Service) End(ctx context.Context, claims *auth.Claims) error {
+	if !claims.IsImpersonated() {
+		return apiErrors.BadRequest("Not an impersonation session")
+	}
+
+	if err := s.authService.RevokeAccessToken(ctx, claims); err != nil {
+		return apiErrors.InternalServerError(fmt.Errorf("failed to revoke impersonation token: %w", err))
+	}
+
+	s.audit.RecordGlobal(ctx, claims.Actor.UserID, ActionEnded, resourceName(claims.UserID), map[string]any{
+		"session_id": claims.ID,
+	})
+	return nil
+}
+
+func resourceName(userID uuid.UUID) string {
+	return "user:" + userID.String()
+}
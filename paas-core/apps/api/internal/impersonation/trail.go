@@ -0,0 +1,49 @@
+package impersonation
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"paas-core/apps/api/internal/audit"
+	"paas-core/apps/api/internal/auth"
+)
+
+// AuditTrail records every state-changing request made with an impersonation
+// token, attributed to the impersonating admin. Requests inside an org are
+// recorded in that org's audit log, so its admins can see them too.
+func AuditTrail(auditService *audit.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		claimsVal, exists := c.Get("claims")
+		if !exists {
+			return
+		}
+		claims := claimsVal.(*auth.Claims)
+		if !claims.IsImpersonated() {
+			return
+		}
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+
+		details := map[string]any{
+			"session_id": claims.ID,
+			"user_id":    claims.UserID,
+			"method":     c.Request.Method,
+			"path":       c.FullPath(),
+			"status":     c.Writer.Status(),
+		}
+
+		ctx := c.Request.Context()
+		if orgID, ok := c.Get("org_id"); ok {
+			auditService.Record(ctx, orgID.(uuid.UUID), claims.Actor.UserID, ActionRequest, resourceName(claims.UserID), details)
+			return
+		}
+		auditService.RecordGlobal(ctx, claims.Actor.UserID, ActionRequest, resourceName(claims.UserID), details)
+	}
+}
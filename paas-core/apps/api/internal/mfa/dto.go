@@ -0,0 +1,31 @@
+package mfa
+
+// StatusResponse describes the current user's MFA enrollment.
+type StatusResponse struct {
+	Enabled                bool `json:"enabled"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// EnrollResponse is returned when TOTP enrollment starts. The provisioning URI
+// is rendered as a QR code by the client.
+type EnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// RecoveryCodesResponse carries freshly generated recovery codes. They are
+// only ever shown once.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// CodeRequest is the DTO for endpoints that require a current TOTP or recovery code.
+type CodeRequest struct {
+	Code string `json:"code" binding:"required,max=32"`
+}
+
+// VerifyRequest is the DTO for completing a two-step login.
+type VerifyRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required,max=32"`
+}
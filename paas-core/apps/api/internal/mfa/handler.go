@@ -0,0 +1,209 @@
+package mfa
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"paas-core/apps/api/internal/auth"
+	apiErrors "paas-core/apps/api/internal/errors"
+)
+
+// Handler handles MFA enrollment and the second step of login.
+type Handler struct {
+	service     *Service
+	authService auth.Service
+	transport   *auth.SessionTransport
+}
+
+// NewHandler creates a new MFA handler.
+func NewHandler(service *Service, authService auth.Service, transport *auth.SessionTransport) *Handler {
+	return &Handler{service: service, authService: authService, transport: transport}
+}
+
+// Status godoc
+// @Summary Get MFA status
+// @Tags mfa
+// @Security BearerAuth
+// @Success 200 {object} errors.Response{data=StatusResponse}
+// @Router /api/v1/users/me/mfa [get]
+func (h *Handler) Status(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.service.Status(c.Request.Context(), claims.UserID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(status))
+}
+
+// Enroll godoc
+// @Summary Start TOTP enrollment
+// @Description Generates a new TOTP secret and otpauth:// provisioning URI to display as a QR code
+// @Tags mfa
+// @Security BearerAuth
+// @Success 200 {object} errors.Response{data=EnrollResponse}
+// @Failure 409 {object} errors.Response "MFA already enabled"
+// @Router /api/v1/users/me/mfa/totp [post]
+func (h *Handler) Enroll(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.Enroll(c.Request.Context(), claims.UserID, claims.Email)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(resp))
+}
+
+// ConfirmEnrollment godoc
+// @Summary Confirm TOTP enrollment
+// @Description Verifies the first code from the authenticator app, enables MFA and returns recovery codes
+// @Tags mfa
+// @Security BearerAuth
+// @Param request body CodeRequest true "TOTP code"
+// @Success 200 {object} errors.Response{data=RecoveryCodesResponse}
+// @Failure 400 {object} errors.Response "Invalid code"
+// @Router /api/v1/users/me/mfa/totp/confirm [post]
+func (h *Handler) ConfirmEnrollment(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		return
+	}
+
+	var req CodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	codes, err := h.service.ConfirmEnrollment(c.Request.Context(), claims.UserID, req.Code)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(RecoveryCodesResponse{RecoveryCodes: codes}))
+}
+
+// Disable godoc
+// @Summary Disable MFA
+// @Tags mfa
+// @Security BearerAuth
+// @Param request body CodeRequest true "TOTP or recovery code"
+// @Success 200 {object} errors.Response "MFA disabled"
+// @Failure 400 {object} errors.Response "Invalid code"
+// @Router /api/v1/users/me/mfa/disable [post]
+func (h *Handler) Disable(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		return
+	}
+
+	var req CodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	if err := h.service.Disable(c.Request.Context(), claims.UserID, req.Code); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(gin.H{"message": "MFA disabled"}))
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Invalidates all existing recovery codes and returns a new set
+// @Tags mfa
+// @Security BearerAuth
+// @Param request body CodeRequest true "TOTP or recovery code"
+// @Success 200 {object} errors.Response{data=RecoveryCodesResponse}
+// @Failure 400 {object} errors.Response "Invalid code"
+// @Router /api/v1/users/me/mfa/recovery-codes [post]
+func (h *Handler) RegenerateRecoveryCodes(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		return
+	}
+
+	var req CodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	codes, err := h.service.RegenerateRecoveryCodes(c.Request.Context(), claims.UserID, req.Code)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(RecoveryCodesResponse{RecoveryCodes: codes}))
+}
+
+// Verify godoc
+// @Summary Complete MFA login
+// @Description Exchanges the mfa_token returned by /auth/login plus a TOTP or recovery code for access and refresh tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body VerifyRequest true "MFA verification"
+// @Success 200 {object} errors.Response{data=auth.AuthResponse} "Success"
+// @Failure 400 {object} errors.Response "Invalid code"
+// @Failure 401 {object} errors.Response "Invalid or expired MFA token"
+// @Router /api/v1/auth/mfa/verify [post]
+func (h *Handler) Verify(c *gin.Context) {
+	var req VerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	user, err := h.service.VerifyChallenge(ctx, req.MFAToken, req.Code)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	roles := make([]string, len(user.Roles))
+	for i, r := range user.Roles {
+		roles[i] = r.Name
+	}
+
+	tokenPair, err := h.authService.GenerateTokenPair(ctx, user.ID, user.Email, user.Name, roles, []string{auth.AMROTP, auth.AMRMFA})
+	if err != nil {
+		if errors.Is(err, auth.ErrSuspended) {
+			_ = c.Error(apiErrors.Forbidden("Your account is suspended"))
+			return
+		}
+		_ = c.Error(apiErrors.InternalServerError(fmt.Errorf("failed to generate tokens: %w", err)))
+		return
+	}
+
+	h.transport.WriteAuthResponse(c, auth.NewAuthResponse(tokenPair, user, roles))
+}
+
+func currentClaims(c *gin.Context) (*auth.Claims, bool) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		_ = c.Error(apiErrors.Unauthorized(""))
+		return nil, false
+	}
+	return claims.(*auth.Claims), true
+}
@@ -0,0 +1,383 @@
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"paas-core/apps/api/internal/alert"
+	apiErrors "paas-core/apps/api/internal/errors"
+	"paas-core/apps/api/internal/model"
+)
+
+const (
+	challengeExpiry       = 5 * time.Minute
+	maxChallengeAttempts  = 5
+	recoveryCodeCount     = 10
+	challengeTokenLength  = 32
+	recoveryCodeByteCount = 5 // 10 hex characters
+)
+
+// Service manages TOTP enrollment, recovery codes and login challenges.
+type Service struct {
+	db      *gorm.DB
+	issuer  string // shown in authenticator apps, usually the app name
+	secrets *secretBox
+	alerts  *alert.Service
+}
+
+// NewService creates a new MFA service. TOTP secrets are encrypted with
+// encryptionKey; an empty key stores them in plaintext.
+func NewService(db *gorm.DB, issuer, encryptionKey string, alerts *alert.Service) *Service {
+	return &Service{db: db, issuer: issuer, secrets: newSecretBox(encryptionKey), alerts: alerts}
+}
+
+// EncryptStoredSecrets encrypts TOTP secrets that were stored in plaintext,
+// before an encryption key was configured. It returns how many were updated.
+func (s *Service) EncryptStoredSecrets(ctx context.Context) (int, error) {
+	if s.secrets.aead == nil {
+		return 0, nil
+	}
+
+	var factors []model.TOTPFactor
+	if err := s.db.WithContext(ctx).
+		Where("secret NOT LIKE ?", sealedSecretPrefix+"%").
+		Find(&factors).Error; err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, factor := range factors {
+		sealed, err := s.secrets.seal(factor.UserID, factor.Secret)
+		if err != nil {
+			return updated, err
+		}
+		// Conditional so a factor re-enrolled meanwhile is left alone.
+		res := s.db.WithContext(ctx).Model(&model.TOTPFactor{}).
+			Where("id = ? AND secret = ?", factor.ID, factor.Secret).
+			Update("secret", sealed)
+		if res.Error != nil {
+			return updated, res.Error
+		}
+		updated += int(res.RowsAffected)
+	}
+	return updated, nil
+}
+
+// Status reports whether the user has MFA enabled.
+func (s *Service) Status(ctx context.Context, userID uuid.UUID) (*StatusResponse, error) {
+	enabled, err := s.IsEnabled(ctx, userID)
+	if err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	var remaining int64
+	if enabled {
+		if err := s.db.WithContext(ctx).Model(&model.RecoveryCode{}).
+			Where("user_id = ? AND used_at IS NULL", userID).
+			Count(&remaining).Error; err != nil {
+			return nil, apiErrors.InternalServerError(err)
+		}
+	}
+
+	return &StatusResponse{Enabled: enabled, RecoveryCodesRemaining: int(remaining)}, nil
+}
+
+// IsEnabled reports whether the user has a confirmed TOTP factor.
+func (s *Service) IsEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&model.TOTPFactor{}).
+		Where("user_id = ? AND confirmed_at IS NOT NULL", userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Enroll starts TOTP enrollment by generating a new secret. Any previous
+// unconfirmed enrollment is replaced.
+func (s *Service) Enroll(ctx context.Context, userID uuid.UUID, accountName string) (*EnrollResponse, error) {
+	enabled, err := s.IsEnabled(ctx, userID)
+	if err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+	if enabled {
+		return nil, apiErrors.Conflict("MFA is already enabled")
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, apiErrors.InternalServerError(fmt.Errorf("failed to generate TOTP secret: %w", err))
+	}
+
+	sealed, err := s.secrets.seal(userID, secret)
+	if err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.TOTPFactor{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.TOTPFactor{UserID: userID, Secret: sealed}).Error
+	})
+	if err != nil {
+		return nil, apiErrors.InternalServerError(fmt.Errorf("failed to store TOTP secret: %w", err))
+	}
+
+	return &EnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: provisioningURI(s.issuer, accountName, secret),
+	}, nil
+}
+
+// ConfirmEnrollment activates the pending TOTP factor once the user proves the
+// authenticator works, and returns a fresh set of recovery codes.
+func (s *Service) ConfirmEnrollment(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	var codes []string
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var factor model.TOTPFactor
+		if err := tx.Where("user_id = ?", userID).First(&factor).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apiErrors.BadRequest("No pending MFA enrollment")
+			}
+			return apiErrors.InternalServerError(err)
+		}
+		if factor.ConfirmedAt != nil {
+			return apiErrors.Conflict("MFA is already enabled")
+		}
+
+		secret, err := s.secrets.open(userID, factor.Secret)
+		if err != nil {
+			return apiErrors.InternalServerError(err)
+		}
+		step, ok := validateTOTP(secret, code, time.Now(), factor.LastUsedStep)
+		if !ok {
+			return apiErrors.BadRequest("Invalid verification code")
+		}
+
+		now := time.Now()
+		if err := tx.Model(&factor).Updates(map[string]interface{}{
+			"confirmed_at":   now,
+			"last_used_step": step,
+		}).Error; err != nil {
+			return apiErrors.InternalServerError(err)
+		}
+
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.alerts.MFAChanged(ctx, userID, "Two-factor authentication was turned on")
+	return codes, nil
+}
+
+// Disable removes the TOTP factor and recovery codes. A valid TOTP or recovery
+// code is required.
+func (s *Service) Disable(ctx context.Context, userID uuid.UUID, code string) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.verifyFactor(tx, userID, code); err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&model.TOTPFactor{}).Error; err != nil {
+			return apiErrors.InternalServerError(err)
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return apiErrors.InternalServerError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.alerts.MFAChanged(ctx, userID, "Two-factor authentication was turned off")
+	return nil
+}
+
+// VerifyFactor checks a TOTP or recovery code for a signed-in user, e.g. for
+// step-up re-authentication, and marks it as used.
+func (s *Service) VerifyFactor(ctx context.Context, userID uuid.UUID, code string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return s.verifyFactor(tx, userID, code)
+	})
+}
+
+// RegenerateRecoveryCodes invalidates all existing recovery codes and returns
+// a new set. A valid TOTP or recovery code is required.
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	var codes []string
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.verifyFactor(tx, userID, code); err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.alerts.MFAChanged(ctx, userID, "New recovery codes were generated")
+	return codes, nil
+}
+
+// CreateChallenge issues a short-lived MFA token for a user who has passed
+// the first factor.
+func (s *Service) CreateChallenge(ctx context.Context, userID uuid.UUID) (string, error) {
+	buf := make([]byte, challengeTokenLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate MFA token: %w", err)
+	}
+	rawToken := hex.EncodeToString(buf)
+
+	challenge := &model.MFAChallenge{
+		UserID:    userID,
+		TokenHash: hashValue(rawToken),
+		ExpiresAt: time.Now().Add(challengeExpiry),
+	}
+	if err := s.db.WithContext(ctx).Create(challenge).Error; err != nil {
+		return "", fmt.Errorf("failed to create MFA challenge: %w", err)
+	}
+
+	return rawToken, nil
+}
+
+// VerifyChallenge checks the MFA token and code and, on success, consumes the
+// challenge and returns the user with roles loaded.
+func (s *Service) VerifyChallenge(ctx context.Context, mfaToken, code string) (*model.User, error) {
+	tokenHash := hashValue(mfaToken)
+	now := time.Now()
+
+	// Count the attempt up front so a wrong code cannot be retried indefinitely.
+	res := s.db.WithContext(ctx).Model(&model.MFAChallenge{}).
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ? AND attempts < ?", tokenHash, now, maxChallengeAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if res.Error != nil {
+		return nil, apiErrors.InternalServerError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil, apiErrors.Unauthorized("Invalid or expired MFA token")
+	}
+
+	var user model.User
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var challenge model.MFAChallenge
+		if err := tx.Where("token_hash = ?", tokenHash).First(&challenge).Error; err != nil {
+			return apiErrors.InternalServerError(err)
+		}
+
+		if err := s.verifyFactor(tx, challenge.UserID, code); err != nil {
+			return err
+		}
+
+		res := tx.Model(&model.MFAChallenge{}).
+			Where("id = ? AND used_at IS NULL", challenge.ID).
+			Update("used_at", now)
+		if res.Error != nil {
+			return apiErrors.InternalServerError(res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return apiErrors.Unauthorized("Invalid or expired MFA token")
+		}
+
+		if err := tx.Preload("Roles").First(&user, "id = ?", challenge.UserID).Error; err != nil {
+			return apiErrors.InternalServerError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// verifyFactor accepts either a TOTP code from a confirmed factor or an unused
+// recovery code, and marks it as used.
+func (s *Service) verifyFactor(tx *gorm.DB, userID uuid.UUID, code string) error {
+	var factor model.TOTPFactor
+	err := tx.Where("user_id = ? AND confirmed_at IS NOT NULL", userID).First(&factor).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apiErrors.BadRequest("MFA is not enabled")
+		}
+		return apiErrors.InternalServerError(err)
+	}
+
+	secret, err := s.secrets.open(userID, factor.Secret)
+	if err != nil {
+		return apiErrors.InternalServerError(err)
+	}
+	if step, ok := validateTOTP(secret, code, time.Now(), factor.LastUsedStep); ok {
+		// Conditional update so the same code cannot be used twice concurrently.
+		res := tx.Model(&model.TOTPFactor{}).
+			Where("id = ? AND last_used_step < ?", factor.ID, step).
+			Update("last_used_step", step)
+		if res.Error != nil {
+			return apiErrors.InternalServerError(res.Error)
+		}
+		if res.RowsAffected == 1 {
+			return nil
+		}
+		return apiErrors.BadRequest("Invalid verification code")
+	}
+
+	res := tx.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashValue(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return apiErrors.InternalServerError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return apiErrors.BadRequest("Invalid verification code")
+	}
+	return nil
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and stores a new set,
+// returning the plaintext codes.
+func replaceRecoveryCodes(tx *gorm.DB, userID uuid.UUID) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	records := make([]model.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		buf := make([]byte, recoveryCodeByteCount)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, apiErrors.InternalServerError(fmt.Errorf("failed to generate recovery code: %w", err))
+		}
+		raw := hex.EncodeToString(buf)
+		codes[i] = raw[:5] + "-" + raw[5:]
+		records[i] = model.RecoveryCode{UserID: userID, CodeHash: hashValue(raw)}
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+func hashValue(v string) string {
+	h := sha256.Sum256([]byte(v))
+	return hex.EncodeToString(h[:])
+}
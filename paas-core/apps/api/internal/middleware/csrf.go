@@ -22,17 +22,29 @@ const (
 // For state-changing methods the middleware checks that the header
 // X-CSRF-Token matches the value in the __csrf_token cookie.
 //
+// The cookie is set on the same domain as the session cookies so a frontend
+// on a sibling subdomain can read it.
+//
+// Paths under an exempt prefix are skipped entirely; use this for protocol
+// endpoints called by third-party clients that authenticate themselves.
+//
 // Goilerplate pattern: https://goilerplate.com/docs/features/security
-func CSRFProtection(secureCookie bool) gin.HandlerFunc {
+func CSRFProtection(secureCookie bool, domain string, sameSite http.SameSite, exempt ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		for _, prefix := range exempt {
+			if strings.HasPrefix(c.Request.URL.Path, prefix) {
+				c.Next()
+				return
+			}
+		}
+
 		// Always ensure a CSRF cookie exists (set on every response).
 		token, err := c.Cookie(csrfCookieName)
 		if err != nil || token == "" {
 			token = generateCSRFToken()
 		}
-		sameSite := http.SameSiteLaxMode
 		c.SetSameSite(sameSite)
-		c.SetCookie(csrfCookieName, token, 86400, "/", "", secureCookie, false) // readable by JS
+		c.SetCookie(csrfCookieName, token, 86400, "/", domain, secureCookie, false) // readable by JS
 
 		// Safe methods — skip validation.
 		method := strings.ToUpper(c.Request.Method)
@@ -41,6 +53,14 @@ func CSRFProtection(secureCookie bool) gin.HandlerFunc {
 			return
 		}
 
+		// Requests that carry their own credentials (bearer tokens, client
+		// credentials) are not sent automatically by browsers, so they cannot
+		// be forged cross-site.
+		if c.GetHeader("Authorization") != "" {
+			c.Next()
+			return
+		}
+
 		// State-changing method — validate double-submit.
 		headerToken := c.GetHeader(csrfHeaderName)
 		if headerToken == "" || headerToken != token {
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"log/slog"
 	"strconv"
 	"strings"
@@ -19,6 +20,12 @@ type TokenValidator interface {
 	ValidateToken(tokenString string) (*auth.Claims, error)
 }
 
+// ContextTokenValidator is implemented by validators that query the database
+// and should use the request's context.
+type ContextTokenValidator interface {
+	ValidateTokenContext(ctx context.Context, tokenString string) (*auth.Claims, error)
+}
+
 // RequestID injects a unique request-id header for tracing.
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -32,6 +39,19 @@ func RequestID() gin.HandlerFunc {
 	}
 }
 
+// ClientInfo attaches the caller's user agent and IP to the request context so
+// the auth service can record them on device sessions.
+func ClientInfo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := auth.WithClientInfo(c.Request.Context(), auth.ClientInfo{
+			UserAgent: c.Request.UserAgent(),
+			IPAddress: c.ClientIP(),
+		})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
 // Logger is a structured slog request logger.
 func Logger() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -70,7 +90,8 @@ func Logger() gin.HandlerFunc {
 }
 
 // JWTAuth validates the JWT access token from the Authorization header and
-// stores the parsed Claims in the Gin context.
+// stores the parsed Claims in the Gin context. Scoped tokens (personal access
+// tokens) also have their scopes stored under "scopes".
 func JWTAuth(validator TokenValidator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Try Authorization header first
@@ -84,9 +105,10 @@ func JWTAuth(validator TokenValidator) gin.HandlerFunc {
 			}
 		}
 
-		// Fall back to cookie
-		if tokenString == "" {
-			if cookie, err := c.Cookie("access_token"); err == nil {
+		// Fall back to the session cookie only when no Authorization header
+		// was sent, so cookie-authenticated requests always pass CSRF checks
+		if header == "" {
+			if cookie, err := c.Cookie(auth.AccessTokenCookie); err == nil {
 				tokenString = cookie
 			}
 		}
@@ -97,15 +119,104 @@ func JWTAuth(validator TokenValidator) gin.HandlerFunc {
 			return
 		}
 
-		claims, err := validator.ValidateToken(tokenString)
+		var claims *auth.Claims
+		var err error
+		if cv, ok := validator.(ContextTokenValidator); ok {
+			claims, err = cv.ValidateTokenContext(c.Request.Context(), tokenString)
+		} else {
+			claims, err = validator.ValidateToken(tokenString)
+		}
 		if err != nil {
 			_ = c.Error(apiErrors.Unauthorized(err.Error()))
 			c.Abort()
 			return
 		}
 
+		if claims.IsScoped() {
+			c.Set("scopes", claims.Scopes)
+		}
+
 		c.Set("claims", claims)
 		c.Set("user_id", claims.UserID)
+		c.Request = c.Request.WithContext(auth.WithAccessToken(c.Request.Context(), tokenString))
+		c.Next()
+	}
+}
+
+// RejectScopedTokens only lets interactive sessions through. Routes that
+// should be reachable with personal access tokens declare RequireScope instead.
+func RejectScopedTokens() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, scoped := c.Get("scopes"); scoped {
+			_ = c.Error(apiErrors.Forbidden("This endpoint does not accept access tokens"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// BlockImpersonation rejects impersonation tokens on destructive or
+// credential-changing endpoints, such as billing changes and org deletion.
+func BlockImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsVal, exists := c.Get("claims")
+		if !exists {
+			_ = c.Error(apiErrors.Unauthorized(""))
+			c.Abort()
+			return
+		}
+
+		if claimsVal.(*auth.Claims).IsImpersonated() {
+			_ = c.Error(apiErrors.Forbidden("This action is not allowed while impersonating a user"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRecentAuth guards sensitive operations such as deleting an org or
+// revealing secrets: the user must have signed in or re-authenticated within
+// maxAge. Long-lived sessions kept alive by refresh rotation get a
+// REAUTH_REQUIRED error. Scoped and impersonation tokens carry no auth_time
+// and are always refused.
+func RequireRecentAuth(maxAge time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsVal, exists := c.Get("claims")
+		if !exists {
+			_ = c.Error(apiErrors.Unauthorized(""))
+			c.Abort()
+			return
+		}
+
+		if !claimsVal.(*auth.Claims).AuthenticatedWithin(maxAge) {
+			_ = c.Error(apiErrors.ReauthRequired(int(maxAge.Seconds())))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireScope checks that a scoped token grants the given scope. Interactive
+// sessions are not restricted by scopes.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsVal, exists := c.Get("claims")
+		if !exists {
+			_ = c.Error(apiErrors.Unauthorized(""))
+			c.Abort()
+			return
+		}
+		authClaims := claimsVal.(*auth.Claims)
+
+		if !authClaims.HasScope(scope) {
+			_ = c.Error(apiErrors.Forbidden("Token is missing the " + scope + " scope"))
+			c.Abort()
+			return
+		}
+
 		c.Next()
 	}
 }
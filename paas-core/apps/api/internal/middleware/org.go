@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"paas-core/apps/api/internal/auth"
 	apiErrors "paas-core/apps/api/internal/errors"
 	"paas-core/apps/api/internal/model"
 )
@@ -38,6 +39,15 @@ func OrgResolver(db *gorm.DB) gin.HandlerFunc {
 		}
 		userID := userIDVal.(uuid.UUID)
 
+		// Org-restricted access tokens may only reach their own org.
+		if claimsVal, ok := c.Get("claims"); ok {
+			if claims := claimsVal.(*auth.Claims); claims.OrgID != nil && *claims.OrgID != orgID {
+				_ = c.Error(apiErrors.Forbidden("Token is not valid for this organization"))
+				c.Abort()
+				return
+			}
+		}
+
 		var membership model.Membership
 		result := db.Where("org_id = ? AND user_id = ?", orgID, userID).First(&membership)
 		if result.Error != nil {
@@ -22,13 +22,27 @@ type BaseModel struct {
 // User represents an authenticated user.
 type User struct {
 	BaseModel
-	Name          string       `gorm:"size:255;not null" json:"name"`
-	Email         string       `gorm:"size:255;uniqueIndex;not null" json:"email"`
-	PasswordHash  string       `gorm:"size:255" json:"-"` // empty for OAuth-only users
-	AvatarURL     string       `gorm:"size:512" json:"avatar_url,omitempty"`
-	EmailVerified bool         `gorm:"default:false" json:"email_verified"`
-	Roles         []Role       `gorm:"many2many:user_roles;" json:"roles,omitempty"`
-	Memberships   []Membership `gorm:"foreignKey:UserID" json:"-"`
+	Name             string       `gorm:"size:255;not null" json:"name"`
+	Email            string       `gorm:"size:255;uniqueIndex;not null" json:"email"`
+	PasswordHash     string       `gorm:"size:255" json:"-"` // empty for OAuth-only users
+	AvatarURL        string       `gorm:"size:512" json:"avatar_url,omitempty"`
+	EmailVerified    bool         `gorm:"default:false" json:"email_verified"`
+	IsServiceAccount bool         `gorm:"default:false;index" json:"is_service_account,omitempty"`   // backing user of a ServiceAccount
+	AuthProvider     string       `gorm:"size:32;not null;default:local;index" json:"auth_provider"` // provider that holds the credentials, see authprovider.CompositeProvider
+	SuspendedUntil   *time.Time   `json:"suspended_until,omitempty"`                                 // set while the auth provider bans the user
+	Roles            []Role       `gorm:"many2many:user_roles;" json:"roles,omitempty"`
+	Memberships      []Membership `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// HasPassword reports whether the user can sign in with a password. OAuth-only,
+// passkey-only and magic-link users have none.
+func (u *User) HasPassword() bool {
+	return u.PasswordHash != ""
+}
+
+// IsSuspended reports whether the user is currently banned from signing in.
+func (u *User) IsSuspended() bool {
+	return u.SuspendedUntil != nil && time.Now().Before(*u.SuspendedUntil)
 }
 
 // Role represents an RBAC role (e.g. admin, user).
@@ -55,6 +69,50 @@ type RefreshToken struct {
 	RevokedAt   *time.Time `gorm:""`
 }
 
+// UserSession describes the device behind a refresh token family. Its ID is
+// the Family shared by every refresh token issued for that login.
+type UserSession struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index"`
+	UserAgent  string     `gorm:"size:512"`
+	IPAddress  string     `gorm:"size:64"`
+	LastUsedAt time.Time  `gorm:"not null"`
+	ExpiresAt  time.Time  `gorm:"not null"` // expiry of the newest refresh token
+	AuthTime   *time.Time `gorm:""`         // last time the user proved their identity; kept across refreshes
+	AMR        string     `gorm:"size:100"` // space-separated authentication methods behind AuthTime
+	CreatedAt  time.Time  `gorm:"autoCreateTime"`
+	RevokedAt  *time.Time `gorm:""`
+}
+
+// RevokedToken denylists a single access token by its jti until it expires.
+type RevokedToken struct {
+	JTI       string    `gorm:"size:64;primaryKey"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+}
+
+// TokenWatermark invalidates every access token issued to a user at or before
+// ValidAfter.
+type TokenWatermark struct {
+	UserID     uuid.UUID `gorm:"type:uuid;primaryKey"`
+	ValidAfter time.Time `gorm:"not null"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime;index"`
+}
+
+// SigningKey stores an asymmetric JWT signing key. The primary key doubles as
+// the JWS "kid" header so verifiers can pick the right public key.
+type SigningKey struct {
+	ID         string     `gorm:"size:64;primaryKey"`     // kid
+	Algorithm  string     `gorm:"size:16;not null"`       // RS256, EdDSA
+	PrivateKey string     `gorm:"type:text"`              // PKCS#8 PEM, encrypted when jwt.key_encryption_key is set; cleared once retired
+	PublicKey  string     `gorm:"type:text;not null"`     // PKIX PEM
+	Status     string     `gorm:"size:20;not null;index"` // next, active, previous, retired
+	CreatedAt  time.Time  `gorm:"autoCreateTime"`
+	RotatedAt  *time.Time `gorm:""`
+	RetireAt   *time.Time `gorm:""`
+}
+
 // EmailVerificationToken stores tokens for email verification.
 type EmailVerificationToken struct {
 	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
@@ -75,6 +133,122 @@ type PasswordResetToken struct {
 	CreatedAt time.Time `gorm:"autoCreateTime"`
 }
 
+// MagicLinkToken stores single-use passwordless sign-in tokens. UserID is nil
+// when the link signs up an invited email that has no account yet.
+type MagicLinkToken struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID    *uuid.UUID `gorm:"type:uuid;index"`
+	Email     string     `gorm:"size:255;not null;index"`
+	TokenHash string     `gorm:"size:255;uniqueIndex;not null"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// EmailChangeRequest is a pending change of a user's email address. The
+// change is applied only once the new address confirms it; the old address
+// gets a link to cancel it.
+type EmailChangeRequest struct {
+	ID              uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID          uuid.UUID `gorm:"type:uuid;not null;index"`
+	OldEmail        string    `gorm:"size:255;not null"`
+	NewEmail        string    `gorm:"size:255;not null;index"`
+	TokenHash       string    `gorm:"size:255;uniqueIndex;not null"` // sent to the new address
+	CancelTokenHash string    `gorm:"size:255;uniqueIndex;not null"` // sent to the old address
+	ExpiresAt       time.Time `gorm:"not null"`
+	ConfirmedAt     *time.Time
+	CancelledAt     *time.Time
+	CreatedAt       time.Time `gorm:"autoCreateTime"`
+}
+
+// AccountDeletion is a user's request to delete their account. The account
+// is purged once PurgeAt passes unless the request is cancelled first.
+type AccountDeletion struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;index"`
+	PurgeAt     time.Time  `gorm:"not null;index"`
+	CancelledAt *time.Time `gorm:""`
+	CompletedAt *time.Time `gorm:""`
+	CreatedAt   time.Time  `gorm:"autoCreateTime"`
+}
+
+// AccountLockout tracks consecutive failed password logins for a user.
+type AccountLockout struct {
+	UserID         uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	FailedAttempts int        `gorm:"not null;default:0"`
+	LastFailedAt   time.Time  `gorm:"not null"`
+	LockedUntil    *time.Time `gorm:""`
+}
+
+// AccountUnlockToken is emailed to a locked-out user to unlock their account.
+type AccountUnlockToken struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	TokenHash string    `gorm:"size:255;uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// SecurityAlertToken backs the "this wasn't me" link of a security alert
+// email. Following the link signs the user out everywhere.
+type SecurityAlertToken struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	Event     string    `gorm:"size:50;not null"`
+	TokenHash string    `gorm:"size:255;uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// PersonalAccessToken is a long-lived, scoped API token for CI and scripts.
+// Only the SHA-256 hash of the token is stored.
+type PersonalAccessToken struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;index"`
+	OrgID       *uuid.UUID `gorm:"type:uuid;index"` // optional org restriction
+	Name        string     `gorm:"size:100;not null"`
+	TokenPrefix string     `gorm:"size:16;not null"` // first characters, shown to identify the token
+	TokenHash   string     `gorm:"size:255;uniqueIndex;not null"`
+	Scopes      string     `gorm:"size:512;not null"` // space-separated, e.g. "deployments:write env:read"
+	ExpiresAt   time.Time  `gorm:"not null"`
+	LastUsedAt  *time.Time `gorm:""`
+	RevokedAt   *time.Time `gorm:""`
+	CreatedAt   time.Time  `gorm:"autoCreateTime"`
+}
+
+// TOTPFactor stores a user's authenticator app secret (RFC 6238).
+type TOTPFactor struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID       uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex"`
+	Secret       string     `gorm:"size:128;not null"` // base32, AES-GCM encrypted when an MFA encryption key is set
+	LastUsedStep int64      `gorm:"default:0"`         // rejects replay of an accepted code
+	ConfirmedAt  *time.Time `gorm:""`                  // nil until enrollment is confirmed
+	CreatedAt    time.Time  `gorm:"autoCreateTime"`
+}
+
+// RecoveryCode is a single-use MFA backup code.
+type RecoveryCode struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index"`
+	CodeHash  string     `gorm:"size:255;not null"`
+	UsedAt    *time.Time `gorm:""`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+}
+
+// MFAChallenge is issued after a successful password check for users with MFA
+// enabled and must be exchanged together with a valid code for tokens.
+type MFAChallenge struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index"`
+	TokenHash string     `gorm:"size:255;uniqueIndex;not null"`
+	Attempts  int        `gorm:"default:0"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time `gorm:""`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+}
+
 // OAuthAccount links a user to an external OAuth provider.
 type OAuthAccount struct {
 	BaseModel
@@ -88,6 +262,47 @@ type OAuthAccount struct {
 	User         User      `gorm:"foreignKey:UserID" json:"-"`
 }
 
+// OAuthReauthState binds an OAuth round trip started for step-up
+// re-authentication to the session that asked for it.
+type OAuthReauthState struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	SessionID uuid.UUID `gorm:"type:uuid;not null"`
+	Provider  string    `gorm:"size:50;not null"`
+	StateHash string    `gorm:"size:64;uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// WebAuthnCredential is a registered passkey (WebAuthn public key credential).
+type WebAuthnCredential struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID          uuid.UUID  `gorm:"type:uuid;not null;index"`
+	CredentialID    []byte     `gorm:"type:bytea;uniqueIndex;not null"`
+	PublicKey       []byte     `gorm:"type:bytea;not null"` // COSE-encoded
+	AttestationType string     `gorm:"size:32"`
+	AAGUID          []byte     `gorm:"type:bytea"`
+	SignCount       uint32     `gorm:"default:0"`
+	Transports      string     `gorm:"size:255"` // comma-separated, e.g. "internal,hybrid"
+	BackupEligible  bool       `gorm:"default:false"`
+	BackupState     bool       `gorm:"default:false"`
+	Name            string     `gorm:"size:100"` // user-facing label
+	LastUsedAt      *time.Time `gorm:""`
+	CreatedAt       time.Time  `gorm:"autoCreateTime"`
+}
+
+// WebAuthnSession holds the challenge state between the begin and finish
+// steps of a registration or login ceremony.
+type WebAuthnSession struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID    *uuid.UUID `gorm:"type:uuid;index"`    // nil for discoverable login
+	Ceremony  string     `gorm:"size:20;not null"`   // registration, login
+	Data      string     `gorm:"type:text;not null"` // JSON-encoded webauthn.SessionData
+	ExpiresAt time.Time  `gorm:"not null"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+}
+
 // FileUpload tracks uploaded files (avatars, attachments, etc.).
 type FileUpload struct {
 	BaseModel
@@ -135,6 +350,78 @@ type OrgInvite struct {
 	InvitedBy uuid.UUID  `gorm:"type:uuid;not null" json:"invited_by"`
 }
 
+// ServiceAccount is a non-human identity owned by an org. It is backed by a
+// User (IsServiceAccount) so it holds an org role through a regular
+// Membership, and authenticates with a client ID and secret.
+type ServiceAccount struct {
+	BaseModel
+	OrgID       uuid.UUID  `gorm:"type:uuid;not null;index" json:"org_id"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex" json:"user_id"`
+	Name        string     `gorm:"size:100;not null" json:"name"`
+	Description string     `gorm:"size:255" json:"description,omitempty"`
+	ClientID    string     `gorm:"size:64;uniqueIndex;not null" json:"client_id"`
+	SecretHash  string     `gorm:"size:64;not null" json:"-"`                // SHA-256 hex of the client secret
+	Scopes      string     `gorm:"size:512;not null" json:"scopes"`          // space-separated
+	CreatedByID *uuid.UUID `gorm:"type:uuid" json:"created_by_id,omitempty"` // no FK: outlives its creator
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	User        User       `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// --- OpenID Connect provider ---
+
+// OIDCClient is an app registered by an org to "Sign in with" the platform.
+// Public clients (SPAs, mobile apps) have no secret and rely on PKCE alone.
+type OIDCClient struct {
+	BaseModel
+	OrgID        uuid.UUID  `gorm:"type:uuid;not null;index" json:"org_id"`
+	Name         string     `gorm:"size:100;not null" json:"name"`
+	ClientID     string     `gorm:"size:64;uniqueIndex;not null" json:"client_id"`
+	SecretHash   string     `gorm:"size:64" json:"-"`                        // SHA-256 hex; empty for public clients
+	RedirectURIs string     `gorm:"type:text;not null" json:"redirect_uris"` // space-separated, matched exactly
+	LogoURL      string     `gorm:"size:512" json:"logo_url,omitempty"`
+	CreatedByID  *uuid.UUID `gorm:"type:uuid" json:"created_by_id,omitempty"` // no FK: outlives its creator
+}
+
+// OIDCAuthorizationCode is a single-use authorization code bound to a PKCE
+// challenge.
+type OIDCAuthorizationCode struct {
+	ID            uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	CodeHash      string    `gorm:"size:64;uniqueIndex;not null"`
+	ClientID      uuid.UUID `gorm:"type:uuid;not null;index"`
+	UserID        uuid.UUID `gorm:"type:uuid;not null;index"`
+	RedirectURI   string    `gorm:"type:text;not null"`
+	Scope         string    `gorm:"size:255;not null"`
+	Nonce         string    `gorm:"size:255"`
+	CodeChallenge string    `gorm:"size:128;not null"`
+	AuthTime      time.Time `gorm:"not null"`
+	ExpiresAt     time.Time `gorm:"not null"`
+	UsedAt        *time.Time
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+}
+
+// OIDCConsent remembers the scopes a user has granted to a client.
+type OIDCConsent struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	ClientID  uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Scope     string    `gorm:"size:255;not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// OIDCAccessToken is an opaque access token issued to a client. These tokens
+// are only accepted by the userinfo endpoint, never by the platform API.
+type OIDCAccessToken struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TokenHash string    `gorm:"size:64;uniqueIndex;not null"`
+	ClientID  uuid.UUID `gorm:"type:uuid;not null;index"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	CodeID    uuid.UUID `gorm:"type:uuid;not null;index"` // revoked together if the code is replayed
+	Scope     string    `gorm:"size:255;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	RevokedAt *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
 // --- Projects & Deployments ---
 
 // Project represents a deployable application within an org.
@@ -169,15 +456,24 @@ type EnvVar struct {
 	IsSecret  bool      `gorm:"default:false" json:"is_secret"`
 }
 
-// AuditLog records important actions within an org.
+// AuditLog records important actions within an org. Platform events such as
+// impersonation sessions have no org.
 type AuditLog struct {
-	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	OrgID     uuid.UUID `gorm:"type:uuid;not null;index" json:"org_id"`
-	ActorID   uuid.UUID `gorm:"type:uuid;not null" json:"actor_id"`
-	Action    string    `gorm:"size:100;not null" json:"action"`
-	Resource  string    `gorm:"size:100" json:"resource"`
-	Details   string    `gorm:"type:jsonb" json:"details,omitempty"`
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	OrgID     *uuid.UUID `gorm:"type:uuid;index" json:"org_id,omitempty"`
+	ActorID   uuid.UUID  `gorm:"type:uuid;not null" json:"actor_id"`
+	Action    string     `gorm:"size:100;not null" json:"action"`
+	Resource  string     `gorm:"size:100" json:"resource"`
+	Details   string     `gorm:"type:jsonb" json:"details,omitempty"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// ProcessedWebhookEvent records an inbound webhook event that was applied, so
+// redeliveries are acknowledged without being applied twice.
+type ProcessedWebhookEvent struct {
+	Source    string    `gorm:"size:50;primaryKey"`
+	EventID   string    `gorm:"size:255;primaryKey"`
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
 }
 
 // --- Billing / Xendit ---
@@ -249,6 +545,12 @@ var RoleHierarchy = map[string]int{
 	RoleOwner:     4,
 }
 
+// HumanMembers is a GORM scope on memberships that leaves out service accounts.
+func HumanMembers(db *gorm.DB) *gorm.DB {
+	return db.Joins("JOIN users ON users.id = memberships.user_id").
+		Where("users.is_service_account = ?", false)
+}
+
 // HasPermission checks if roleA has at least the power of requiredRole.
 func HasPermission(userRole, requiredRole string) bool {
 	return RoleHierarchy[userRole] >= RoleHierarchy[requiredRole]
@@ -0,0 +1,86 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/bitbucket"
+
+	"paas-core/apps/api/internal/config"
+)
+
+// bitbucketProvider signs users in with Bitbucket Cloud.
+type bitbucketProvider struct {
+	providerBase
+	config *oauth2.Config
+}
+
+// NewBitbucketProvider creates a Bitbucket OAuth provider.
+func NewBitbucketProvider(cfg config.OAuthProviderConfig, baseURL string) Provider {
+	cfg.Type = "bitbucket"
+	base := newProviderBase(cfg)
+	return &bitbucketProvider{
+		providerBase: base,
+		config:       oauth2Config(cfg, base, baseURL, bitbucket.Endpoint, []string{"account", "email"}),
+	}
+}
+
+func (b *bitbucketProvider) GetAuthURL(req AuthRequest) string {
+	return authCodeURL(b.config, req)
+}
+
+func (b *bitbucketProvider) ExchangeCode(ctx context.Context, code string, req AuthRequest) (*ProviderUser, *oauth2.Token, error) {
+	// Bitbucket does not issue ID tokens
+	token, _, err := exchangeCode(ctx, b.config, nil, code, req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bitbucket code exchange failed: %w", err)
+	}
+	client := b.config.Client(ctx, token)
+
+	var user struct {
+		UUID        string `json:"uuid"`
+		DisplayName string `json:"display_name"`
+		Nickname    string `json:"nickname"`
+		Links       struct {
+			Avatar struct {
+				Href string `json:"href"`
+			} `json:"avatar"`
+		} `json:"links"`
+	}
+	if err := getJSON(client, "https://api.bitbucket.org/2.0/user", &user); err != nil {
+		return nil, nil, fmt.Errorf("bitbucket user request failed: %w", err)
+	}
+
+	// The profile carries no email; take the primary confirmed address
+	var emails struct {
+		Values []struct {
+			Email       string `json:"email"`
+			IsPrimary   bool   `json:"is_primary"`
+			IsConfirmed bool   `json:"is_confirmed"`
+		} `json:"values"`
+	}
+	if err := getJSON(client, "https://api.bitbucket.org/2.0/user/emails", &emails); err != nil {
+		return nil, nil, fmt.Errorf("bitbucket emails request failed: %w", err)
+	}
+	email, verified := "", false
+	for _, e := range emails.Values {
+		if e.IsPrimary {
+			email, verified = e.Email, e.IsConfirmed
+			break
+		}
+	}
+
+	name := user.DisplayName
+	if name == "" {
+		name = user.Nickname
+	}
+
+	return &ProviderUser{
+		ID:            user.UUID,
+		Email:         email,
+		EmailVerified: verified,
+		Name:          name,
+		AvatarURL:     user.Links.Avatar.Href,
+	}, token, nil
+}
@@ -0,0 +1,82 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/oauth2"
+
+	"paas-core/apps/api/internal/config"
+)
+
+const defaultGitLabURL = "https://gitlab.com"
+
+// gitlabProvider signs users in with GitLab.com or a self-hosted instance.
+type gitlabProvider struct {
+	providerBase
+	config  *oauth2.Config
+	baseURL string
+}
+
+// NewGitLabProvider creates a GitLab OAuth provider. cfg.BaseURL selects a
+// self-hosted instance.
+func NewGitLabProvider(cfg config.OAuthProviderConfig, baseURL string) Provider {
+	cfg.Type = "gitlab"
+	instance := strings.TrimSuffix(cfg.BaseURL, "/")
+	if instance == "" {
+		instance = defaultGitLabURL
+	}
+	base := newProviderBase(cfg)
+	endpoint := oauth2.Endpoint{
+		AuthURL:  instance + "/oauth/authorize",
+		TokenURL: instance + "/oauth/token",
+	}
+	return &gitlabProvider{
+		providerBase: base,
+		config:       oauth2Config(cfg, base, baseURL, endpoint, []string{"read_user"}),
+		baseURL:      instance,
+	}
+}
+
+func (g *gitlabProvider) GetAuthURL(req AuthRequest) string {
+	return authCodeURL(g.config, req)
+}
+
+func (g *gitlabProvider) ExchangeCode(ctx context.Context, code string, req AuthRequest) (*ProviderUser, *oauth2.Token, error) {
+	// The instance URL is the issuer when the openid scope is configured
+	token, sub, err := exchangeCode(ctx, g.config, exactIssuer(g.baseURL), code, req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("gitlab code exchange failed: %w", err)
+	}
+
+	var user struct {
+		ID          int64   `json:"id"`
+		Username    string  `json:"username"`
+		Name        string  `json:"name"`
+		Email       string  `json:"email"`
+		AvatarURL   string  `json:"avatar_url"`
+		ConfirmedAt *string `json:"confirmed_at"`
+	}
+	if err := getJSON(g.config.Client(ctx, token), g.baseURL+"/api/v4/user", &user); err != nil {
+		return nil, nil, fmt.Errorf("gitlab user request failed: %w", err)
+	}
+	id := fmt.Sprintf("%d", user.ID)
+	if err := checkSubject(sub, id); err != nil {
+		return nil, nil, fmt.Errorf("gitlab user request failed: %w", err)
+	}
+
+	name := user.Name
+	if name == "" {
+		name = user.Username
+	}
+
+	// The primary email of a confirmed GitLab account is confirmed itself
+	return &ProviderUser{
+		ID:            id,
+		Email:         user.Email,
+		EmailVerified: user.Email != "" && user.ConfirmedAt != nil,
+		Name:          name,
+		AvatarURL:     user.AvatarURL,
+	}, token, nil
+}
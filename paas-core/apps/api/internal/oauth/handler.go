@@ -1,12 +1,12 @@
 package oauth
 
 import (
-	"crypto/rand"
-	"encoding/base64"
+	"crypto/subtle"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -20,6 +20,8 @@ import (
 var (
 	ErrLastAuthMethod = errors.New("cannot unlink the last authentication method")
 	ErrAccountNotLinked = errors.New("oauth account not linked")
+	ErrUnverifiedEmail  = errors.New("provider email is not verified")
+	ErrUnverifiedAccount = errors.New("existing account email is not verified")
 )
 
 // OAuthAccountResponse is the public DTO for a linked OAuth account.
@@ -33,23 +35,49 @@ type OAuthAccountResponse struct {
 // Handler handles OAuth HTTP routes.
 type Handler struct {
 	providers   map[string]Provider
+	order       []Provider // configuration order, for listing
 	service     *OAuthService
 	authService auth.Service
+	mfa         auth.MFAChallenger // optional; nil disables the MFA step
+	transport   *auth.SessionTransport
+	states      *stateSealer
 	frontendURL string
 }
 
-// NewHandler creates a new OAuth handler.
-func NewHandler(providers map[string]Provider, service *OAuthService, authService auth.Service, frontendURL string) *Handler {
+// NewHandler creates a new OAuth handler for the given providers, listed in
+// the order their login buttons should appear. stateSecret seals the state
+// of round trips; all API instances must share it.
+func NewHandler(providers []Provider, service *OAuthService, authService auth.Service, mfa auth.MFAChallenger, transport *auth.SessionTransport, frontendURL, stateSecret string) *Handler {
+	byName := make(map[string]Provider, len(providers))
+	for _, p := range providers {
+		byName[p.Name()] = p
+	}
 	return &Handler{
-		providers:   providers,
+		providers:   byName,
+		order:       providers,
 		service:     service,
 		authService: authService,
+		mfa:         mfa,
+		transport:   transport,
+		states:      newStateSealer(stateSecret),
 		frontendURL: frontendURL,
 	}
 }
 
-// Initiate redirects the user to the provider's consent screen.
-// GET /auth/oauth/:provider
+// ListProviders returns the configured providers so the frontend can render
+// a login button for each.
+// GET /auth/oauth/providers
+func (h *Handler) ListProviders(c *gin.Context) {
+	infos := make([]ProviderInfo, 0, len(h.order))
+	for _, p := range h.order {
+		infos = append(infos, p.Info())
+	}
+	c.JSON(http.StatusOK, apiErrors.Success(infos))
+}
+
+// Initiate redirects the user to the provider's consent screen. An optional
+// redirect_to path on the frontend is handed back after login.
+// GET /auth/oauth/:provider?redirect_to=/path
 func (h *Handler) Initiate(c *gin.Context) {
 	providerName := c.Param("provider")
 	provider, ok := h.providers[providerName]
@@ -58,28 +86,32 @@ func (h *Handler) Initiate(c *gin.Context) {
 		return
 	}
 
-	// Generate cryptographic state token for CSRF protection
-	stateBytes := make([]byte, 32)
-	if _, err := rand.Read(stateBytes); err != nil {
-		_ = c.Error(apiErrors.InternalServerError(err))
+	redirectTo, err := sanitizeRedirect(h.frontendURL, c.Query("redirect_to"))
+	if err != nil {
+		_ = c.Error(apiErrors.BadRequest(err.Error()))
 		return
 	}
-	state := base64.URLEncoding.EncodeToString(stateBytes)
 
-	// Store state in a secure cookie (5 minutes TTL)
-	isProduction := c.GetHeader("X-Forwarded-Proto") == "https"
-	c.SetCookie(
-		"oauth_state",
-		state,
-		300, // 5 minutes
-		"/",
-		"",
-		isProduction,
-		true, // HttpOnly
-	)
+	flow, err := newFlowState(providerName)
+	if err != nil {
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+	flow.RedirectTo = redirectTo
+	// The binding cookie stops an attacker from completing their own login
+	// round trip in the victim's browser
+	if flow.Binding, err = randomToken(); err != nil {
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+	state, err := h.states.seal(flow)
+	if err != nil {
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
 
-	url := provider.GetAuthURL(state)
-	c.Redirect(http.StatusTemporaryRedirect, url)
+	h.setStateCookie(c, flow.Binding, int(stateExpiry.Seconds()))
+	c.Redirect(http.StatusTemporaryRedirect, provider.GetAuthURL(flow.authRequest(state)))
 }
 
 // Callback handles the provider's redirect after consent.
@@ -92,16 +124,25 @@ func (h *Handler) Callback(c *gin.Context) {
 		return
 	}
 
-	// Verify state token
 	state := c.Query("state")
-	savedState, err := c.Cookie("oauth_state")
-	if err != nil || state == "" || state != savedState {
+	flow, err := h.states.open(state, providerName)
+	if err != nil {
 		h.redirectError(c, "invalid_state", "Invalid or missing state token")
 		return
 	}
 
-	// Clear the state cookie
-	c.SetCookie("oauth_state", "", -1, "/", "", false, true)
+	// Re-authentication round trips are bound to the session server-side
+	if flow.ReauthState != "" {
+		h.reauthCallback(c, providerName, provider, flow, state)
+		return
+	}
+
+	binding, err := c.Cookie(stateCookie)
+	h.setStateCookie(c, "", -1)
+	if err != nil || flow.Binding == "" || subtle.ConstantTimeCompare([]byte(binding), []byte(flow.Binding)) != 1 {
+		h.redirectError(c, "invalid_state", "Invalid or missing state token")
+		return
+	}
 
 	// Check for error from provider
 	if errCode := c.Query("error"); errCode != "" {
@@ -117,7 +158,7 @@ func (h *Handler) Callback(c *gin.Context) {
 		return
 	}
 
-	providerUser, _, err := provider.ExchangeCode(c.Request.Context(), code)
+	providerUser, _, err := provider.ExchangeCode(c.Request.Context(), code, flow.authRequest(state))
 	if err != nil {
 		slog.Error("OAuth code exchange failed", "provider", providerName, "error", err)
 		h.redirectError(c, "exchange_failed", "Failed to exchange authorization code")
@@ -131,12 +172,45 @@ func (h *Handler) Callback(c *gin.Context) {
 
 	// Find or create user
 	user, roles, _, err := h.service.FindOrCreateUser(c.Request.Context(), providerName, providerUser)
+	if errors.Is(err, ErrUnverifiedEmail) {
+		h.redirectError(c, "email_not_verified", "An account with this email exists; verify the email with your provider first, or sign in with your password")
+		return
+	}
+	if errors.Is(err, ErrUnverifiedAccount) {
+		h.redirectError(c, "account_not_verified", "An account with this email exists but its address is not verified; verify it or reset the password first, then sign in with your provider")
+		return
+	}
 	if err != nil {
 		slog.Error("OAuth user creation failed", "provider", providerName, "error", err)
 		h.redirectError(c, "user_error", "Failed to create or link user account")
 		return
 	}
 
+	// Users with MFA enabled must complete /auth/mfa/verify before getting tokens
+	if h.mfa != nil {
+		enabled, err := h.mfa.IsEnabled(c.Request.Context(), user.ID)
+		if err != nil {
+			slog.Error("OAuth MFA lookup failed", "provider", providerName, "error", err)
+			h.redirectError(c, "user_error", "Failed to create or link user account")
+			return
+		}
+		if enabled {
+			mfaToken, err := h.mfa.CreateChallenge(c.Request.Context(), user.ID)
+			if err != nil {
+				slog.Error("OAuth MFA challenge failed", "provider", providerName, "error", err)
+				h.redirectError(c, "token_error", "Failed to generate authentication tokens")
+				return
+			}
+			c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf(
+				"%s/auth/oauth/callback#mfa_required=true&mfa_token=%s%s",
+				h.frontendURL,
+				mfaToken,
+				redirectParam(flow.RedirectTo),
+			))
+			return
+		}
+	}
+
 	// Generate JWT token pair
 	tokenPair, err := h.authService.GenerateTokenPair(
 		c.Request.Context(),
@@ -144,25 +218,159 @@ func (h *Handler) Callback(c *gin.Context) {
 		user.Email,
 		user.Name,
 		roles,
+		[]string{auth.AMRFederated},
 	)
+	if errors.Is(err, auth.ErrSuspended) {
+		h.redirectError(c, "account_suspended", "Your account is suspended")
+		return
+	}
 	if err != nil {
 		slog.Error("OAuth token generation failed", "provider", providerName, "error", err)
 		h.redirectError(c, "token_error", "Failed to generate authentication tokens")
 		return
 	}
 
+	h.transport.SetCookies(c, tokenPair)
+
+	// In cookie mode the tokens never appear in the URL
+	if !h.transport.ExposesTokens() {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf(
+			"%s/auth/oauth/callback#token_type=%s&expires_in=%d%s",
+			h.frontendURL,
+			tokenPair.TokenType,
+			tokenPair.ExpiresIn,
+			redirectParam(flow.RedirectTo),
+		))
+		return
+	}
+
 	// Redirect to frontend with tokens in URL fragment (not query params for security)
 	redirectURL := fmt.Sprintf(
-		"%s/auth/oauth/callback#access_token=%s&refresh_token=%s&token_type=%s&expires_in=%d",
+		"%s/auth/oauth/callback#access_token=%s&refresh_token=%s&token_type=%s&expires_in=%d%s",
 		h.frontendURL,
 		tokenPair.AccessToken,
 		tokenPair.RefreshToken,
 		tokenPair.TokenType,
 		tokenPair.ExpiresIn,
+		redirectParam(flow.RedirectTo),
 	)
 	c.Redirect(http.StatusTemporaryRedirect, redirectURL)
 }
 
+// BeginReauth starts a step-up re-authentication with a linked provider and
+// returns the provider URL to send the browser to.
+// POST /auth/reauthenticate/oauth/:provider
+func (h *Handler) BeginReauth(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+	providerName := c.Param("provider")
+	provider, ok := h.providers[providerName]
+	if !ok {
+		_ = c.Error(apiErrors.BadRequest(fmt.Sprintf("Unsupported provider: %s", providerName)))
+		return
+	}
+	if claims.SessionID == uuid.Nil {
+		_ = c.Error(apiErrors.BadRequest("Re-authentication requires an interactive session"))
+		return
+	}
+
+	reauthState, err := h.service.CreateReauthState(c.Request.Context(), claims.UserID, claims.SessionID, providerName)
+	if err != nil {
+		if errors.Is(err, ErrAccountNotLinked) {
+			_ = c.Error(apiErrors.BadRequest(fmt.Sprintf("No %s account is linked to your user", providerName)))
+			return
+		}
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	flow, err := newFlowState(providerName)
+	if err != nil {
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+	flow.ReauthState = reauthState
+	state, err := h.states.seal(flow)
+	if err != nil {
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(gin.H{"authorization_url": provider.GetAuthURL(flow.authRequest(state))}))
+}
+
+// reauthCallback completes a re-authentication round trip: the provider
+// account must be the one linked to the session's user. The session gets a
+// new access token with a fresh auth_time; its refresh token is unchanged.
+func (h *Handler) reauthCallback(c *gin.Context, providerName string, provider Provider, flow *flowState, state string) {
+	if errCode := c.Query("error"); errCode != "" {
+		errDesc := c.DefaultQuery("error_description", "OAuth authorization was denied")
+		h.redirectError(c, errCode, errDesc)
+		return
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		h.redirectError(c, "missing_code", "Authorization code is missing")
+		return
+	}
+
+	providerUser, _, err := provider.ExchangeCode(c.Request.Context(), code, flow.authRequest(state))
+	if err != nil {
+		slog.Error("OAuth code exchange failed", "provider", providerName, "error", err)
+		h.redirectError(c, "exchange_failed", "Failed to exchange authorization code")
+		return
+	}
+
+	record, err := h.service.ConsumeReauthState(c.Request.Context(), providerName, flow.ReauthState, providerUser)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidReauthState):
+			h.redirectError(c, "invalid_state", "Invalid or missing state token")
+		case errors.Is(err, ErrReauthMismatch):
+			h.redirectError(c, "reauth_mismatch", "Sign in with the account linked to your user")
+		default:
+			slog.Error("OAuth re-authentication failed", "provider", providerName, "error", err)
+			h.redirectError(c, "user_error", "Failed to verify your account")
+		}
+		return
+	}
+
+	tokenPair, err := h.authService.Reauthenticate(c.Request.Context(), record.UserID, record.SessionID, []string{auth.AMRFederated})
+	if err != nil {
+		if errors.Is(err, auth.ErrNoSession) {
+			h.redirectError(c, "session_revoked", "Your session has ended, please sign in again")
+			return
+		}
+		if errors.Is(err, auth.ErrSuspended) {
+			h.redirectError(c, "account_suspended", "Your account is suspended")
+			return
+		}
+		slog.Error("OAuth re-authentication token failed", "provider", providerName, "error", err)
+		h.redirectError(c, "token_error", "Failed to generate authentication tokens")
+		return
+	}
+
+	h.transport.SetCookies(c, tokenPair)
+
+	if !h.transport.ExposesTokens() {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf(
+			"%s/auth/reauthenticate/callback#token_type=%s&expires_in=%d",
+			h.frontendURL,
+			tokenPair.TokenType,
+			tokenPair.ExpiresIn,
+		))
+		return
+	}
+
+	c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf(
+		"%s/auth/reauthenticate/callback#access_token=%s&token_type=%s&expires_in=%d",
+		h.frontendURL,
+		tokenPair.AccessToken,
+		tokenPair.TokenType,
+		tokenPair.ExpiresIn,
+	))
+}
+
 // GetLinkedAccounts returns all OAuth accounts linked to the current user.
 // GET /users/me/oauth-accounts
 func (h *Handler) GetLinkedAccounts(c *gin.Context) {
@@ -210,6 +418,29 @@ func (h *Handler) UnlinkAccount(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("%s account unlinked successfully", provider)})
 }
 
+// setStateCookie sets or, with a negative maxAge, clears the cookie binding a
+// login round trip to the browser. It is always SameSite=Lax: the callback is
+// a cross-site navigation from the provider.
+func (h *Handler) setStateCookie(c *gin.Context, value string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     stateCookie,
+		Value:    value,
+		Path:     stateCookiePath,
+		MaxAge:   maxAge,
+		Secure:   h.transport.Secure(),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// redirectParam appends the post-login path to a callback fragment.
+func redirectParam(redirectTo string) string {
+	if redirectTo == "" {
+		return ""
+	}
+	return "&redirect_to=" + url.QueryEscape(redirectTo)
+}
+
 // redirectError redirects to the frontend with an error code and message.
 func (h *Handler) redirectError(c *gin.Context, code, message string) {
 	redirectURL := fmt.Sprintf(
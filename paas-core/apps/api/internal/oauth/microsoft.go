@@ -0,0 +1,87 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/microsoft"
+
+	"paas-core/apps/api/internal/config"
+)
+
+// microsoftProvider signs users in with Microsoft Entra ID work or school
+// accounts and, depending on the tenant, personal Microsoft accounts.
+type microsoftProvider struct {
+	providerBase
+	config *oauth2.Config
+	tenant string
+}
+
+// NewMicrosoftProvider creates a Microsoft Entra ID OAuth provider. cfg.Tenant
+// restricts sign-in to one directory; the default "common" accepts any.
+func NewMicrosoftProvider(cfg config.OAuthProviderConfig, baseURL string) Provider {
+	cfg.Type = "microsoft"
+	tenant := cfg.Tenant
+	if tenant == "" {
+		tenant = "common"
+	}
+	base := newProviderBase(cfg)
+	return &microsoftProvider{
+		providerBase: base,
+		config:       oauth2Config(cfg, base, baseURL, microsoft.AzureADEndpoint(tenant), []string{"openid", "profile", "email", "User.Read"}),
+		tenant:       tenant,
+	}
+}
+
+// issuer accepts ID tokens from the signed-in user's directory. Multi-tenant
+// endpoints name that directory in "tid"; a tenant configured by ID must
+// match it.
+func (m *microsoftProvider) issuer(iss string, claims jwt.MapClaims) bool {
+	tid, _ := claims["tid"].(string)
+	if tid == "" || iss != "https://login.microsoftonline.com/"+tid+"/v2.0" {
+		return false
+	}
+	if _, err := uuid.Parse(m.tenant); err == nil {
+		return strings.EqualFold(tid, m.tenant)
+	}
+	return true
+}
+
+func (m *microsoftProvider) GetAuthURL(req AuthRequest) string {
+	return authCodeURL(m.config, req)
+}
+
+func (m *microsoftProvider) ExchangeCode(ctx context.Context, code string, req AuthRequest) (*ProviderUser, *oauth2.Token, error) {
+	token, _, err := exchangeCode(ctx, m.config, m.issuer, code, req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("microsoft code exchange failed: %w", err)
+	}
+
+	var me struct {
+		ID                string `json:"id"`
+		DisplayName       string `json:"displayName"`
+		Mail              string `json:"mail"`
+		UserPrincipalName string `json:"userPrincipalName"`
+	}
+	if err := getJSON(m.config.Client(ctx, token), "https://graph.microsoft.com/v1.0/me", &me); err != nil {
+		return nil, nil, fmt.Errorf("microsoft user request failed: %w", err)
+	}
+
+	email := me.Mail
+	if email == "" {
+		email = me.UserPrincipalName
+	}
+
+	// Tenant admins can set any address as mail, so it is never treated as
+	// verified and Microsoft accounts are not auto-linked by email
+	return &ProviderUser{
+		ID:            me.ID,
+		Email:         email,
+		EmailVerified: false,
+		Name:          me.DisplayName,
+	}, token, nil
+}
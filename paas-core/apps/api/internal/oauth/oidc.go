@@ -0,0 +1,157 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"paas-core/apps/api/internal/config"
+)
+
+// oidcProvider signs users in with any OpenID Connect issuer through the
+// authorization code flow. The profile is read from the userinfo endpoint.
+type oidcProvider struct {
+	providerBase
+	config      *oauth2.Config
+	issuer      string
+	userInfoURL string
+}
+
+// NewOIDCProvider creates a generic OpenID Connect provider. Endpoints that
+// are not configured explicitly are read from the issuer's discovery document.
+func NewOIDCProvider(ctx context.Context, cfg config.OAuthProviderConfig, baseURL string) (Provider, error) {
+	cfg.Type = "oidc"
+	endpoint := oauth2.Endpoint{AuthURL: cfg.AuthURL, TokenURL: cfg.TokenURL}
+	userInfoURL := cfg.UserInfoURL
+	issuer := cfg.Issuer
+
+	if endpoint.AuthURL == "" || endpoint.TokenURL == "" || userInfoURL == "" {
+		doc, err := discoverOIDC(ctx, cfg.Issuer)
+		if err != nil {
+			return nil, fmt.Errorf("oauth provider %q: %w", cfg.Name, err)
+		}
+		issuer = doc.Issuer
+		if endpoint.AuthURL == "" {
+			endpoint.AuthURL = doc.AuthorizationEndpoint
+		}
+		if endpoint.TokenURL == "" {
+			endpoint.TokenURL = doc.TokenEndpoint
+		}
+		if userInfoURL == "" {
+			userInfoURL = doc.UserInfoEndpoint
+		}
+	}
+	if endpoint.AuthURL == "" || endpoint.TokenURL == "" || userInfoURL == "" {
+		return nil, fmt.Errorf("oauth provider %q: issuer does not publish authorization, token and userinfo endpoints", cfg.Name)
+	}
+
+	base := newProviderBase(cfg)
+	oauthConfig := oauth2Config(cfg, base, baseURL, endpoint, []string{"openid", "email", "profile"})
+	if issuer == "" && requestsIDToken(oauthConfig) {
+		return nil, fmt.Errorf("oauth provider %q: issuer is required to verify ID tokens", cfg.Name)
+	}
+	return &oidcProvider{
+		providerBase: base,
+		config:       oauthConfig,
+		issuer:       issuer,
+		userInfoURL:  userInfoURL,
+	}, nil
+}
+
+func (o *oidcProvider) GetAuthURL(req AuthRequest) string {
+	return authCodeURL(o.config, req)
+}
+
+func (o *oidcProvider) ExchangeCode(ctx context.Context, code string, req AuthRequest) (*ProviderUser, *oauth2.Token, error) {
+	token, sub, err := exchangeCode(ctx, o.config, exactIssuer(o.issuer), code, req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("oidc code exchange failed: %w", err)
+	}
+
+	var info struct {
+		Sub               string      `json:"sub"`
+		Email             string      `json:"email"`
+		EmailVerified     interface{} `json:"email_verified"`
+		Name              string      `json:"name"`
+		PreferredUsername string      `json:"preferred_username"`
+		Picture           string      `json:"picture"`
+	}
+	if err := getJSON(o.config.Client(ctx, token), o.userInfoURL, &info); err != nil {
+		return nil, nil, fmt.Errorf("oidc userinfo request failed: %w", err)
+	}
+	if info.Sub == "" {
+		return nil, nil, fmt.Errorf("oidc userinfo has no sub")
+	}
+	if err := checkSubject(sub, info.Sub); err != nil {
+		return nil, nil, fmt.Errorf("oidc userinfo request failed: %w", err)
+	}
+
+	// Some issuers send email_verified as the string "true"
+	verified := false
+	switch v := info.EmailVerified.(type) {
+	case bool:
+		verified = v
+	case string:
+		verified = v == "true"
+	}
+
+	name := info.Name
+	if name == "" {
+		name = info.PreferredUsername
+	}
+
+	return &ProviderUser{
+		ID:            info.Sub,
+		Email:         info.Email,
+		EmailVerified: verified,
+		Name:          name,
+		AvatarURL:     info.Picture,
+	}, token, nil
+}
+
+// oidcDiscovery is the subset of the provider metadata document we use.
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// discoverOIDC fetches <issuer>/.well-known/openid-configuration and checks
+// that the document belongs to the issuer.
+func discoverOIDC(ctx context.Context, issuer string) (*oidcDiscovery, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc discovery failed: %s returned HTTP %d", wellKnown, resp.StatusCode)
+	}
+
+	var doc oidcDiscovery
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+	if strings.TrimSuffix(doc.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return nil, fmt.Errorf("oidc discovery failed: issuer %q does not match %q", doc.Issuer, issuer)
+	}
+	return &doc, nil
+}
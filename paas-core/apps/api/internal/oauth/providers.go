@@ -2,11 +2,16 @@ package oauth
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/github"
 	googleOAuth "golang.org/x/oauth2/google"
@@ -16,46 +21,243 @@ import (
 
 // ProviderUser holds the user profile returned by an OAuth provider.
 type ProviderUser struct {
-	ID        string // provider's unique user ID
-	Email     string
-	Name      string
-	AvatarURL string
+	ID            string // provider's unique user ID
+	Email         string
+	EmailVerified bool // the provider vouches that the user owns Email
+	Name          string
+	AvatarURL     string
+}
+
+// AuthRequest holds the values that bind an authorization request to its
+// callback.
+type AuthRequest struct {
+	State        string
+	CodeVerifier string // PKCE verifier; only its S256 challenge leaves the server
+	Nonce        string // echoed in the ID token by OpenID Connect providers
 }
 
 // Provider defines the interface for an OAuth identity provider.
 type Provider interface {
-	GetAuthURL(state string) string
-	ExchangeCode(ctx context.Context, code string) (*ProviderUser, *oauth2.Token, error)
+	GetAuthURL(req AuthRequest) string
+	ExchangeCode(ctx context.Context, code string, req AuthRequest) (*ProviderUser, *oauth2.Token, error)
 	Name() string
+	Info() ProviderInfo
+}
+
+// ProviderInfo is the public description of a configured provider, used by
+// the frontend to render its login buttons.
+type ProviderInfo struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	DisplayName string `json:"display_name"`
+}
+
+// defaultDisplayNames label the login buttons of providers without a
+// configured display_name.
+var defaultDisplayNames = map[string]string{
+	"google":    "Google",
+	"github":    "GitHub",
+	"gitlab":    "GitLab",
+	"microsoft": "Microsoft",
+	"bitbucket": "Bitbucket",
+	"oidc":      "Single sign-on",
+}
+
+// NewProvider creates the provider described by cfg. Callbacks are expected
+// at baseURL + "/api/v1/auth/oauth/<name>/callback". Only generic OIDC
+// providers make network requests here, to read the discovery document.
+func NewProvider(ctx context.Context, cfg config.OAuthProviderConfig, baseURL string) (Provider, error) {
+	switch cfg.Type {
+	case "google":
+		return NewGoogleProvider(cfg, baseURL), nil
+	case "github":
+		return NewGitHubProvider(cfg, baseURL), nil
+	case "gitlab":
+		return NewGitLabProvider(cfg, baseURL), nil
+	case "microsoft":
+		return NewMicrosoftProvider(cfg, baseURL), nil
+	case "bitbucket":
+		return NewBitbucketProvider(cfg, baseURL), nil
+	case "oidc":
+		return NewOIDCProvider(ctx, cfg, baseURL)
+	default:
+		return nil, fmt.Errorf("unsupported oauth provider type %q", cfg.Type)
+	}
+}
+
+// providerBase holds the configured identity shared by all providers.
+type providerBase struct {
+	info ProviderInfo
+}
+
+func newProviderBase(cfg config.OAuthProviderConfig) providerBase {
+	info := ProviderInfo{Name: cfg.Name, Type: cfg.Type, DisplayName: cfg.DisplayName}
+	if info.Name == "" {
+		info.Name = cfg.Type
+	}
+	if info.DisplayName == "" {
+		info.DisplayName = defaultDisplayNames[cfg.Type]
+	}
+	return providerBase{info: info}
+}
+
+func (b providerBase) Name() string       { return b.info.Name }
+func (b providerBase) Info() ProviderInfo { return b.info }
+
+// oauth2Config builds the client configuration of a provider, preferring the
+// configured scopes over the type's defaults.
+func oauth2Config(cfg config.OAuthProviderConfig, base providerBase, baseURL string, endpoint oauth2.Endpoint, defaultScopes []string) *oauth2.Config {
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = defaultScopes
+	}
+	return &oauth2.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  baseURL + "/api/v1/auth/oauth/" + base.Name() + "/callback",
+		Scopes:       scopes,
+		Endpoint:     endpoint,
+	}
+}
+
+// authCodeURL builds the authorization URL with the PKCE challenge and, when
+// an ID token is requested, the nonce.
+func authCodeURL(cfg *oauth2.Config, req AuthRequest, opts ...oauth2.AuthCodeOption) string {
+	opts = append(opts, oauth2.S256ChallengeOption(req.CodeVerifier))
+	if requestsIDToken(cfg) {
+		opts = append(opts, oauth2.SetAuthURLParam("nonce", req.Nonce))
+	}
+	return cfg.AuthCodeURL(req.State, opts...)
+}
+
+// exchangeCode redeems the code with the PKCE verifier and checks the ID
+// token, if one was requested, against the nonce and issuer. It returns the
+// ID token's subject, or "" when no ID token was requested.
+func exchangeCode(ctx context.Context, cfg *oauth2.Config, issuer idTokenIssuer, code string, req AuthRequest) (*oauth2.Token, string, error) {
+	token, err := cfg.Exchange(ctx, code, oauth2.VerifierOption(req.CodeVerifier))
+	if err != nil {
+		return nil, "", err
+	}
+	if !requestsIDToken(cfg) {
+		return token, "", nil
+	}
+	sub, err := checkIDToken(token, cfg.ClientID, req.Nonce, issuer)
+	if err != nil {
+		return nil, "", err
+	}
+	return token, sub, nil
+}
+
+func requestsIDToken(cfg *oauth2.Config) bool {
+	return slices.Contains(cfg.Scopes, "openid")
+}
+
+// idTokenIssuer reports whether iss may issue the provider's ID tokens. The
+// claims are passed for multi-tenant issuers whose "iss" names the tenant.
+type idTokenIssuer func(iss string, claims jwt.MapClaims) bool
+
+// exactIssuer accepts ID tokens from the listed issuers only.
+func exactIssuer(issuers ...string) idTokenIssuer {
+	return func(iss string, _ jwt.MapClaims) bool {
+		for _, want := range issuers {
+			if want != "" && strings.TrimSuffix(iss, "/") == strings.TrimSuffix(want, "/") {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// checkIDToken verifies the issuer, audience, expiry and nonce of the ID token
+// in a token response and returns its subject. The token came straight from
+// the token endpoint over TLS, which OpenID Connect Core (3.1.3.7) accepts in
+// place of a signature check. Providers without an issuer accept no ID token.
+func checkIDToken(token *oauth2.Token, clientID, nonce string, issuer idTokenIssuer) (string, error) {
+	raw, _ := token.Extra("id_token").(string)
+	if raw == "" {
+		return "", fmt.Errorf("token response has no id_token")
+	}
+
+	var claims jwt.MapClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(raw, &claims); err != nil {
+		return "", fmt.Errorf("id_token is malformed: %w", err)
+	}
+	iss, _ := claims.GetIssuer()
+	if issuer == nil || !issuer(iss, claims) {
+		return "", fmt.Errorf("id_token was issued by an unexpected issuer %q", iss)
+	}
+	aud, _ := claims.GetAudience()
+	if !slices.Contains(aud, clientID) {
+		return "", fmt.Errorf("id_token was issued to another client")
+	}
+	if exp, _ := claims.GetExpirationTime(); exp == nil || time.Now().After(exp.Time) {
+		return "", fmt.Errorf("id_token has expired")
+	}
+	got, _ := claims["nonce"].(string)
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(got), []byte(nonce)) != 1 {
+		return "", fmt.Errorf("id_token nonce does not match")
+	}
+	sub, _ := claims.GetSubject()
+	if sub == "" {
+		return "", fmt.Errorf("id_token has no subject")
+	}
+	return sub, nil
+}
+
+// checkSubject rejects a profile that belongs to another user than the ID
+// token, as OpenID Connect Core (5.3.2) requires for userinfo responses.
+func checkSubject(idTokenSub, profileSub string) error {
+	if idTokenSub != "" && profileSub != idTokenSub {
+		return fmt.Errorf("userinfo subject does not match the id_token")
+	}
+	return nil
+}
+
+// getJSON fetches url with the token-bearing client and decodes the JSON
+// response into out.
+func getJSON(client *http.Client, url string, out interface{}) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP %d", url, resp.StatusCode)
+	}
+	return json.Unmarshal(body, out)
 }
 
 // --- Google Provider ---
 
 type googleProvider struct {
+	providerBase
 	config *oauth2.Config
 }
 
 // NewGoogleProvider creates a Google OAuth provider.
 func NewGoogleProvider(cfg config.OAuthProviderConfig, baseURL string) Provider {
+	cfg.Type = "google"
+	base := newProviderBase(cfg)
 	return &googleProvider{
-		config: &oauth2.Config{
-			ClientID:     cfg.ClientID,
-			ClientSecret: cfg.ClientSecret,
-			RedirectURL:  baseURL + "/api/v1/auth/oauth/google/callback",
-			Scopes:       []string{"openid", "email", "profile"},
-			Endpoint:     googleOAuth.Endpoint,
-		},
+		providerBase: base,
+		config:       oauth2Config(cfg, base, baseURL, googleOAuth.Endpoint, []string{"openid", "email", "profile"}),
 	}
 }
 
-func (g *googleProvider) Name() string { return "google" }
+// googleIssuer accepts both forms of Google's issuer, as its documentation asks.
+var googleIssuer = exactIssuer("https://accounts.google.com", "accounts.google.com")
 
-func (g *googleProvider) GetAuthURL(state string) string {
-	return g.config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.SetAuthURLParam("prompt", "consent"))
+func (g *googleProvider) GetAuthURL(req AuthRequest) string {
+	return authCodeURL(g.config, req, oauth2.AccessTypeOffline, oauth2.SetAuthURLParam("prompt", "consent"))
 }
 
-func (g *googleProvider) ExchangeCode(ctx context.Context, code string) (*ProviderUser, *oauth2.Token, error) {
-	token, err := g.config.Exchange(ctx, code)
+func (g *googleProvider) ExchangeCode(ctx context.Context, code string, req AuthRequest) (*ProviderUser, *oauth2.Token, error) {
+	token, sub, err := exchangeCode(ctx, g.config, googleIssuer, code, req)
 	if err != nil {
 		return nil, nil, fmt.Errorf("google code exchange failed: %w", err)
 	}
@@ -78,42 +280,43 @@ func (g *googleProvider) ExchangeCode(ctx context.Context, code string) (*Provid
 	if err := json.Unmarshal(body, &info); err != nil {
 		return nil, nil, fmt.Errorf("google userinfo parse failed: %w", err)
 	}
+	if err := checkSubject(sub, info.ID); err != nil {
+		return nil, nil, fmt.Errorf("google userinfo request failed: %w", err)
+	}
 
 	return &ProviderUser{
-		ID:        info.ID,
-		Email:     info.Email,
-		Name:      info.Name,
-		AvatarURL: info.Picture,
+		ID:            info.ID,
+		Email:         info.Email,
+		EmailVerified: info.Verified,
+		Name:          info.Name,
+		AvatarURL:     info.Picture,
 	}, token, nil
 }
 
 // --- GitHub Provider ---
 
 type githubProvider struct {
+	providerBase
 	config *oauth2.Config
 }
 
 // NewGitHubProvider creates a GitHub OAuth provider.
 func NewGitHubProvider(cfg config.OAuthProviderConfig, baseURL string) Provider {
+	cfg.Type = "github"
+	base := newProviderBase(cfg)
 	return &githubProvider{
-		config: &oauth2.Config{
-			ClientID:     cfg.ClientID,
-			ClientSecret: cfg.ClientSecret,
-			RedirectURL:  baseURL + "/api/v1/auth/oauth/github/callback",
-			Scopes:       []string{"user:email", "read:user"},
-			Endpoint:     github.Endpoint,
-		},
+		providerBase: base,
+		config:       oauth2Config(cfg, base, baseURL, github.Endpoint, []string{"user:email", "read:user"}),
 	}
 }
 
-func (g *githubProvider) Name() string { return "github" }
-
-func (g *githubProvider) GetAuthURL(state string) string {
-	return g.config.AuthCodeURL(state)
+func (g *githubProvider) GetAuthURL(req AuthRequest) string {
+	return authCodeURL(g.config, req)
 }
 
-func (g *githubProvider) ExchangeCode(ctx context.Context, code string) (*ProviderUser, *oauth2.Token, error) {
-	token, err := g.config.Exchange(ctx, code)
+func (g *githubProvider) ExchangeCode(ctx context.Context, code string, req AuthRequest) (*ProviderUser, *oauth2.Token, error) {
+	// GitHub does not issue ID tokens
+	token, _, err := exchangeCode(ctx, g.config, nil, code, req)
 	if err != nil {
 		return nil, nil, fmt.Errorf("github code exchange failed: %w", err)
 	}
@@ -139,10 +342,11 @@ func (g *githubProvider) ExchangeCode(ctx context.Context, code string) (*Provid
 		return nil, nil, fmt.Errorf("github user parse failed: %w", err)
 	}
 
-	// If email is private, fetch from /user/emails
-	email := user.Email
-	if email == "" {
-		email, _ = g.fetchPrimaryEmail(ctx, client)
+	// Prefer the primary verified address from /user/emails; the profile
+	// email may be private (empty) and carries no verification status
+	email, verified := user.Email, false
+	if primary, err := g.fetchPrimaryEmail(ctx, client); err == nil {
+		email, verified = primary, true
 	}
 
 	name := user.Name
@@ -151,10 +355,11 @@ func (g *githubProvider) ExchangeCode(ctx context.Context, code string) (*Provid
 	}
 
 	return &ProviderUser{
-		ID:        fmt.Sprintf("%d", user.ID),
-		Email:     email,
-		Name:      name,
-		AvatarURL: user.AvatarURL,
+		ID:            fmt.Sprintf("%d", user.ID),
+		Email:         email,
+		EmailVerified: verified,
+		Name:          name,
+		AvatarURL:     user.AvatarURL,
 	}, token, nil
 }
 
@@ -0,0 +1,94 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"paas-core/apps/api/internal/model"
+)
+
+const reauthStateExpiry = 5 * time.Minute
+
+// Sentinel errors for re-authentication
+var (
+	ErrInvalidReauthState = errors.New("invalid or expired re-authentication state")
+	ErrReauthMismatch     = errors.New("provider account is not linked to the signed-in user")
+)
+
+// CreateReauthState starts a re-authentication round trip for the session
+// and returns the raw state, which the handler seals into the state sent to
+// the provider. The user must already have the provider linked.
+func (s *OAuthService) CreateReauthState(ctx context.Context, userID, sessionID uuid.UUID, provider string) (string, error) {
+	var linked int64
+	if err := s.db.WithContext(ctx).Model(&model.OAuthAccount{}).
+		Where("user_id = ? AND provider = ?", userID, provider).
+		Count(&linked).Error; err != nil {
+		return "", err
+	}
+	if linked == 0 {
+		return "", ErrAccountNotLinked
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate state: %w", err)
+	}
+	state := base64.RawURLEncoding.EncodeToString(buf)
+
+	record := &model.OAuthReauthState{
+		UserID:    userID,
+		SessionID: sessionID,
+		Provider:  provider,
+		StateHash: hashState(state),
+		ExpiresAt: time.Now().Add(reauthStateExpiry),
+	}
+	if err := s.db.WithContext(ctx).Create(record).Error; err != nil {
+		return "", fmt.Errorf("failed to store state: %w", err)
+	}
+	return state, nil
+}
+
+// ConsumeReauthState redeems a re-authentication state once. The provider
+// user must be the account linked to the user who started the round trip.
+func (s *OAuthService) ConsumeReauthState(ctx context.Context, provider, state string, pu *ProviderUser) (*model.OAuthReauthState, error) {
+	var record model.OAuthReauthState
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("state_hash = ? AND provider = ? AND used_at IS NULL AND expires_at > ?", hashState(state), provider, time.Now()).
+			First(&record).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidReauthState
+			}
+			return err
+		}
+		return tx.Model(&record).Update("used_at", time.Now()).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var account model.OAuthAccount
+	err = s.db.WithContext(ctx).Where("provider = ? AND provider_id = ?", provider, pu.ID).First(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && account.UserID != record.UserID) {
+		return nil, ErrReauthMismatch
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func hashState(state string) string {
+	h := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(h[:])
+}
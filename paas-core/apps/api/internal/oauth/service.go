@@ -7,21 +7,26 @@ import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"paas-core/apps/api/internal/alert"
 	"paas-core/apps/api/internal/model"
 )
 
 // OAuthService handles user lookup/creation and account linking for OAuth flows.
 type OAuthService struct {
-	db *gorm.DB
+	db     *gorm.DB
+	alerts *alert.Service
 }
 
 // NewOAuthService creates a new OAuth service.
-func NewOAuthService(db *gorm.DB) *OAuthService {
-	return &OAuthService{db: db}
+func NewOAuthService(db *gorm.DB, alerts *alert.Service) *OAuthService {
+	return &OAuthService{db: db, alerts: alerts}
 }
 
 // FindOrCreateUser finds an existing user by OAuth link or email, or creates a new one.
 // Returns the user, their roles, and whether the account is newly created.
+// Returns ErrUnverifiedEmail when the email belongs to an existing user but the
+// provider has not verified it, and ErrUnverifiedAccount when the existing user
+// has not verified it.
 func (s *OAuthService) FindOrCreateUser(ctx context.Context, provider string, pu *ProviderUser) (*model.User, []string, bool, error) {
 	// 1. Check if an OAuth account already exists for this provider + provider ID
 	var oauthAccount model.OAuthAccount
@@ -39,10 +44,20 @@ func (s *OAuthService) FindOrCreateUser(ctx context.Context, provider string, pu
 		return &user, roles, false, nil
 	}
 
-	// 2. Check if a user with this email already exists → auto-link
+	// 2. Check if a user with this email already exists → auto-link, but only
+	// when the provider vouches for the address; otherwise anyone could claim
+	// an account by setting its email on their provider profile
 	if pu.Email != "" {
 		var existingUser model.User
 		err := s.db.Preload("Roles").Where("email = ?", pu.Email).First(&existingUser).Error
+		if err == nil && !pu.EmailVerified {
+			return nil, nil, false, ErrUnverifiedEmail
+		}
+		// Whoever registered an unverified address may not own it, and their
+		// password would keep working next to the provider login
+		if err == nil && !existingUser.EmailVerified {
+			return nil, nil, false, ErrUnverifiedAccount
+		}
 		if err == nil {
 			// Auto-link the OAuth account to the existing user
 			link := model.OAuthAccount{
@@ -55,11 +70,6 @@ func (s *OAuthService) FindOrCreateUser(ctx context.Context, provider string, pu
 			if err := s.db.Create(&link).Error; err != nil {
 				return nil, nil, false, err
 			}
-			// Auto-verify email since OAuth provider already verified it
-			if !existingUser.EmailVerified {
-				s.db.Model(&existingUser).Update("email_verified", true)
-				existingUser.EmailVerified = true
-			}
 			// Update avatar if empty
 			if existingUser.AvatarURL == "" && pu.AvatarURL != "" {
 				s.db.Model(&existingUser).Update("avatar_url", pu.AvatarURL)
@@ -70,6 +80,7 @@ func (s *OAuthService) FindOrCreateUser(ctx context.Context, provider string, pu
 				roles[i] = r.Name
 			}
 			slog.Info("OAuth account auto-linked to existing user", "provider", provider, "email", pu.Email)
+			s.alerts.OAuthLinked(ctx, existingUser.ID, provider)
 			return &existingUser, roles, false, nil
 		}
 	}
@@ -80,7 +91,7 @@ func (s *OAuthService) FindOrCreateUser(ctx context.Context, provider string, pu
 		Email:         pu.Email,
 		PasswordHash:  "", // OAuth-only user, no password
 		AvatarURL:     pu.AvatarURL,
-		EmailVerified: true, // OAuth provider already verified the email
+		EmailVerified: pu.EmailVerified,
 	}
 	if err := s.db.Create(&newUser).Error; err != nil {
 		return nil, nil, false, err
@@ -135,13 +146,11 @@ func (s *OAuthService) UnlinkAccount(ctx context.Context, userID uuid.UUID, prov
 		return err
 	}
 
-	var linkCount int64
+	var linkCount, passkeyCount int64
 	s.db.Model(&model.OAuthAccount{}).Where("user_id = ?", userID).Count(&linkCount)
+	s.db.Model(&model.WebAuthnCredential{}).Where("user_id = ?", userID).Count(&passkeyCount)
 
-	// A valid bcrypt hash is always 60 characters long
-	hasPassword := len(user.PasswordHash) >= 60
-
-	if linkCount <= 1 && !hasPassword {
+	if linkCount <= 1 && passkeyCount == 0 && !user.HasPassword() {
 		return ErrLastAuthMethod
 	}
 
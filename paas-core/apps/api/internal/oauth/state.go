@@ -0,0 +1,170 @@
+package oauth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	// stateCookie binds a login round trip to the browser that started it.
+	stateCookie     = "oauth_state"
+	stateCookiePath = "/api/v1/auth/oauth"
+	stateExpiry     = 10 * time.Minute
+)
+
+// ErrInvalidState is returned for state values that were tampered with, have
+// expired or belong to another provider.
+var ErrInvalidState = errors.New("invalid or expired oauth state")
+
+// flowState is everything the callback needs to finish a round trip. It is
+// sealed into the state parameter, so no server-side storage is needed; the
+// code verifier stays confidential because the state is encrypted, not just
+// signed.
+type flowState struct {
+	Provider     string `json:"p"`
+	CodeVerifier string `json:"v"`
+	Nonce        string `json:"n"`
+	RedirectTo   string `json:"r,omitempty"`  // validated path on the frontend
+	Binding      string `json:"b,omitempty"`  // value of the state cookie (login only)
+	ReauthState  string `json:"ra,omitempty"` // raw re-authentication state (reauth only)
+	ExpiresAt    int64  `json:"e"`
+}
+
+// authRequest returns the values bound into the authorization request.
+func (s *flowState) authRequest(state string) AuthRequest {
+	return AuthRequest{State: state, CodeVerifier: s.CodeVerifier, Nonce: s.Nonce}
+}
+
+// stateSealer encrypts and authenticates flow states with AES-256-GCM.
+type stateSealer struct {
+	aead cipher.AEAD
+}
+
+// newStateSealer derives the sealing key from secret. An empty secret gets a
+// random key, which only works while a single API instance is running and
+// invalidates pending logins on restart.
+func newStateSealer(secret string) *stateSealer {
+	var key [32]byte
+	if secret == "" {
+		_, _ = rand.Read(key[:])
+	} else {
+		key = sha256.Sum256([]byte("oauth-state:" + secret))
+	}
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		panic(err) // unreachable with a 32-byte key
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return &stateSealer{aead: aead}
+}
+
+// newFlowState creates a state for provider with a fresh PKCE verifier and
+// nonce, valid for stateExpiry.
+func newFlowState(provider string) (*flowState, error) {
+	nonce, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	return &flowState{
+		Provider:     provider,
+		CodeVerifier: oauth2.GenerateVerifier(),
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(stateExpiry).Unix(),
+	}, nil
+}
+
+// seal encodes st as an opaque, URL-safe state parameter.
+func (s *stateSealer) seal(st *flowState) (string, error) {
+	plaintext, err := json.Marshal(st)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate state: %w", err)
+	}
+	sealed := s.aead.Seal(nonce, nonce, plaintext, nil)
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// open decodes a state parameter sealed for provider and checks its expiry.
+func (s *stateSealer) open(raw, provider string) (*flowState, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil || len(sealed) < s.aead.NonceSize() {
+		return nil, ErrInvalidState
+	}
+	nonceSize := s.aead.NonceSize()
+	plaintext, err := s.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return nil, ErrInvalidState
+	}
+
+	var st flowState
+	if err := json.Unmarshal(plaintext, &st); err != nil {
+		return nil, ErrInvalidState
+	}
+	if st.Provider != provider || time.Now().Unix() > st.ExpiresAt {
+		return nil, ErrInvalidState
+	}
+	return &st, nil
+}
+
+// sanitizeRedirect checks that redirectTo points into the frontend and
+// returns it as a path, so the frontend can never be made to navigate to
+// another site after login. Empty input yields an empty path.
+func sanitizeRedirect(frontendURL, redirectTo string) (string, error) {
+	if redirectTo == "" {
+		return "", nil
+	}
+	// Browsers treat backslashes as slashes, which would turn "/\evil.com"
+	// into a protocol-relative URL
+	if strings.Contains(redirectTo, "\\") {
+		return "", fmt.Errorf("redirect_to must point to the frontend")
+	}
+
+	target, err := url.Parse(redirectTo)
+	if err != nil {
+		return "", fmt.Errorf("redirect_to is not a valid URL")
+	}
+	if target.Scheme != "" || target.Host != "" {
+		frontend, err := url.Parse(frontendURL)
+		if err != nil || frontend.Host == "" ||
+			!strings.EqualFold(target.Scheme, frontend.Scheme) || !strings.EqualFold(target.Host, frontend.Host) {
+			return "", fmt.Errorf("redirect_to must point to the frontend")
+		}
+	}
+	if target.User != nil || !strings.HasPrefix(target.Path, "/") || strings.HasPrefix(target.Path, "//") {
+		return "", fmt.Errorf("redirect_to must point to the frontend")
+	}
+
+	path := target.EscapedPath()
+	if target.RawQuery != "" {
+		path += "?" + target.RawQuery
+	}
+	if target.Fragment != "" {
+		path += "#" + target.EscapedFragment()
+	}
+	return path, nil
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -172,7 +172,8 @@ func (h *Handler) UpdateMemberRole(c *gin.Context) {
 		return
 	}
 
-	member, err := h.orgService.UpdateMemberRole(c.Request.Context(), memberID, req)
+	orgID := c.MustGet("org_id").(uuid.UUID)
+	member, err := h.orgService.UpdateMemberRole(c.Request.Context(), orgID, memberID, req)
 	if err != nil {
 		_ = c.Error(err)
 		return
@@ -197,7 +198,8 @@ func (h *Handler) RemoveMember(c *gin.Context) {
 		return
 	}
 
-	if err := h.orgService.RemoveMember(c.Request.Context(), memberID); err != nil {
+	orgID := c.MustGet("org_id").(uuid.UUID)
+	if err := h.orgService.RemoveMember(c.Request.Context(), orgID, memberID); err != nil {
 		_ = c.Error(err)
 		return
 	}
@@ -22,6 +22,7 @@ type Repository interface {
 	// Memberships
 	CreateMembership(ctx context.Context, m *model.Membership) error
 	FindMembership(ctx context.Context, orgID, userID uuid.UUID) (*model.Membership, error)
+	FindMember(ctx context.Context, orgID, membershipID uuid.UUID) (*model.Membership, error)
 	UpdateMembership(ctx context.Context, m *model.Membership) error
 	DeleteMembership(ctx context.Context, id uuid.UUID) error
 	ListMembers(ctx context.Context, orgID uuid.UUID) ([]model.Membership, error)
@@ -115,6 +116,20 @@ func (r *repository) FindMembership(ctx context.Context, orgID, userID uuid.UUID
 	return &m, err
 }
 
+// FindMember returns a membership of a human member of the org by its ID, or
+// nil. Service account memberships are managed through their own API.
+func (r *repository) FindMember(ctx context.Context, orgID, membershipID uuid.UUID) (*model.Membership, error) {
+	var m model.Membership
+	err := r.getDB(ctx).WithContext(ctx).
+		Scopes(model.HumanMembers).
+		Where("memberships.id = ? AND memberships.org_id = ?", membershipID, orgID).
+		First(&m).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &m, err
+}
+
 func (r *repository) UpdateMembership(ctx context.Context, m *model.Membership) error {
 	return r.getDB(ctx).WithContext(ctx).Save(m).Error
 }
@@ -127,7 +142,8 @@ func (r *repository) ListMembers(ctx context.Context, orgID uuid.UUID) ([]model.
 	var members []model.Membership
 	err := r.getDB(ctx).WithContext(ctx).
 		Preload("User").
-		Where("org_id = ?", orgID).
+		Scopes(model.HumanMembers). // service accounts are listed separately
+		Where("memberships.org_id = ?", orgID).
 		Order("joined_at ASC").
 		Find(&members).Error
 	return members, err
@@ -23,8 +23,8 @@ type Service interface {
 
 	// Members
 	ListMembers(ctx context.Context, orgID uuid.UUID) ([]MemberResponse, error)
-	UpdateMemberRole(ctx context.Context, membershipID uuid.UUID, req UpdateMemberRoleRequest) (*MemberResponse, error)
-	RemoveMember(ctx context.Context, membershipID uuid.UUID) error
+	UpdateMemberRole(ctx context.Context, orgID, membershipID uuid.UUID, req UpdateMemberRoleRequest) (*MemberResponse, error)
+	RemoveMember(ctx context.Context, orgID, membershipID uuid.UUID) error
 
 	// Invites
 	InviteMember(ctx context.Context, orgID, invitedBy uuid.UUID, req InviteMemberRequest) (*InviteResponse, error)
@@ -145,19 +145,31 @@ func (s *service) ListMembers(ctx context.Context, orgID uuid.UUID) ([]MemberRes
 	return responses, nil
 }
 
-func (s *service) UpdateMemberRole(ctx context.Context, membershipID uuid.UUID, req UpdateMemberRoleRequest) (*MemberResponse, error) {
-	var m model.Membership
-	m.ID = membershipID
+func (s *service) UpdateMemberRole(ctx context.Context, orgID, membershipID uuid.UUID, req UpdateMemberRoleRequest) (*MemberResponse, error) {
+	m, err := s.repo.FindMember(ctx, orgID, membershipID)
+	if err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+	if m == nil {
+		return nil, apiErrors.NotFound("Member not found")
+	}
 	m.Role = req.Role
 
-	if err := s.repo.UpdateMembership(ctx, &m); err != nil {
+	if err := s.repo.UpdateMembership(ctx, m); err != nil {
 		return nil, apiErrors.InternalServerError(err)
 	}
 	return &MemberResponse{ID: m.ID, Role: m.Role}, nil
 }
 
-func (s *service) RemoveMember(ctx context.Context, membershipID uuid.UUID) error {
-	return s.repo.DeleteMembership(ctx, membershipID)
+func (s *service) RemoveMember(ctx context.Context, orgID, membershipID uuid.UUID) error {
+	m, err := s.repo.FindMember(ctx, orgID, membershipID)
+	if err != nil {
+		return apiErrors.InternalServerError(err)
+	}
+	if m == nil {
+		return apiErrors.NotFound("Member not found")
+	}
+	return s.repo.DeleteMembership(ctx, m.ID)
 }
 
 // --- Invites ---
@@ -0,0 +1,37 @@
+package passkey
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BeginResponse is returned by the begin step of a ceremony. Options is passed
+// to navigator.credentials.create() or navigator.credentials.get() as-is.
+type BeginResponse struct {
+	SessionID uuid.UUID   `json:"session_id"`
+	Options   interface{} `json:"options"`
+}
+
+// FinishRegistrationRequest is the DTO for completing passkey registration.
+type FinishRegistrationRequest struct {
+	SessionID  uuid.UUID       `json:"session_id" binding:"required"`
+	Name       string          `json:"name" binding:"max=100"`
+	Credential json.RawMessage `json:"credential" binding:"required"`
+}
+
+// FinishLoginRequest is the DTO for completing passkey login.
+type FinishLoginRequest struct {
+	SessionID  uuid.UUID       `json:"session_id" binding:"required"`
+	Credential json.RawMessage `json:"credential" binding:"required"`
+}
+
+// CredentialResponse is the public DTO for a registered passkey.
+type CredentialResponse struct {
+	ID             uuid.UUID  `json:"id"`
+	Name           string     `json:"name"`
+	BackupEligible bool       `json:"backup_eligible"` // synced passkey
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
@@ -0,0 +1,196 @@
+package passkey
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"paas-core/apps/api/internal/auth"
+	apiErrors "paas-core/apps/api/internal/errors"
+)
+
+// Handler handles passkey registration, login and management.
+type Handler struct {
+	service     *Service
+	authService auth.Service
+	transport   *auth.SessionTransport
+}
+
+// NewHandler creates a new passkey handler.
+func NewHandler(service *Service, authService auth.Service, transport *auth.SessionTransport) *Handler {
+	return &Handler{service: service, authService: authService, transport: transport}
+}
+
+// BeginRegistration godoc
+// @Summary Start passkey registration
+// @Description Returns WebAuthn creation options for navigator.credentials.create()
+// @Tags passkeys
+// @Security BearerAuth
+// @Success 200 {object} errors.Response{data=BeginResponse}
+// @Router /api/v1/users/me/passkeys/register/begin [post]
+func (h *Handler) BeginRegistration(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.BeginRegistration(c.Request.Context(), userID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(resp))
+}
+
+// FinishRegistration godoc
+// @Summary Finish passkey registration
+// @Tags passkeys
+// @Security BearerAuth
+// @Param request body FinishRegistrationRequest true "Authenticator response"
+// @Success 201 {object} errors.Response{data=CredentialResponse}
+// @Failure 400 {object} errors.Response "Registration could not be verified"
+// @Router /api/v1/users/me/passkeys/register/finish [post]
+func (h *Handler) FinishRegistration(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req FinishRegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	cred, err := h.service.FinishRegistration(c.Request.Context(), userID, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, apiErrors.Success(cred))
+}
+
+// ListCredentials godoc
+// @Summary List passkeys
+// @Tags passkeys
+// @Security BearerAuth
+// @Success 200 {object} errors.Response{data=[]CredentialResponse}
+// @Router /api/v1/users/me/passkeys [get]
+func (h *Handler) ListCredentials(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	creds, err := h.service.ListCredentials(c.Request.Context(), userID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(creds))
+}
+
+// DeleteCredential godoc
+// @Summary Revoke a passkey
+// @Tags passkeys
+// @Security BearerAuth
+// @Param id path string true "Passkey ID"
+// @Success 200 {object} errors.Response "Passkey revoked"
+// @Failure 400 {object} errors.Response "Last authentication method"
+// @Failure 404 {object} errors.Response "Passkey not found"
+// @Router /api/v1/users/me/passkeys/{id} [delete]
+func (h *Handler) DeleteCredential(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		_ = c.Error(apiErrors.BadRequest("Invalid passkey ID"))
+		return
+	}
+
+	if err := h.service.DeleteCredential(c.Request.Context(), userID, id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(gin.H{"message": "Passkey revoked"}))
+}
+
+// BeginLogin godoc
+// @Summary Start passkey login
+// @Description Returns WebAuthn request options for navigator.credentials.get(); the authenticator selects the account
+// @Tags auth
+// @Produce json
+// @Success 200 {object} errors.Response{data=BeginResponse}
+// @Router /api/v1/auth/passkey/begin [post]
+func (h *Handler) BeginLogin(c *gin.Context) {
+	resp, err := h.service.BeginLogin(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(resp))
+}
+
+// FinishLogin godoc
+// @Summary Finish passkey login
+// @Description Verifies the passkey assertion and returns access and refresh tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body FinishLoginRequest true "Authenticator response"
+// @Success 200 {object} errors.Response{data=auth.AuthResponse} "Success"
+// @Failure 401 {object} errors.Response "Passkey could not be verified"
+// @Router /api/v1/auth/passkey/finish [post]
+func (h *Handler) FinishLogin(c *gin.Context) {
+	var req FinishLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	// Passkeys require user verification, so they satisfy MFA on their own.
+	user, err := h.service.FinishLogin(ctx, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	roles := make([]string, len(user.Roles))
+	for i, r := range user.Roles {
+		roles[i] = r.Name
+	}
+
+	tokenPair, err := h.authService.GenerateTokenPair(ctx, user.ID, user.Email, user.Name, roles, []string{auth.AMRPasskey})
+	if err != nil {
+		if errors.Is(err, auth.ErrSuspended) {
+			_ = c.Error(apiErrors.Forbidden("Your account is suspended"))
+			return
+		}
+		_ = c.Error(apiErrors.InternalServerError(fmt.Errorf("failed to generate tokens: %w", err)))
+		return
+	}
+
+	h.transport.WriteAuthResponse(c, auth.NewAuthResponse(tokenPair, user, roles))
+}
+
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		_ = c.Error(apiErrors.Unauthorized(""))
+		return uuid.Nil, false
+	}
+	return userIDVal.(uuid.UUID), true
+}
@@ -0,0 +1,316 @@
+package passkey
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"paas-core/apps/api/internal/alert"
+	"paas-core/apps/api/internal/config"
+	apiErrors "paas-core/apps/api/internal/errors"
+	"paas-core/apps/api/internal/model"
+)
+
+const (
+	sessionExpiry        = 5 * time.Minute
+	ceremonyRegistration = "registration"
+	ceremonyLogin        = "login"
+)
+
+// Service runs WebAuthn registration and login ceremonies and manages the
+// stored passkeys.
+type Service struct {
+	db       *gorm.DB
+	webauthn *webauthn.WebAuthn
+	alerts   *alert.Service
+}
+
+// NewService creates a new passkey service for the configured relying party.
+func NewService(db *gorm.DB, cfg config.WebAuthnConfig, alerts *alert.Service) (*Service, error) {
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: cfg.RPDisplayName,
+		RPOrigins:     cfg.RPOrigins,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid webauthn config: %w", err)
+	}
+	return &Service{db: db, webauthn: w, alerts: alerts}, nil
+}
+
+// BeginRegistration starts registering a new passkey for a logged-in user.
+func (s *Service) BeginRegistration(ctx context.Context, userID uuid.UUID) (*BeginResponse, error) {
+	user, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, len(user.credentials))
+	for i, cred := range user.credentials {
+		exclusions[i] = cred.Descriptor()
+	}
+
+	options, session, err := s.webauthn.BeginRegistration(user,
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithExclusions(exclusions),
+	)
+	if err != nil {
+		return nil, apiErrors.InternalServerError(fmt.Errorf("failed to begin registration: %w", err))
+	}
+
+	sessionID, err := s.saveSession(ctx, &userID, ceremonyRegistration, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BeginResponse{SessionID: sessionID, Options: options}, nil
+}
+
+// FinishRegistration verifies the authenticator's attestation and stores the
+// new passkey.
+func (s *Service) FinishRegistration(ctx context.Context, userID uuid.UUID, req FinishRegistrationRequest) (*CredentialResponse, error) {
+	session, err := s.consumeSession(ctx, req.SessionID, ceremonyRegistration, &userID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(req.Credential)
+	if err != nil {
+		return nil, apiErrors.BadRequest("Invalid passkey registration response")
+	}
+
+	user, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	cred, err := s.webauthn.CreateCredential(user, *session, parsed)
+	if err != nil {
+		return nil, apiErrors.BadRequest("Passkey registration could not be verified")
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = "Passkey"
+	}
+
+	transports := make([]string, len(cred.Transport))
+	for i, t := range cred.Transport {
+		transports[i] = string(t)
+	}
+
+	record := &model.WebAuthnCredential{
+		UserID:          userID,
+		CredentialID:    cred.ID,
+		PublicKey:       cred.PublicKey,
+		AttestationType: cred.AttestationType,
+		AAGUID:          cred.Authenticator.AAGUID,
+		SignCount:       cred.Authenticator.SignCount,
+		Transports:      strings.Join(transports, ","),
+		BackupEligible:  cred.Flags.BackupEligible,
+		BackupState:     cred.Flags.BackupState,
+		Name:            name,
+	}
+	if err := s.db.WithContext(ctx).Create(record).Error; err != nil {
+		return nil, apiErrors.InternalServerError(fmt.Errorf("failed to store passkey: %w", err))
+	}
+	s.alerts.MFAChanged(ctx, userID, fmt.Sprintf("A passkey (%s) was added", name))
+
+	resp := toCredentialResponse(record)
+	return &resp, nil
+}
+
+// BeginLogin starts a discoverable-credential login: the authenticator picks
+// the account, so no email is needed.
+func (s *Service) BeginLogin(ctx context.Context) (*BeginResponse, error) {
+	options, session, err := s.webauthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		return nil, apiErrors.InternalServerError(fmt.Errorf("failed to begin login: %w", err))
+	}
+
+	sessionID, err := s.saveSession(ctx, nil, ceremonyLogin, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BeginResponse{SessionID: sessionID, Options: options}, nil
+}
+
+// FinishLogin verifies the assertion and returns the authenticated user with
+// roles loaded.
+func (s *Service) FinishLogin(ctx context.Context, req FinishLoginRequest) (*model.User, error) {
+	session, err := s.consumeSession(ctx, req.SessionID, ceremonyLogin, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		return nil, apiErrors.BadRequest("Invalid passkey login response")
+	}
+
+	found, cred, err := s.webauthn.ValidatePasskeyLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		userID, err := uuid.FromBytes(userHandle)
+		if err != nil {
+			return nil, err
+		}
+		return s.loadUser(ctx, userID)
+	}, *session, parsed)
+	if err != nil {
+		return nil, apiErrors.Unauthorized("Passkey could not be verified")
+	}
+
+	// A sign counter that goes backwards indicates a cloned authenticator.
+	if cred.Authenticator.CloneWarning {
+		return nil, apiErrors.Unauthorized("Passkey could not be verified")
+	}
+
+	now := time.Now()
+	err = s.db.WithContext(ctx).Model(&model.WebAuthnCredential{}).
+		Where("credential_id = ?", cred.ID).
+		Updates(map[string]interface{}{
+			"sign_count":   cred.Authenticator.SignCount,
+			"backup_state": cred.Flags.BackupState,
+			"last_used_at": now,
+		}).Error
+	if err != nil {
+		return nil, apiErrors.InternalServerError(fmt.Errorf("failed to update passkey: %w", err))
+	}
+
+	return found.(*webauthnUser).user, nil
+}
+
+// ListCredentials returns the user's registered passkeys.
+func (s *Service) ListCredentials(ctx context.Context, userID uuid.UUID) ([]CredentialResponse, error) {
+	var records []model.WebAuthnCredential
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&records).Error; err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	result := make([]CredentialResponse, len(records))
+	for i := range records {
+		result[i] = toCredentialResponse(&records[i])
+	}
+	return result, nil
+}
+
+// DeleteCredential revokes a passkey. The last remaining sign-in method
+// cannot be removed.
+func (s *Service) DeleteCredential(ctx context.Context, userID, credentialID uuid.UUID) error {
+	db := s.db.WithContext(ctx)
+
+	var user model.User
+	if err := db.First(&user, "id = ?", userID).Error; err != nil {
+		return apiErrors.InternalServerError(err)
+	}
+
+	var passkeyCount, linkCount int64
+	db.Model(&model.WebAuthnCredential{}).Where("user_id = ?", userID).Count(&passkeyCount)
+	db.Model(&model.OAuthAccount{}).Where("user_id = ?", userID).Count(&linkCount)
+
+	if passkeyCount <= 1 && linkCount == 0 && !user.HasPassword() {
+		return apiErrors.BadRequest("Cannot remove the last authentication method. Please set a password first.")
+	}
+
+	result := db.Where("id = ? AND user_id = ?", credentialID, userID).Delete(&model.WebAuthnCredential{})
+	if result.Error != nil {
+		return apiErrors.InternalServerError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return apiErrors.NotFound("Passkey not found")
+	}
+	s.alerts.MFAChanged(ctx, userID, "A passkey was removed")
+	return nil
+}
+
+// saveSession persists ceremony state until the finish step.
+func (s *Service) saveSession(ctx context.Context, userID *uuid.UUID, ceremony string, session *webauthn.SessionData) (uuid.UUID, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return uuid.Nil, apiErrors.InternalServerError(err)
+	}
+
+	record := &model.WebAuthnSession{
+		UserID:    userID,
+		Ceremony:  ceremony,
+		Data:      string(data),
+		ExpiresAt: time.Now().Add(sessionExpiry),
+	}
+	if err := s.db.WithContext(ctx).Create(record).Error; err != nil {
+		return uuid.Nil, apiErrors.InternalServerError(fmt.Errorf("failed to store webauthn session: %w", err))
+	}
+	return record.ID, nil
+}
+
+// consumeSession loads and deletes ceremony state so each challenge can be
+// answered only once.
+func (s *Service) consumeSession(ctx context.Context, id uuid.UUID, ceremony string, userID *uuid.UUID) (*webauthn.SessionData, error) {
+	var record model.WebAuthnSession
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		q := tx.Where("id = ? AND ceremony = ? AND expires_at > ?", id, ceremony, time.Now())
+		if userID != nil {
+			q = q.Where("user_id = ?", *userID)
+		}
+		if err := q.First(&record).Error; err != nil {
+			return err
+		}
+		res := tx.Delete(&model.WebAuthnSession{}, "id = ?", record.ID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apiErrors.BadRequest("Invalid or expired passkey session")
+		}
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal([]byte(record.Data), &session); err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+	return &session, nil
+}
+
+// loadUser loads a user and their passkeys as a webauthn.User.
+func (s *Service) loadUser(ctx context.Context, userID uuid.UUID) (*webauthnUser, error) {
+	var user model.User
+	if err := s.db.WithContext(ctx).Preload("Roles").First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apiErrors.NotFound("User not found")
+		}
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	var records []model.WebAuthnCredential
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Find(&records).Error; err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	return newWebAuthnUser(&user, records), nil
+}
+
+func toCredentialResponse(c *model.WebAuthnCredential) CredentialResponse {
+	return CredentialResponse{
+		ID:             c.ID,
+		Name:           c.Name,
+		BackupEligible: c.BackupEligible,
+		LastUsedAt:     c.LastUsedAt,
+		CreatedAt:      c.CreatedAt,
+	}
+}
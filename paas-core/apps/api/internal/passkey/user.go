@@ -0,0 +1,57 @@
+package passkey
+
+import (
+	"strings"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+
+	"paas-core/apps/api/internal/model"
+)
+
+// webauthnUser adapts model.User to the webauthn.User interface. The user
+// handle is the raw 16-byte user UUID.
+type webauthnUser struct {
+	user        *model.User
+	credentials []webauthn.Credential
+}
+
+func newWebAuthnUser(user *model.User, records []model.WebAuthnCredential) *webauthnUser {
+	creds := make([]webauthn.Credential, len(records))
+	for i, r := range records {
+		var transports []protocol.AuthenticatorTransport
+		if r.Transports != "" {
+			for _, t := range strings.Split(r.Transports, ",") {
+				transports = append(transports, protocol.AuthenticatorTransport(t))
+			}
+		}
+		creds[i] = webauthn.Credential{
+			ID:              r.CredentialID,
+			PublicKey:       r.PublicKey,
+			AttestationType: r.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				UserPresent:    true,
+				UserVerified:   true,
+				BackupEligible: r.BackupEligible,
+				BackupState:    r.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    r.AAGUID,
+				SignCount: r.SignCount,
+			},
+		}
+	}
+	return &webauthnUser{user: user, credentials: creds}
+}
+
+func (u *webauthnUser) WebAuthnID() []byte {
+	id := u.user.ID
+	return id[:]
+}
+
+func (u *webauthnUser) WebAuthnName() string { return u.user.Email }
+
+func (u *webauthnUser) WebAuthnDisplayName() string { return u.user.Name }
+
+func (u *webauthnUser) WebAuthnCredentials() []webauthn.Credential { return u.credentials }
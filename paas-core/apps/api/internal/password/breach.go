@@ -0,0 +1,161 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// breachFilterMagic starts every breach filter file, followed by the format
+// version.
+const (
+	breachFilterMagic   = "PWBF"
+	breachFilterVersion = 1
+)
+
+// ErrInvalidBreachFilter is returned when a file is not a breach filter.
+var ErrInvalidBreachFilter = errors.New("invalid breach filter file")
+
+// BreachFilter is a bloom filter over the SHA-1 digests of known breached
+// passwords. It can report false positives at the rate it was built for, but
+// never false negatives. A nil filter contains nothing.
+type BreachFilter struct {
+	k    uint32   // hash functions per entry
+	m    uint64   // number of bits
+	bits []uint64 // bit array, m rounded up to whole words
+}
+
+// NewBreachFilter creates an empty filter sized for n entries at the given
+// false positive rate.
+func NewBreachFilter(n uint64, fpRate float64) (*BreachFilter, error) {
+	if n == 0 {
+		return nil, errors.New("breach filter needs at least one entry")
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		return nil, fmt.Errorf("false positive rate must be between 0 and 1, got %v", fpRate)
+	}
+
+	m := uint64(math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	k := uint32(math.Max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+	return &BreachFilter{k: k, m: m, bits: make([]uint64, (m+63)/64)}, nil
+}
+
+// LoadBreachFilter reads a filter written by WriteTo.
+func LoadBreachFilter(path string) (*BreachFilter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breach filter: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var header struct {
+		Magic   [4]byte
+		Version uint32
+		K       uint32
+		M       uint64
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, ErrInvalidBreachFilter
+	}
+	if string(header.Magic[:]) != breachFilterMagic || header.Version != breachFilterVersion || header.K == 0 || header.M == 0 {
+		return nil, ErrInvalidBreachFilter
+	}
+
+	filter := &BreachFilter{k: header.K, m: header.M, bits: make([]uint64, (header.M+63)/64)}
+	if err := binary.Read(r, binary.LittleEndian, filter.bits); err != nil {
+		return nil, fmt.Errorf("%w: truncated bit array", ErrInvalidBreachFilter)
+	}
+	return filter, nil
+}
+
+// WriteTo writes the filter in the format LoadBreachFilter reads.
+func (f *BreachFilter) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	header := struct {
+		Magic   [4]byte
+		Version uint32
+		K       uint32
+		M       uint64
+	}{Version: breachFilterVersion, K: f.k, M: f.m}
+	copy(header.Magic[:], breachFilterMagic)
+
+	if err := binary.Write(bw, binary.LittleEndian, header); err != nil {
+		return 0, err
+	}
+	if err := binary.Write(bw, binary.LittleEndian, f.bits); err != nil {
+		return 0, err
+	}
+	if err := bw.Flush(); err != nil {
+		return 0, err
+	}
+	return int64(binary.Size(header) + 8*len(f.bits)), nil
+}
+
+// Add inserts a plaintext password.
+func (f *BreachFilter) Add(password string) {
+	f.AddDigest(sha1.Sum([]byte(password)))
+}
+
+// AddDigest inserts the SHA-1 digest of a password, as found in hash-only
+// corpora such as the Pwned Passwords download.
+func (f *BreachFilter) AddDigest(digest [sha1.Size]byte) {
+	for _, bit := range f.positions(digest) {
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// Contains reports whether password, or its lowercase form, is in the filter.
+func (f *BreachFilter) Contains(password string) bool {
+	if f == nil {
+		return false
+	}
+	if f.containsDigest(sha1.Sum([]byte(password))) {
+		return true
+	}
+	lower := strings.ToLower(password)
+	return lower != password && f.containsDigest(sha1.Sum([]byte(lower)))
+}
+
+func (f *BreachFilter) containsDigest(digest [sha1.Size]byte) bool {
+	for _, bit := range f.positions(digest) {
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// positions derives the k bit positions of a digest by double hashing on two
+// 64-bit halves of the SHA-1 digest.
+func (f *BreachFilter) positions(digest [sha1.Size]byte) []uint64 {
+	h1 := binary.LittleEndian.Uint64(digest[0:8])
+	h2 := binary.LittleEndian.Uint64(digest[8:16]) | 1
+
+	out := make([]uint64, f.k)
+	for i := range out {
+		out[i] = (h1 + uint64(i)*h2) % f.m
+	}
+	return out
+}
+
+// ParseSHA1Line parses a line of a hash-only corpus: a hex SHA-1 digest,
+// optionally followed by ":count".
+func ParseSHA1Line(line string) ([sha1.Size]byte, error) {
+	var digest [sha1.Size]byte
+	hexDigest, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+	if len(hexDigest) != 2*sha1.Size {
+		return digest, fmt.Errorf("not a SHA-1 digest: %q", line)
+	}
+	if _, err := hex.Decode(digest[:], []byte(hexDigest)); err != nil {
+		return digest, fmt.Errorf("not a SHA-1 digest: %q", line)
+	}
+	return digest, nil
+}
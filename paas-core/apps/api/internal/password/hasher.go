@@ -0,0 +1,179 @@
+// Package password hashes and verifies user passwords. New hashes use the
+// configured algorithm in PHC string format; hashes made with another
+// algorithm or weaker parameters still verify and are reported as needing a
+// rehash so callers can upgrade them on the next successful login.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"paas-core/apps/api/internal/config"
+)
+
+// Supported algorithms.
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+// Defaults follow the OWASP recommendations for argon2id and bcrypt.
+const (
+	defaultArgon2Memory      = 64 * 1024 // KiB
+	defaultArgon2Iterations  = 3
+	defaultArgon2Parallelism = 2
+	defaultBcryptCost        = 12
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+var (
+	// ErrMismatch is returned when the password does not match the hash.
+	ErrMismatch = errors.New("password does not match")
+	// ErrUnknownFormat is returned for hashes no supported algorithm produced.
+	ErrUnknownFormat = errors.New("unknown password hash format")
+)
+
+// Hasher creates and verifies password hashes.
+type Hasher interface {
+	// Hash returns a new hash of password with the configured algorithm.
+	Hash(password string) (string, error)
+	// Verify checks password against hash. needsRehash is true when the
+	// password matched but the hash uses an outdated algorithm or cost.
+	Verify(hash, password string) (needsRehash bool, err error)
+}
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+type hasher struct {
+	algorithm  string
+	argon2     argon2Params
+	bcryptCost int
+}
+
+// NewHasher creates a hasher from config, filling in defaults for unset
+// parameters.
+func NewHasher(cfg config.PasswordConfig) Hasher {
+	h := &hasher{
+		algorithm: cfg.Algorithm,
+		argon2: argon2Params{
+			memory:      cfg.Argon2Memory,
+			iterations:  cfg.Argon2Iterations,
+			parallelism: cfg.Argon2Parallelism,
+		},
+		bcryptCost: cfg.BcryptCost,
+	}
+	if h.algorithm == "" {
+		h.algorithm = AlgorithmArgon2id
+	}
+	if h.argon2.memory == 0 {
+		h.argon2.memory = defaultArgon2Memory
+	}
+	if h.argon2.iterations == 0 {
+		h.argon2.iterations = defaultArgon2Iterations
+	}
+	if h.argon2.parallelism == 0 {
+		h.argon2.parallelism = defaultArgon2Parallelism
+	}
+	if h.bcryptCost == 0 {
+		h.bcryptCost = defaultBcryptCost
+	}
+	return h
+}
+
+func (h *hasher) Hash(password string) (string, error) {
+	if h.algorithm == AlgorithmBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash password: %w", err)
+		}
+		return string(hash), nil
+	}
+
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, h.argon2.iterations, h.argon2.memory, h.argon2.parallelism, argon2KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.argon2.memory, h.argon2.iterations, h.argon2.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *hasher) Verify(hash, password string) (bool, error) {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return false, err
+		}
+		other := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(key, other) != 1 {
+			return false, ErrMismatch
+		}
+		return h.algorithm != AlgorithmArgon2id || params != h.argon2, nil
+
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return false, ErrMismatch
+			}
+			return false, err
+		}
+		if h.algorithm != AlgorithmBcrypt {
+			return true, nil
+		}
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err == nil && cost < h.bcryptCost, nil
+
+	default:
+		return false, ErrUnknownFormat
+	}
+}
+
+// decodeArgon2id parses "$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>".
+func decodeArgon2id(hash string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, ErrUnknownFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil ||
+		params.iterations == 0 || params.parallelism == 0 {
+		return params, nil, nil, fmt.Errorf("invalid argon2 parameters %q", parts[3])
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2 key: %w", err)
+	}
+	if len(key) == 0 {
+		return params, nil, nil, ErrUnknownFormat
+	}
+	return params, salt, key, nil
+}
@@ -0,0 +1,53 @@
+package pat
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Scopes that can be granted to a personal access token.
+const (
+	ScopeProjectsRead     = "projects:read"
+	ScopeProjectsWrite    = "projects:write"
+	ScopeDeploymentsRead  = "deployments:read"
+	ScopeDeploymentsWrite = "deployments:write"
+	ScopeEnvRead          = "env:read"
+	ScopeEnvWrite         = "env:write"
+)
+
+// ValidScopes lists every scope a token may request.
+var ValidScopes = map[string]bool{
+	ScopeProjectsRead:     true,
+	ScopeProjectsWrite:    true,
+	ScopeDeploymentsRead:  true,
+	ScopeDeploymentsWrite: true,
+	ScopeEnvRead:          true,
+	ScopeEnvWrite:         true,
+}
+
+// CreateTokenRequest is the DTO for creating a personal access token.
+type CreateTokenRequest struct {
+	Name          string     `json:"name" binding:"required,min=1,max=100"`
+	Scopes        []string   `json:"scopes" binding:"required,min=1"`
+	OrgID         *uuid.UUID `json:"org_id"`
+	ExpiresInDays int        `json:"expires_in_days" binding:"omitempty,min=1,max=365"` // default 90
+}
+
+// TokenResponse is the public DTO for a personal access token.
+type TokenResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	Scopes      []string   `json:"scopes"`
+	OrgID       *uuid.UUID `json:"org_id,omitempty"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// CreateTokenResponse includes the plaintext token, which is shown only once.
+type CreateTokenResponse struct {
+	TokenResponse
+	Token string `json:"token"`
+}
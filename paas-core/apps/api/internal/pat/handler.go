@@ -0,0 +1,111 @@
+package pat
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	apiErrors "paas-core/apps/api/internal/errors"
+)
+
+// Handler handles personal access token management.
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new personal access token handler.
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// ListTokens godoc
+// @Summary List personal access tokens
+// @Tags tokens
+// @Security BearerAuth
+// @Success 200 {object} errors.Response{data=[]TokenResponse}
+// @Router /api/v1/users/me/tokens [get]
+func (h *Handler) ListTokens(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	tokens, err := h.service.List(c.Request.Context(), userID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(tokens))
+}
+
+// CreateToken godoc
+// @Summary Create a personal access token
+// @Description Issues a scoped token for CI and scripts. The token value is only returned once.
+// @Tags tokens
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateTokenRequest true "Token details"
+// @Success 201 {object} errors.Response{data=CreateTokenResponse}
+// @Failure 400 {object} errors.Response "Unknown scope"
+// @Failure 403 {object} errors.Response "Not a member of the organization"
+// @Router /api/v1/users/me/tokens [post]
+func (h *Handler) CreateToken(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req CreateTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	token, err := h.service.Create(c.Request.Context(), userID, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, apiErrors.Success(token))
+}
+
+// RevokeToken godoc
+// @Summary Revoke a personal access token
+// @Tags tokens
+// @Security BearerAuth
+// @Param id path string true "Token ID"
+// @Success 200 {object} errors.Response "Token revoked"
+// @Failure 404 {object} errors.Response "Token not found"
+// @Router /api/v1/users/me/tokens/{id} [delete]
+func (h *Handler) RevokeToken(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		_ = c.Error(apiErrors.BadRequest("Invalid token ID"))
+		return
+	}
+
+	if err := h.service.Revoke(c.Request.Context(), userID, id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(gin.H{"message": "Token revoked"}))
+}
+
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		_ = c.Error(apiErrors.Unauthorized(""))
+		return uuid.Nil, false
+	}
+	return userIDVal.(uuid.UUID), true
+}
@@ -0,0 +1,235 @@
+package pat
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"paas-core/apps/api/internal/auth"
+	apiErrors "paas-core/apps/api/internal/errors"
+	"paas-core/apps/api/internal/model"
+)
+
+const (
+	// TokenPrefix marks personal access tokens so they can be told apart from JWTs.
+	TokenPrefix = "pat_"
+
+	defaultExpiry     = 90 * 24 * time.Hour
+	tokenByteLength   = 32
+	displayPrefixLen  = 12
+	lastUsedPrecision = time.Minute // avoid a write on every request
+)
+
+// Service manages personal access tokens.
+type Service struct {
+	db *gorm.DB
+}
+
+// NewService creates a new personal access token service.
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// Create issues a new token for the user. The plaintext token is returned
+// once and only its hash is stored.
+func (s *Service) Create(ctx context.Context, userID uuid.UUID, req CreateTokenRequest) (*CreateTokenResponse, error) {
+	scopes, err := NormalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.OrgID != nil {
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&model.Membership{}).
+			Where("org_id = ? AND user_id = ?", *req.OrgID, userID).
+			Count(&count).Error; err != nil {
+			return nil, apiErrors.InternalServerError(err)
+		}
+		if count == 0 {
+			return nil, apiErrors.Forbidden("You are not a member of this organization")
+		}
+	}
+
+	expiry := defaultExpiry
+	if req.ExpiresInDays > 0 {
+		expiry = time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	}
+
+	buf := make([]byte, tokenByteLength)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, apiErrors.InternalServerError(fmt.Errorf("failed to generate token: %w", err))
+	}
+	rawToken := TokenPrefix + base64.RawURLEncoding.EncodeToString(buf)
+
+	record := &model.PersonalAccessToken{
+		UserID:      userID,
+		OrgID:       req.OrgID,
+		Name:        req.Name,
+		TokenPrefix: rawToken[:displayPrefixLen],
+		TokenHash:   hashToken(rawToken),
+		Scopes:      strings.Join(scopes, " "),
+		ExpiresAt:   time.Now().Add(expiry),
+	}
+	if err := s.db.WithContext(ctx).Create(record).Error; err != nil {
+		return nil, apiErrors.InternalServerError(fmt.Errorf("failed to store token: %w", err))
+	}
+
+	return &CreateTokenResponse{TokenResponse: toTokenResponse(record), Token: rawToken}, nil
+}
+
+// List returns the user's active tokens.
+func (s *Service) List(ctx context.Context, userID uuid.UUID) ([]TokenResponse, error) {
+	var records []model.PersonalAccessToken
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("created_at DESC").
+		Find(&records).Error; err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	result := make([]TokenResponse, len(records))
+	for i := range records {
+		result[i] = toTokenResponse(&records[i])
+	}
+	return result, nil
+}
+
+// Revoke revokes one of the user's tokens.
+func (s *Service) Revoke(ctx context.Context, userID, tokenID uuid.UUID) error {
+	res := s.db.WithContext(ctx).Model(&model.PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", tokenID, userID).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		return apiErrors.InternalServerError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return apiErrors.NotFound("Access token not found")
+	}
+	return nil
+}
+
+// Validate resolves a raw token into claims carrying its scopes and org
+// restriction.
+func (s *Service) Validate(ctx context.Context, rawToken string) (*auth.Claims, error) {
+	var record model.PersonalAccessToken
+	err := s.db.WithContext(ctx).Where("token_hash = ?", hashToken(rawToken)).First(&record).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, auth.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if record.RevokedAt != nil {
+		return nil, auth.ErrTokenRevoked
+	}
+	if now.After(record.ExpiresAt) {
+		return nil, auth.ErrExpiredToken
+	}
+
+	var user model.User
+	if err := s.db.WithContext(ctx).Preload("Roles").First(&user, "id = ?", record.UserID).Error; err != nil {
+		return nil, auth.ErrInvalidToken
+	}
+	if user.IsSuspended() {
+		return nil, auth.ErrSuspended
+	}
+	revoked, err := s.revokedByUser(ctx, &record)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, auth.ErrTokenRevoked
+	}
+
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) > lastUsedPrecision {
+		s.db.WithContext(ctx).Model(&record).Update("last_used_at", now)
+	}
+
+	roles := make([]string, len(user.Roles))
+	for i, r := range user.Roles {
+		roles[i] = r.Name
+	}
+
+	return &auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   user.ID.String(),
+			ID:        record.ID.String(),
+			ExpiresAt: jwt.NewNumericDate(record.ExpiresAt),
+		},
+		UserID: user.ID,
+		Email:  user.Email,
+		Name:   user.Name,
+		Roles:  roles,
+		Scopes: splitScopes(record.Scopes),
+		OrgID:  record.OrgID,
+	}, nil
+}
+
+// revokedByUser reports whether the token is void because the user asked for
+// their account to be deleted. Signing out everywhere revokes tokens
+// directly; an ordinary logout leaves them alone.
+func (s *Service) revokedByUser(ctx context.Context, record *model.PersonalAccessToken) (bool, error) {
+	var pendingDeletions int64
+	if err := s.db.WithContext(ctx).Model(&model.AccountDeletion{}).
+		Where("user_id = ? AND cancelled_at IS NULL AND completed_at IS NULL", record.UserID).
+		Count(&pendingDeletions).Error; err != nil {
+		return false, err
+	}
+	return pendingDeletions > 0, nil
+}
+
+// NormalizeScopes validates requested scopes and removes duplicates.
+func NormalizeScopes(requested []string) ([]string, error) {
+	seen := make(map[string]bool, len(requested))
+	scopes := make([]string, 0, len(requested))
+	for _, scope := range requested {
+		scope = strings.TrimSpace(scope)
+		if !ValidScopes[scope] {
+			return nil, apiErrors.BadRequest(fmt.Sprintf("Unknown scope: %s", scope))
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+// splitScopes always returns a non-nil slice so the claims are marked scoped.
+func splitScopes(s string) []string {
+	scopes := strings.Fields(s)
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return scopes
+}
+
+func toTokenResponse(t *model.PersonalAccessToken) TokenResponse {
+	return TokenResponse{
+		ID:          t.ID,
+		Name:        t.Name,
+		TokenPrefix: t.TokenPrefix,
+		Scopes:      splitScopes(t.Scopes),
+		OrgID:       t.OrgID,
+		ExpiresAt:   t.ExpiresAt,
+		LastUsedAt:  t.LastUsedAt,
+		CreatedAt:   t.CreatedAt,
+	}
+}
+
+func hashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,252 @@
+package pat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"paas-core/apps/api/internal/auth"
+	"paas-core/apps/api/internal/config"
+	"paas-core/apps/api/internal/database/dbtest"
+	apiErrors "paas-core/apps/api/internal/errors"
+	"paas-core/apps/api/internal/model"
+)
+
+// newTestService returns a service over a fresh database holding one user
+// who is a member of one org.
+func newTestService(t *testing.T) (*Service, *gorm.DB, *model.User, *model.Org) {
+	t.Helper()
+	db := dbtest.Open(t,
+		&model.User{}, &model.Role{}, &model.UserRole{}, &model.Org{}, &model.Membership{},
+		&model.PersonalAccessToken{}, &model.AccountDeletion{},
+		&model.RefreshToken{}, &model.UserSession{}, &model.RevokedToken{}, &model.TokenWatermark{},
+	)
+
+	user := &model.User{Name: "Ada", Email: "ada@example.com"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	org := &model.Org{Name: "Acme", Slug: "acme"}
+	if err := db.Create(org).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&model.Membership{UserID: user.ID, OrgID: org.ID, Role: "developer"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return NewService(db), db, user, org
+}
+
+func TestNormalizeScopes(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested []string
+		want      []string
+		wantErr   bool
+	}{
+		{"single", []string{ScopeProjectsRead}, []string{ScopeProjectsRead}, false},
+		{"trims and removes duplicates", []string{" env:read", ScopeEnvRead, ScopeEnvWrite}, []string{ScopeEnvRead, ScopeEnvWrite}, false},
+		{"unknown scope", []string{ScopeProjectsRead, "admin"}, nil, true},
+		{"empty scope", []string{""}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeScopes(tt.requested)
+			if tt.wantErr {
+				if !isStatus(err, http.StatusBadRequest) {
+					t.Fatalf("NormalizeScopes() error = %v, want bad request", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizeScopes() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NormalizeScopes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateOrgRestriction(t *testing.T) {
+	svc, db, user, org := newTestService(t)
+	other := &model.Org{Name: "Other", Slug: "other"}
+	if err := db.Create(other).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		orgID      *uuid.UUID
+		wantStatus int // 0 when the token is issued
+	}{
+		{"unrestricted", nil, 0},
+		{"member org", &org.ID, 0},
+		{"foreign org", &other.ID, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := svc.Create(context.Background(), user.ID, CreateTokenRequest{
+				Name:   tt.name,
+				Scopes: []string{ScopeDeploymentsWrite},
+				OrgID:  tt.orgID,
+			})
+			if tt.wantStatus != 0 {
+				if !isStatus(err, tt.wantStatus) {
+					t.Fatalf("Create() error = %v, want status %d", err, tt.wantStatus)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+
+			claims, err := svc.Validate(context.Background(), resp.Token)
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if !reflect.DeepEqual(claims.OrgID, tt.orgID) {
+				t.Errorf("OrgID = %v, want %v", claims.OrgID, tt.orgID)
+			}
+			if !reflect.DeepEqual(claims.Scopes, []string{ScopeDeploymentsWrite}) {
+				t.Errorf("Scopes = %v, want [%s]", claims.Scopes, ScopeDeploymentsWrite)
+			}
+			if !claims.HasScope(ScopeDeploymentsWrite) || claims.HasScope(ScopeEnvRead) {
+				t.Errorf("HasScope disagrees with scopes %v", claims.Scopes)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, db *gorm.DB, userID, tokenID uuid.UUID)
+		wantErr error
+	}{
+		{
+			name:  "valid",
+			setup: func(*testing.T, *gorm.DB, uuid.UUID, uuid.UUID) {},
+		},
+		{
+			name: "revoked",
+			setup: func(t *testing.T, db *gorm.DB, _, tokenID uuid.UUID) {
+				mustExec(t, db.Model(&model.PersonalAccessToken{}).Where("id = ?", tokenID).Update("revoked_at", time.Now()))
+			},
+			wantErr: auth.ErrTokenRevoked,
+		},
+		{
+			name: "expired",
+			setup: func(t *testing.T, db *gorm.DB, _, tokenID uuid.UUID) {
+				mustExec(t, db.Model(&model.PersonalAccessToken{}).Where("id = ?", tokenID).Update("expires_at", time.Now().Add(-time.Minute)))
+			},
+			wantErr: auth.ErrExpiredToken,
+		},
+		{
+			name: "suspended user",
+			setup: func(t *testing.T, db *gorm.DB, userID, _ uuid.UUID) {
+				mustExec(t, db.Model(&model.User{}).Where("id = ?", userID).Update("suspended_until", time.Now().Add(time.Hour)))
+			},
+			wantErr: auth.ErrSuspended,
+		},
+		{
+			name: "pending account deletion",
+			setup: func(t *testing.T, db *gorm.DB, userID, _ uuid.UUID) {
+				mustExec(t, db.Create(&model.AccountDeletion{UserID: userID, PurgeAt: time.Now().Add(24 * time.Hour)}))
+			},
+			wantErr: auth.ErrTokenRevoked,
+		},
+		{
+			name: "cancelled account deletion",
+			setup: func(t *testing.T, db *gorm.DB, userID, _ uuid.UUID) {
+				now := time.Now()
+				mustExec(t, db.Create(&model.AccountDeletion{UserID: userID, PurgeAt: now.Add(24 * time.Hour), CancelledAt: &now}))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, db, user, _ := newTestService(t)
+			resp, err := svc.Create(context.Background(), user.ID, CreateTokenRequest{Name: "ci", Scopes: []string{ScopeProjectsRead}})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			tt.setup(t, db, user.ID, resp.ID)
+
+			claims, err := svc.Validate(context.Background(), resp.Token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && claims.UserID != user.ID {
+				t.Errorf("UserID = %s, want %s", claims.UserID, user.ID)
+			}
+		})
+	}
+}
+
+func TestValidateUnknownToken(t *testing.T) {
+	svc, _, _, _ := newTestService(t)
+	if _, err := svc.Validate(context.Background(), TokenPrefix+"unknown"); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatalf("Validate() error = %v, want %v", err, auth.ErrInvalidToken)
+	}
+}
+
+// TestValidateAfterSignOut checks that a plain logout keeps tokens made for
+// CI and scripts working, while signing out everywhere revokes them.
+func TestValidateAfterSignOut(t *testing.T) {
+	tests := []struct {
+		name    string
+		signOut func(ctx context.Context, s auth.Service, userID uuid.UUID) error
+		wantErr error
+	}{
+		{
+			name: "logout",
+			signOut: func(ctx context.Context, s auth.Service, userID uuid.UUID) error {
+				return s.RevokeAllUserTokens(ctx, userID)
+			},
+		},
+		{
+			name: "sign out everywhere",
+			signOut: func(ctx context.Context, s auth.Service, userID uuid.UUID) error {
+				return s.SignOutEverywhere(ctx, userID)
+			},
+			wantErr: auth.ErrTokenRevoked,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, db, user, _ := newTestService(t)
+			ctx := context.Background()
+			resp, err := svc.Create(ctx, user.ID, CreateTokenRequest{Name: "ci", Scopes: []string{ScopeDeploymentsWrite}})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+
+			authSvc := auth.NewService(&config.JWTConfig{}, db, nil, nil)
+			if err := tt.signOut(ctx, authSvc, user.ID); err != nil {
+				t.Fatalf("sign out: %v", err)
+			}
+
+			if _, err := svc.Validate(ctx, resp.Token); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func isStatus(err error, status int) bool {
+	var apiErr *apiErrors.APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+func mustExec(t *testing.T, tx *gorm.DB) {
+	t.Helper()
+	if tx.Error != nil {
+		t.Fatal(tx.Error)
+	}
+}
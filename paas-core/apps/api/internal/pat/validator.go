@@ -0,0 +1,39 @@
+package pat
+
+import (
+	"context"
+	"strings"
+
+	"paas-core/apps/api/internal/auth"
+)
+
+// TokenValidator mirrors middleware.TokenValidator.
+type TokenValidator interface {
+	ValidateToken(tokenString string) (*auth.Claims, error)
+}
+
+// Validator accepts personal access tokens and hands every other token to the
+// wrapped validator.
+type Validator struct {
+	next    TokenValidator
+	service *Service
+}
+
+// NewValidator wraps next so that personal access tokens are also accepted.
+func NewValidator(next TokenValidator, service *Service) *Validator {
+	return &Validator{next: next, service: service}
+}
+
+// ValidateToken implements middleware.TokenValidator.
+func (v *Validator) ValidateToken(tokenString string) (*auth.Claims, error) {
+	return v.ValidateTokenContext(context.Background(), tokenString)
+}
+
+// ValidateTokenContext implements middleware.ContextTokenValidator, so the
+// database lookups of personal access tokens end with the request.
+func (v *Validator) ValidateTokenContext(ctx context.Context, tokenString string) (*auth.Claims, error) {
+	if strings.HasPrefix(tokenString, TokenPrefix) {
+		return v.service.Validate(ctx, tokenString)
+	}
+	return v.next.ValidateToken(tokenString)
+}
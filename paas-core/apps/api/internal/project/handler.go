@@ -265,6 +265,40 @@ func (h *Handler) ListEnvVars(c *gin.Context) {
 	c.JSON(http.StatusOK, apiErrors.Success(envVars))
 }
 
+// RevealEnvVar godoc
+// @Summary Reveal the value of an environment variable
+// @Description Returns the unredacted value of a secret. Requires a recent sign-in; otherwise responds with REAUTH_REQUIRED.
+// @Tags projects
+// @Security BearerAuth
+// @Param orgId path string true "Organization ID"
+// @Param projectId path string true "Project ID"
+// @Param envVarId path string true "Env Var ID"
+// @Success 200 {object} errors.Response{data=EnvVarResponse}
+// @Failure 403 {object} errors.Response "Re-authentication required"
+// @Failure 404 {object} errors.Response "Environment variable not found"
+// @Router /api/v1/orgs/{orgId}/projects/{projectId}/env/{envVarId}/reveal [get]
+func (h *Handler) RevealEnvVar(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		_ = c.Error(apiErrors.BadRequest("Invalid project ID"))
+		return
+	}
+	envVarID, err := uuid.Parse(c.Param("envVarId"))
+	if err != nil {
+		_ = c.Error(apiErrors.BadRequest("Invalid env var ID"))
+		return
+	}
+
+	envVar, err := h.projectService.RevealEnvVar(c.Request.Context(), projectID, envVarID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, apiErrors.Success(envVar))
+}
+
 // DeleteEnvVar godoc
 // @Summary Delete an environment variable
 // @Tags projects
@@ -28,6 +28,7 @@ type Repository interface {
 	// Env Vars
 	SetEnvVar(ctx context.Context, ev *model.EnvVar) error
 	ListEnvVars(ctx context.Context, projectID uuid.UUID) ([]model.EnvVar, error)
+	FindEnvVar(ctx context.Context, projectID, id uuid.UUID) (*model.EnvVar, error)
 	DeleteEnvVar(ctx context.Context, id uuid.UUID) error
 }
 
@@ -140,6 +141,15 @@ func (r *repository) ListEnvVars(ctx context.Context, projectID uuid.UUID) ([]mo
 	return envVars, err
 }
 
+func (r *repository) FindEnvVar(ctx context.Context, projectID, id uuid.UUID) (*model.EnvVar, error) {
+	var ev model.EnvVar
+	err := r.db.WithContext(ctx).Where("id = ? AND project_id = ?", id, projectID).First(&ev).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &ev, err
+}
+
 func (r *repository) DeleteEnvVar(ctx context.Context, id uuid.UUID) error {
 	return r.db.WithContext(ctx).Delete(&model.EnvVar{}, "id = ?", id).Error
 }
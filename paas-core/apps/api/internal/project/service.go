@@ -24,6 +24,7 @@ type Service interface {
 	// Env Vars
 	SetEnvVar(ctx context.Context, projectID uuid.UUID, req SetEnvVarRequest) (*EnvVarResponse, error)
 	ListEnvVars(ctx context.Context, projectID uuid.UUID) ([]EnvVarResponse, error)
+	RevealEnvVar(ctx context.Context, projectID, envVarID uuid.UUID) (*EnvVarResponse, error)
 	DeleteEnvVar(ctx context.Context, envVarID uuid.UUID) error
 }
 
@@ -164,6 +165,20 @@ func (s *service) ListEnvVars(ctx context.Context, projectID uuid.UUID) ([]EnvVa
 	return responses, nil
 }
 
+// RevealEnvVar returns an env var with its value, even when it is a secret.
+func (s *service) RevealEnvVar(ctx context.Context, projectID, envVarID uuid.UUID) (*EnvVarResponse, error) {
+	ev, err := s.repo.FindEnvVar(ctx, projectID, envVarID)
+	if err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+	if ev == nil {
+		return nil, apiErrors.NotFound("Environment variable not found")
+	}
+	resp := toEnvVarResponse(ev)
+	resp.Value = ev.Value
+	return resp, nil
+}
+
 func (s *service) DeleteEnvVar(ctx context.Context, envVarID uuid.UUID) error {
 	if err := s.repo.DeleteEnvVar(ctx, envVarID); err != nil {
 		return apiErrors.InternalServerError(err)
@@ -0,0 +1,63 @@
+package serviceaccount
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GrantTypeClientCredentials is the only grant accepted by the token endpoint.
+const GrantTypeClientCredentials = "client_credentials"
+
+// CreateRequest is the DTO for creating a service account.
+type CreateRequest struct {
+	Name        string   `json:"name" binding:"required,min=1,max=100"`
+	Description string   `json:"description" binding:"max=255"`
+	Role        string   `json:"role" binding:"required,oneof=admin developer viewer"`
+	Scopes      []string `json:"scopes" binding:"required,min=1"`
+}
+
+// UpdateRequest is the DTO for updating a service account. Omitted fields are
+// left unchanged.
+type UpdateRequest struct {
+	Name        *string  `json:"name" binding:"omitempty,min=1,max=100"`
+	Description *string  `json:"description" binding:"omitempty,max=255"`
+	Role        *string  `json:"role" binding:"omitempty,oneof=admin developer viewer"`
+	Scopes      []string `json:"scopes" binding:"omitempty,min=1"`
+}
+
+// Response is the public DTO for a service account.
+type Response struct {
+	ID          uuid.UUID  `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	ClientID    string     `json:"client_id"`
+	Role        string     `json:"role"`
+	Scopes      []string   `json:"scopes"`
+	CreatedByID *uuid.UUID `json:"created_by_id,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// CredentialsResponse includes the client secret, which is shown only once.
+type CredentialsResponse struct {
+	Response
+	ClientSecret string `json:"client_secret"`
+}
+
+// TokenRequest is the client credentials grant. Credentials may be sent in the
+// body or with HTTP Basic auth.
+type TokenRequest struct {
+	GrantType    string `json:"grant_type" form:"grant_type" binding:"required"`
+	ClientID     string `json:"client_id" form:"client_id"`
+	ClientSecret string `json:"client_secret" form:"client_secret"`
+	Scope        string `json:"scope" form:"scope"` // optional, space-separated subset
+}
+
+// TokenResponse is returned by the token endpoint.
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
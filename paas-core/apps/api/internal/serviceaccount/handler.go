@@ -0,0 +1,213 @@
+package serviceaccount
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"paas-core/apps/api/internal/auth"
+	apiErrors "paas-core/apps/api/internal/errors"
+)
+
+// Handler handles service account management and the token endpoint.
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new service account handler.
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// List godoc
+// @Summary List service accounts
+// @Tags service-accounts
+// @Security BearerAuth
+// @Param orgId path string true "Organization ID"
+// @Success 200 {object} errors.Response{data=[]Response}
+// @Router /api/v1/orgs/{orgId}/service-accounts [get]
+func (h *Handler) List(c *gin.Context) {
+	orgID := c.MustGet("org_id").(uuid.UUID)
+
+	accounts, err := h.service.List(c.Request.Context(), orgID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(accounts))
+}
+
+// Create godoc
+// @Summary Create a service account
+// @Description Creates a non-human org member. The client secret is only returned once.
+// @Tags service-accounts
+// @Security BearerAuth
+// @Param orgId path string true "Organization ID"
+// @Param request body CreateRequest true "Service account"
+// @Success 201 {object} errors.Response{data=CredentialsResponse}
+// @Router /api/v1/orgs/{orgId}/service-accounts [post]
+func (h *Handler) Create(c *gin.Context) {
+	orgID := c.MustGet("org_id").(uuid.UUID)
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	account, err := h.service.Create(c.Request.Context(), orgID, claims.UserID, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, apiErrors.Success(account))
+}
+
+// Get godoc
+// @Summary Get a service account
+// @Tags service-accounts
+// @Security BearerAuth
+// @Param orgId path string true "Organization ID"
+// @Param accountId path string true "Service account ID"
+// @Success 200 {object} errors.Response{data=Response}
+// @Failure 404 {object} errors.Response "Service account not found"
+// @Router /api/v1/orgs/{orgId}/service-accounts/{accountId} [get]
+func (h *Handler) Get(c *gin.Context) {
+	orgID := c.MustGet("org_id").(uuid.UUID)
+	id, ok := accountID(c)
+	if !ok {
+		return
+	}
+
+	account, err := h.service.Get(c.Request.Context(), orgID, id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(account))
+}
+
+// Update godoc
+// @Summary Update a service account
+// @Tags service-accounts
+// @Security BearerAuth
+// @Param orgId path string true "Organization ID"
+// @Param accountId path string true "Service account ID"
+// @Param request body UpdateRequest true "Changes"
+// @Success 200 {object} errors.Response{data=Response}
+// @Router /api/v1/orgs/{orgId}/service-accounts/{accountId} [put]
+func (h *Handler) Update(c *gin.Context) {
+	orgID := c.MustGet("org_id").(uuid.UUID)
+	claims := c.MustGet("claims").(*auth.Claims)
+	id, ok := accountID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	account, err := h.service.Update(c.Request.Context(), orgID, claims.UserID, id, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(account))
+}
+
+// RotateSecret godoc
+// @Summary Rotate a service account's client secret
+// @Tags service-accounts
+// @Security BearerAuth
+// @Param orgId path string true "Organization ID"
+// @Param accountId path string true "Service account ID"
+// @Success 200 {object} errors.Response{data=CredentialsResponse}
+// @Router /api/v1/orgs/{orgId}/service-accounts/{accountId}/secret [post]
+func (h *Handler) RotateSecret(c *gin.Context) {
+	orgID := c.MustGet("org_id").(uuid.UUID)
+	claims := c.MustGet("claims").(*auth.Claims)
+	id, ok := accountID(c)
+	if !ok {
+		return
+	}
+
+	account, err := h.service.RotateSecret(c.Request.Context(), orgID, claims.UserID, id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(account))
+}
+
+// Delete godoc
+// @Summary Delete a service account
+// @Tags service-accounts
+// @Security BearerAuth
+// @Param orgId path string true "Organization ID"
+// @Param accountId path string true "Service account ID"
+// @Success 200 {object} errors.Response "Service account deleted"
+// @Router /api/v1/orgs/{orgId}/service-accounts/{accountId} [delete]
+func (h *Handler) Delete(c *gin.Context) {
+	orgID := c.MustGet("org_id").(uuid.UUID)
+	claims := c.MustGet("claims").(*auth.Claims)
+	id, ok := accountID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), orgID, claims.UserID, id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(gin.H{"message": "Service account deleted"}))
+}
+
+// Token godoc
+// @Summary Issue a service account access token
+// @Description Client credentials grant. Send client_id and client_secret with HTTP Basic auth or in the body.
+// @Tags auth
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body TokenRequest true "Client credentials"
+// @Success 200 {object} errors.Response{data=TokenResponse}
+// @Failure 401 {object} errors.Response "Invalid client credentials"
+// @Router /api/v1/auth/token [post]
+func (h *Handler) Token(c *gin.Context) {
+	var req TokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		req.ClientID, req.ClientSecret = id, secret
+	}
+
+	token, err := h.service.IssueToken(c.Request.Context(), req, c.ClientIP())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(token))
+}
+
+func accountID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("accountId"))
+	if err != nil {
+		_ = c.Error(apiErrors.BadRequest("Invalid service account ID"))
+		return uuid.Nil, false
+	}
+	return id, true
+}
@@ -0,0 +1,377 @@
+package serviceaccount
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"paas-core/apps/api/internal/audit"
+	"paas-core/apps/api/internal/auth"
+	apiErrors "paas-core/apps/api/internal/errors"
+	"paas-core/apps/api/internal/model"
+	"paas-core/apps/api/internal/pat"
+)
+
+// Audit actions recorded for service accounts.
+const (
+	ActionCreated       = "service_account.created"
+	ActionUpdated       = "service_account.updated"
+	ActionSecretRotated = "service_account.secret_rotated"
+	ActionDeleted       = "service_account.deleted"
+	ActionTokenIssued   = "service_account.token_issued"
+)
+
+const (
+	clientIDPrefix     = "sa_"
+	clientSecretPrefix = "sas_"
+	emailDomain        = "service-accounts.invalid" // never deliverable, never matches a real login
+)
+
+// Service manages org-owned service accounts and issues their access tokens.
+type Service struct {
+	db          *gorm.DB
+	authService auth.Service
+	audit       *audit.Service
+}
+
+// NewService creates a new service account service.
+func NewService(db *gorm.DB, authService auth.Service, auditService *audit.Service) *Service {
+	return &Service{db: db, authService: authService, audit: auditService}
+}
+
+// Create adds a service account to the org with its own membership and
+// returns its credentials. The client secret is only returned once.
+func (s *Service) Create(ctx context.Context, orgID, actorID uuid.UUID, req CreateRequest) (*CredentialsResponse, error) {
+	scopes, err := pat.NormalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	clientID, err := randomString(clientIDPrefix, 12, hex.EncodeToString)
+	if err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+	secret, err := randomString(clientSecretPrefix, 32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	account := &model.ServiceAccount{
+		OrgID:       orgID,
+		Name:        req.Name,
+		Description: req.Description,
+		ClientID:    clientID,
+		SecretHash:  hashSecret(secret),
+		Scopes:      strings.Join(scopes, " "),
+		CreatedByID: &actorID,
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		user := &model.User{
+			Name:             req.Name,
+			Email:            clientID + "@" + emailDomain,
+			EmailVerified:    true,
+			IsServiceAccount: true,
+		}
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&model.Membership{UserID: user.ID, OrgID: orgID, Role: req.Role}).Error; err != nil {
+			return err
+		}
+		account.UserID = user.ID
+		return tx.Create(account).Error
+	})
+	if err != nil {
+		return nil, apiErrors.InternalServerError(fmt.Errorf("failed to create service account: %w", err))
+	}
+
+	s.audit.Record(ctx, orgID, actorID, ActionCreated, resourceName(account.ID), map[string]any{
+		"name":   account.Name,
+		"role":   req.Role,
+		"scopes": scopes,
+	})
+
+	return &CredentialsResponse{Response: toResponse(account, req.Role), ClientSecret: secret}, nil
+}
+
+// List returns the org's service accounts.
+func (s *Service) List(ctx context.Context, orgID uuid.UUID) ([]Response, error) {
+	var accounts []model.ServiceAccount
+	if err := s.db.WithContext(ctx).Where("org_id = ?", orgID).Order("created_at ASC").Find(&accounts).Error; err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	roles, err := s.roles(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Response, len(accounts))
+	for i := range accounts {
+		result[i] = toResponse(&accounts[i], roles[accounts[i].UserID])
+	}
+	return result, nil
+}
+
+// Get returns a single service account.
+func (s *Service) Get(ctx context.Context, orgID, id uuid.UUID) (*Response, error) {
+	account, membership, err := s.find(ctx, s.db.WithContext(ctx), orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	resp := toResponse(account, membership.Role)
+	return &resp, nil
+}
+
+// Update changes a service account's name, description, org role or scopes.
+func (s *Service) Update(ctx context.Context, orgID, actorID, id uuid.UUID, req UpdateRequest) (*Response, error) {
+	var scopes []string
+	if req.Scopes != nil {
+		var err error
+		if scopes, err = pat.NormalizeScopes(req.Scopes); err != nil {
+			return nil, err
+		}
+	}
+
+	var account *model.ServiceAccount
+	var membership *model.Membership
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		account, membership, err = s.find(ctx, tx, orgID, id)
+		if err != nil {
+			return err
+		}
+
+		if req.Name != nil {
+			account.Name = *req.Name
+			if err := tx.Model(&model.User{}).Where("id = ?", account.UserID).Update("name", *req.Name).Error; err != nil {
+				return err
+			}
+		}
+		if req.Description != nil {
+			account.Description = *req.Description
+		}
+		if scopes != nil {
+			account.Scopes = strings.Join(scopes, " ")
+		}
+		if err := tx.Save(account).Error; err != nil {
+			return err
+		}
+
+		if req.Role != nil {
+			membership.Role = *req.Role
+			if err := tx.Model(membership).Update("role", *req.Role).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		var apiErr *apiErrors.APIError
+		if errors.As(err, &apiErr) {
+			return nil, apiErr
+		}
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	s.audit.Record(ctx, orgID, actorID, ActionUpdated, resourceName(account.ID), req)
+
+	resp := toResponse(account, membership.Role)
+	return &resp, nil
+}
+
+// RotateSecret replaces the client secret. The old secret stops working
+// immediately; tokens already issued stay valid until they expire.
+func (s *Service) RotateSecret(ctx context.Context, orgID, actorID, id uuid.UUID) (*CredentialsResponse, error) {
+	account, membership, err := s.find(ctx, s.db.WithContext(ctx), orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := randomString(clientSecretPrefix, 32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	account.SecretHash = hashSecret(secret)
+	if err := s.db.WithContext(ctx).Model(account).Update("secret_hash", account.SecretHash).Error; err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	s.audit.Record(ctx, orgID, actorID, ActionSecretRotated, resourceName(account.ID), nil)
+
+	return &CredentialsResponse{Response: toResponse(account, membership.Role), ClientSecret: secret}, nil
+}
+
+// Delete removes a service account together with its membership and backing
+// user, and revokes any access tokens it still holds.
+func (s *Service) Delete(ctx context.Context, orgID, actorID, id uuid.UUID) error {
+	var account *model.ServiceAccount
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		account, _, err = s.find(ctx, tx, orgID, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(account).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", account.UserID).Delete(&model.Membership{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.User{}, "id = ?", account.UserID).Error
+	})
+	if err != nil {
+		var apiErr *apiErrors.APIError
+		if errors.As(err, &apiErr) {
+			return apiErr
+		}
+		return apiErrors.InternalServerError(err)
+	}
+
+	if err := s.authService.RevokeAllUserTokens(ctx, account.UserID); err != nil {
+		return apiErrors.InternalServerError(fmt.Errorf("failed to revoke service account tokens: %w", err))
+	}
+
+	s.audit.Record(ctx, orgID, actorID, ActionDeleted, resourceName(account.ID), map[string]any{"name": account.Name})
+	return nil
+}
+
+// IssueToken runs the client credentials grant and returns a short-lived
+// access token restricted to the account's org and scopes.
+func (s *Service) IssueToken(ctx context.Context, req TokenRequest, ipAddress string) (*TokenResponse, error) {
+	if req.GrantType != GrantTypeClientCredentials {
+		return nil, apiErrors.BadRequest("Unsupported grant type")
+	}
+	if req.ClientID == "" || req.ClientSecret == "" {
+		return nil, apiErrors.Unauthorized("Invalid client credentials")
+	}
+
+	var account model.ServiceAccount
+	err := s.db.WithContext(ctx).Preload("User").Where("client_id = ?", req.ClientID).First(&account).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apiErrors.Unauthorized("Invalid client credentials")
+		}
+		return nil, apiErrors.InternalServerError(err)
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(req.ClientSecret)), []byte(account.SecretHash)) != 1 {
+		return nil, apiErrors.Unauthorized("Invalid client credentials")
+	}
+
+	granted := strings.Fields(account.Scopes)
+	scopes := granted
+	if req.Scope != "" {
+		scopes = strings.Fields(req.Scope)
+		for _, scope := range scopes {
+			if !contains(granted, scope) {
+				return nil, apiErrors.Forbidden(fmt.Sprintf("Scope %s is not granted to this service account", scope))
+			}
+		}
+	}
+
+	pair, err := s.authService.GenerateScopedToken(ctx, account.UserID, account.User.Email, account.Name, scopes, &account.OrgID)
+	if err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	now := time.Now()
+	s.db.WithContext(ctx).Model(&account).Update("last_used_at", now)
+	s.audit.Record(ctx, account.OrgID, account.UserID, ActionTokenIssued, resourceName(account.ID), map[string]any{
+		"scopes":     scopes,
+		"ip_address": ipAddress,
+	})
+
+	return &TokenResponse{
+		AccessToken: pair.AccessToken,
+		TokenType:   pair.TokenType,
+		ExpiresIn:   pair.ExpiresIn,
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// find loads a service account in the org along with its membership.
+func (s *Service) find(ctx context.Context, db *gorm.DB, orgID, id uuid.UUID) (*model.ServiceAccount, *model.Membership, error) {
+	var account model.ServiceAccount
+	if err := db.WithContext(ctx).Where("id = ? AND org_id = ?", id, orgID).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, apiErrors.NotFound("Service account not found")
+		}
+		return nil, nil, apiErrors.InternalServerError(err)
+	}
+
+	var membership model.Membership
+	if err := db.WithContext(ctx).Where("org_id = ? AND user_id = ?", orgID, account.UserID).First(&membership).Error; err != nil {
+		return nil, nil, apiErrors.InternalServerError(err)
+	}
+	return &account, &membership, nil
+}
+
+// roles maps each service account user in the org to its membership role.
+func (s *Service) roles(ctx context.Context, orgID uuid.UUID) (map[uuid.UUID]string, error) {
+	var memberships []model.Membership
+	err := s.db.WithContext(ctx).
+		Joins("JOIN users ON users.id = memberships.user_id").
+		Where("memberships.org_id = ? AND users.is_service_account = ?", orgID, true).
+		Find(&memberships).Error
+	if err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	roles := make(map[uuid.UUID]string, len(memberships))
+	for _, m := range memberships {
+		roles[m.UserID] = m.Role
+	}
+	return roles, nil
+}
+
+func toResponse(a *model.ServiceAccount, role string) Response {
+	return Response{
+		ID:          a.ID,
+		Name:        a.Name,
+		Description: a.Description,
+		ClientID:    a.ClientID,
+		Role:        role,
+		Scopes:      strings.Fields(a.Scopes),
+		CreatedByID: a.CreatedByID,
+		LastUsedAt:  a.LastUsedAt,
+		CreatedAt:   a.CreatedAt,
+	}
+}
+
+func resourceName(id uuid.UUID) string {
+	return "service_account:" + id.String()
+}
+
+func randomString(prefix string, n int, encode func([]byte) string) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate credentials: %w", err)
+	}
+	return prefix + encode(buf), nil
+}
+
+func hashSecret(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
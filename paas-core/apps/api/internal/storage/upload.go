@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -115,6 +116,88 @@ func (s *UploadService) DeleteAvatar(ctx context.Context, key string) error {
 	return nil
 }
 
+// UploadExport stores a personal data export for a user and returns a
+// time-limited download URL.
+func (s *UploadService) UploadExport(ctx context.Context, ownerID uuid.UUID, filename string, data []byte, expiry time.Duration) (string, error) {
+	key := fmt.Sprintf("exports/user/%s/%s.zip", ownerID.String(), uuid.New().String())
+
+	info, err := s.storage.Upload(ctx, key, bytes.NewReader(data), "application/zip", int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("failed to upload export: %w", err)
+	}
+
+	upload := model.FileUpload{
+		OwnerID:     ownerID,
+		OwnerType:   "user",
+		Key:         info.Key,
+		Filename:    filename,
+		ContentType: info.ContentType,
+		Size:        info.Size,
+		Category:    "export",
+	}
+	if err := s.db.WithContext(ctx).Create(&upload).Error; err != nil {
+		_ = s.storage.Delete(ctx, key)
+		return "", fmt.Errorf("failed to save upload metadata: %w", err)
+	}
+
+	return s.storage.GetPresignedURL(ctx, key, expiry)
+}
+
+// DeleteOwnerFiles removes every file of a user or org from storage along
+// with its metadata. Files that fail to delete are kept for a later retry.
+func (s *UploadService) DeleteOwnerFiles(ctx context.Context, ownerType string, ownerID uuid.UUID) error {
+	var uploads []model.FileUpload
+	if err := s.db.WithContext(ctx).
+		Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).
+		Find(&uploads).Error; err != nil {
+		return fmt.Errorf("failed to list uploads: %w", err)
+	}
+
+	var firstErr error
+	for _, upload := range uploads {
+		if err := s.storage.Delete(ctx, upload.Key); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to delete %s from storage: %w", upload.Key, err)
+			}
+			continue
+		}
+		if err := s.db.WithContext(ctx).Unscoped().Delete(&upload).Error; err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to delete upload metadata: %w", err)
+		}
+	}
+	return firstErr
+}
+
+// DeleteExportsBefore removes personal data exports stored before cutoff.
+// Their download links have expired, so nobody can fetch them anymore.
+func (s *UploadService) DeleteExportsBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	var uploads []model.FileUpload
+	if err := s.db.WithContext(ctx).
+		Where("category = ? AND created_at < ?", "export", cutoff).
+		Find(&uploads).Error; err != nil {
+		return 0, fmt.Errorf("failed to list exports: %w", err)
+	}
+
+	deleted := 0
+	var firstErr error
+	for _, upload := range uploads {
+		if err := s.storage.Delete(ctx, upload.Key); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to delete %s from storage: %w", upload.Key, err)
+			}
+			continue
+		}
+		if err := s.db.WithContext(ctx).Unscoped().Delete(&upload).Error; err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to delete upload metadata: %w", err)
+			}
+			continue
+		}
+		deleted++
+	}
+	return deleted, firstErr
+}
+
 // GetPresignedURL generates a time-limited download URL for a file.
 func (s *UploadService) GetPresignedURL(ctx context.Context, key string) (string, error) {
 	return s.storage.GetPresignedURL(ctx, key, 15*time.Minute)
@@ -0,0 +1,154 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"paas-core/apps/api/internal/email"
+	apiErrors "paas-core/apps/api/internal/errors"
+	"paas-core/apps/api/internal/model"
+)
+
+const emailChangeTokenExpiry = 24 * time.Hour
+
+// RequestEmailChange starts a change of the user's email address. Nothing
+// changes until the new address confirms; the current address is told about
+// the request and can cancel it. A new request replaces any pending one.
+func (s *VerificationService) RequestEmailChange(ctx context.Context, userID uuid.UUID, newEmail string) error {
+	db := s.db.WithContext(ctx)
+	newEmail = strings.TrimSpace(newEmail)
+
+	var usr model.User
+	if err := db.First(&usr, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apiErrors.NotFound("User not found")
+		}
+		return apiErrors.InternalServerError(err)
+	}
+	if strings.EqualFold(usr.Email, newEmail) {
+		return apiErrors.BadRequest("That is already your email address")
+	}
+	if err := ensureEmailAvailable(db, newEmail, usr.ID); err != nil {
+		return err
+	}
+
+	rawToken, tokenHash := generateTokenPair()
+	rawCancel, cancelHash := generateTokenPair()
+	now := time.Now()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.EmailChangeRequest{}).
+			Where("user_id = ? AND confirmed_at IS NULL AND cancelled_at IS NULL", usr.ID).
+			Update("cancelled_at", now).Error; err != nil {
+			return fmt.Errorf("failed to replace pending email change: %w", err)
+		}
+		return tx.Create(&model.EmailChangeRequest{
+			UserID:          usr.ID,
+			OldEmail:        usr.Email,
+			NewEmail:        newEmail,
+			TokenHash:       tokenHash,
+			CancelTokenHash: cancelHash,
+			ExpiresAt:       now.Add(emailChangeTokenExpiry),
+		}).Error
+	})
+	if err != nil {
+		return apiErrors.InternalServerError(err)
+	}
+
+	data := email.TemplateData{
+		AppName:   s.appName,
+		AppURL:    s.appURL,
+		UserName:  usr.Name,
+		UserEmail: usr.Email,
+		NewEmail:  newEmail,
+		ExpiresIn: "24 hours",
+	}
+
+	// The notice to the old address is what makes a takeover visible, so a
+	// failure there is logged but must not block the confirmation
+	notice := data
+	notice.Token = rawCancel
+	notice.Link = fmt.Sprintf("%s/auth/email-change/cancel?token=%s", s.appURL, rawCancel)
+	if err := s.emailService.Send(ctx, email.RenderEmailChangeNoticeEmail(notice)); err != nil {
+		slog.Error("Failed to send email change notice", "user_id", usr.ID, "error", err)
+	}
+
+	confirm := data
+	confirm.Token = rawToken
+	confirm.Link = fmt.Sprintf("%s/auth/email-change/confirm?token=%s", s.appURL, rawToken)
+	if err := s.emailService.Send(ctx, email.RenderEmailChangeConfirmEmail(confirm)); err != nil {
+		return apiErrors.InternalServerError(fmt.Errorf("failed to send email change confirmation: %w", err))
+	}
+	return nil
+}
+
+// ConfirmEmailChange applies a pending email change. Following the link
+// proves ownership of the new address, so it is marked verified.
+func (s *VerificationService) ConfirmEmailChange(ctx context.Context, rawToken string) error {
+	now := time.Now()
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var req model.EmailChangeRequest
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND expires_at > ? AND confirmed_at IS NULL AND cancelled_at IS NULL", hashToken(rawToken), now).
+			First(&req).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apiErrors.BadRequest("Invalid or expired email change link")
+			}
+			return apiErrors.InternalServerError(err)
+		}
+
+		// The address may have been taken since the request was made
+		if err := ensureEmailAvailable(tx, req.NewEmail, req.UserID); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&req).Update("confirmed_at", now).Error; err != nil {
+			return apiErrors.InternalServerError(fmt.Errorf("failed to mark email change as confirmed: %w", err))
+		}
+		if err := tx.Model(&model.User{}).Where("id = ?", req.UserID).Updates(map[string]any{
+			"email":          req.NewEmail,
+			"email_verified": true,
+		}).Error; err != nil {
+			return apiErrors.InternalServerError(fmt.Errorf("failed to update email: %w", err))
+		}
+		return nil
+	})
+}
+
+// CancelEmailChange cancels a pending email change from the link sent to the
+// old address.
+func (s *VerificationService) CancelEmailChange(ctx context.Context, rawToken string) error {
+	result := s.db.WithContext(ctx).Model(&model.EmailChangeRequest{}).
+		Where("cancel_token_hash = ? AND expires_at > ? AND confirmed_at IS NULL AND cancelled_at IS NULL", hashToken(rawToken), time.Now()).
+		Update("cancelled_at", time.Now())
+	if result.Error != nil {
+		return apiErrors.InternalServerError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return apiErrors.BadRequest("Invalid or expired link, or the change was already confirmed")
+	}
+	return nil
+}
+
+// ensureEmailAvailable returns a conflict when another user has the address.
+func ensureEmailAvailable(db *gorm.DB, emailAddr string, userID uuid.UUID) error {
+	var taken int64
+	if err := db.Model(&model.User{}).
+		Where("LOWER(email) = LOWER(?) AND id <> ?", emailAddr, userID).
+		Count(&taken).Error; err != nil {
+		return apiErrors.InternalServerError(err)
+	}
+	if taken > 0 {
+		return apiErrors.Conflict("Email already exists")
+	}
+	return nil
+}
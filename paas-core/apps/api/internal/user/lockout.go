@@ -0,0 +1,239 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"paas-core/apps/api/internal/email"
+	apiErrors "paas-core/apps/api/internal/errors"
+	"paas-core/apps/api/internal/model"
+)
+
+// Brute-force protection policy for password logins.
+const (
+	lockoutFreeAttempts  = 3                // failures allowed before delays start
+	lockoutThreshold     = 10               // failures that lock the account
+	lockoutBaseDelay     = time.Second      // first delay, doubled for every further failure
+	lockoutMaxDelay      = 5 * time.Minute  // cap for the progressive delay
+	lockoutDuration      = 30 * time.Minute // how long a lock lasts without the unlock link
+	lockoutFailureWindow = time.Hour        // failures older than this are forgotten
+	unlockTokenExpiry    = time.Hour
+)
+
+// LockoutService tracks failed logins per account and enforces progressive
+// delays and temporary lockouts.
+type LockoutService struct {
+	db           *gorm.DB
+	emailService email.Service
+	appName      string
+	appURL       string
+}
+
+// NewLockoutService creates a new lockout service.
+func NewLockoutService(db *gorm.DB, emailService email.Service, appName, appURL string) *LockoutService {
+	return &LockoutService{
+		db:           db,
+		emailService: emailService,
+		appName:      appName,
+		appURL:       appURL,
+	}
+}
+
+// Check returns a RateLimitExceeded error if the account is locked or still
+// inside its backoff delay.
+func (s *LockoutService) Check(ctx context.Context, userID uuid.UUID) error {
+	var rec model.AccountLockout
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).First(&rec).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return apiErrors.InternalServerError(err)
+	}
+
+	if wait := retryAfter(&rec, time.Now()); wait > 0 {
+		return apiErrors.RateLimitExceeded(seconds(wait))
+	}
+	return nil
+}
+
+// RecordFailure counts a failed login. It returns a RateLimitExceeded error
+// when this failure locks the account, in which case an unlock link is emailed.
+func (s *LockoutService) RecordFailure(ctx context.Context, usr *model.User) error {
+	now := time.Now()
+	var rec model.AccountLockout
+	var locked bool
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.AccountLockout{UserID: usr.ID, LastFailedAt: now}).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", usr.ID).First(&rec).Error; err != nil {
+			return err
+		}
+
+		lockActive := rec.LockedUntil != nil && now.Before(*rec.LockedUntil)
+		if !lockActive && (rec.LockedUntil != nil || now.Sub(rec.LastFailedAt) > lockoutFailureWindow) {
+			// Start over once a lock has expired or the last failure is stale.
+			rec.FailedAttempts = 0
+			rec.LockedUntil = nil
+		}
+
+		rec.FailedAttempts++
+		rec.LastFailedAt = now
+		if !lockActive && rec.FailedAttempts >= lockoutThreshold {
+			until := now.Add(lockoutDuration)
+			rec.LockedUntil = &until
+			locked = true
+		}
+		return tx.Save(&rec).Error
+	})
+	if err != nil {
+		return apiErrors.InternalServerError(fmt.Errorf("failed to record login failure: %w", err))
+	}
+
+	if !locked {
+		return nil
+	}
+
+	slog.Warn("Account locked after repeated failed logins", "user_id", usr.ID, "attempts", rec.FailedAttempts)
+	if err := s.sendUnlockEmail(ctx, usr); err != nil {
+		slog.Error("Failed to send account unlock email", "user_id", usr.ID, "error", err)
+	}
+	return apiErrors.RateLimitExceeded(seconds(lockoutDuration))
+}
+
+// Reset clears the failure count after a successful login.
+func (s *LockoutService) Reset(ctx context.Context, userID uuid.UUID) error {
+	return s.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.AccountLockout{}).Error
+}
+
+// Unlock redeems an emailed unlock token.
+func (s *LockoutService) Unlock(ctx context.Context, rawToken string) error {
+	var token model.AccountUnlockToken
+	if err := s.db.WithContext(ctx).
+		Where("token_hash = ? AND expires_at > ? AND used_at IS NULL", hashToken(rawToken), time.Now()).
+		First(&token).Error; err != nil {
+		return apiErrors.BadRequest("Invalid or expired unlock token")
+	}
+
+	return s.unlock(ctx, token.UserID)
+}
+
+// AdminUnlock unlocks an account on behalf of an administrator.
+func (s *LockoutService) AdminUnlock(ctx context.Context, userID uuid.UUID) error {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).Count(&count).Error; err != nil {
+		return apiErrors.InternalServerError(err)
+	}
+	if count == 0 {
+		return apiErrors.NotFound("User not found")
+	}
+
+	return s.unlock(ctx, userID)
+}
+
+// unlock clears the lockout and invalidates any outstanding unlock links.
+func (s *LockoutService) unlock(ctx context.Context, userID uuid.UUID) error {
+	now := time.Now()
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.AccountLockout{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.AccountUnlockToken{}).
+			Where("user_id = ? AND used_at IS NULL", userID).
+			Update("used_at", now).Error
+	})
+	if err != nil {
+		return apiErrors.InternalServerError(fmt.Errorf("failed to unlock account: %w", err))
+	}
+	return nil
+}
+
+// sendUnlockEmail emails an unlock link unless one is still outstanding.
+func (s *LockoutService) sendUnlockEmail(ctx context.Context, usr *model.User) error {
+	var pending int64
+	if err := s.db.WithContext(ctx).Model(&model.AccountUnlockToken{}).
+		Where("user_id = ? AND expires_at > ? AND used_at IS NULL", usr.ID, time.Now()).
+		Count(&pending).Error; err != nil {
+		return err
+	}
+	if pending > 0 {
+		return nil
+	}
+
+	rawToken, tokenHash := generateTokenPair()
+
+	record := &model.AccountUnlockToken{
+		UserID:    usr.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(unlockTokenExpiry),
+	}
+	if err := s.db.WithContext(ctx).Create(record).Error; err != nil {
+		return fmt.Errorf("failed to create unlock token: %w", err)
+	}
+
+	link := fmt.Sprintf("%s/auth/unlock?token=%s", s.appURL, rawToken)
+
+	msg := email.RenderAccountLockedEmail(email.TemplateData{
+		AppName:   s.appName,
+		AppURL:    s.appURL,
+		UserName:  usr.Name,
+		UserEmail: usr.Email,
+		Token:     rawToken,
+		Link:      link,
+		ExpiresIn: "1 hour",
+	})
+
+	return s.emailService.Send(ctx, msg)
+}
+
+// retryAfter returns how long the account must wait before the next attempt.
+func retryAfter(rec *model.AccountLockout, now time.Time) time.Duration {
+	if rec.LockedUntil != nil {
+		if now.Before(*rec.LockedUntil) {
+			return rec.LockedUntil.Sub(now)
+		}
+		return 0 // lock expired
+	}
+	if now.Sub(rec.LastFailedAt) > lockoutFailureWindow {
+		return 0
+	}
+
+	next := rec.LastFailedAt.Add(backoffDelay(rec.FailedAttempts))
+	if now.Before(next) {
+		return next.Sub(now)
+	}
+	return 0
+}
+
+// backoffDelay is the wait imposed after the given number of consecutive
+// failures: nothing for the first few, then doubling up to lockoutMaxDelay.
+func backoffDelay(failures int) time.Duration {
+	if failures < lockoutFreeAttempts {
+		return 0
+	}
+	exp := failures - lockoutFreeAttempts
+	if exp > 30 {
+		return lockoutMaxDelay
+	}
+	delay := lockoutBaseDelay << exp
+	if delay > lockoutMaxDelay {
+		return lockoutMaxDelay
+	}
+	return delay
+}
+
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,73 @@
+package user
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	apiErrors "paas-core/apps/api/internal/errors"
+)
+
+// LockoutHandler handles unlocking accounts locked by failed logins.
+type LockoutHandler struct {
+	lockoutService *LockoutService
+}
+
+// NewLockoutHandler creates a new lockout handler.
+func NewLockoutHandler(ls *LockoutService) *LockoutHandler {
+	return &LockoutHandler{lockoutService: ls}
+}
+
+// UnlockAccountRequest is the DTO for unlocking an account by email link.
+type UnlockAccountRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// UnlockAccount godoc
+// @Summary Unlock account
+// @Description Unlock an account with the token from the lockout email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body UnlockAccountRequest true "Unlock request"
+// @Success 200 {object} errors.Response "Account unlocked"
+// @Failure 400 {object} errors.Response "Invalid or expired token"
+// @Router /api/v1/auth/unlock [post]
+func (h *LockoutHandler) UnlockAccount(c *gin.Context) {
+	var req UnlockAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	if err := h.lockoutService.Unlock(c.Request.Context(), req.Token); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(gin.H{"message": "Account unlocked"}))
+}
+
+// AdminUnlock godoc
+// @Summary Unlock a user's account (admin only)
+// @Tags users
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} errors.Response "Account unlocked"
+// @Failure 404 {object} errors.Response "User not found"
+// @Router /api/v1/users/{id}/unlock [post]
+func (h *LockoutHandler) AdminUnlock(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		_ = c.Error(apiErrors.BadRequest("Invalid user ID"))
+		return
+	}
+
+	if err := h.lockoutService.AdminUnlock(c.Request.Context(), id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(gin.H{"message": "Account unlocked"}))
+}
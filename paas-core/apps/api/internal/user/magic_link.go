@@ -0,0 +1,194 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"paas-core/apps/api/internal/email"
+	apiErrors "paas-core/apps/api/internal/errors"
+	"paas-core/apps/api/internal/model"
+)
+
+const (
+	magicLinkTokenExpiry    = 15 * time.Minute
+	magicLinkResendInterval = time.Minute // at most one link per address per minute
+)
+
+// SendMagicLink emails a single-use sign-in link. Addresses without an account
+// only get a link when they hold a pending org invite, in which case the
+// account is created when the link is redeemed. Returns nil silently for
+// unknown addresses (security: don't reveal existence).
+func (s *VerificationService) SendMagicLink(ctx context.Context, emailAddr string) error {
+	db := s.db.WithContext(ctx)
+	now := time.Now()
+
+	var usr model.User
+	var userID *uuid.UUID
+	err := db.Where("email = ?", emailAddr).First(&usr).Error
+	switch {
+	case err == nil:
+		if usr.IsServiceAccount {
+			return nil
+		}
+		userID = &usr.ID
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		invited, err := hasPendingInvite(db, emailAddr, now)
+		if err != nil || !invited {
+			return err
+		}
+		usr = model.User{Name: nameFromEmail(emailAddr), Email: emailAddr}
+	default:
+		return err
+	}
+
+	// Throttle per address so the endpoint can't be used to flood a mailbox
+	var recent int64
+	if err := db.Model(&model.MagicLinkToken{}).
+		Where("email = ? AND created_at > ?", emailAddr, now.Add(-magicLinkResendInterval)).
+		Count(&recent).Error; err != nil {
+		return err
+	}
+	if recent > 0 {
+		return nil
+	}
+
+	rawToken, tokenHash := generateTokenPair()
+
+	record := &model.MagicLinkToken{
+		UserID:    userID,
+		Email:     emailAddr,
+		TokenHash: tokenHash,
+		ExpiresAt: now.Add(magicLinkTokenExpiry),
+	}
+	if err := db.Create(record).Error; err != nil {
+		return fmt.Errorf("failed to create magic link token: %w", err)
+	}
+
+	link := fmt.Sprintf("%s/auth/magic-link?token=%s", s.appURL, rawToken)
+
+	msg := email.RenderMagicLinkEmail(email.TemplateData{
+		AppName:   s.appName,
+		AppURL:    s.appURL,
+		UserName:  usr.Name,
+		UserEmail: usr.Email,
+		Token:     rawToken,
+		Link:      link,
+		ExpiresIn: "15 minutes",
+	})
+
+	return s.emailService.Send(ctx, msg)
+}
+
+// RedeemMagicLink consumes a magic link token and returns the signed-in user
+// with roles loaded, creating the account for an invited address. Redeeming a
+// link proves ownership of the address, so the email is marked verified.
+func (s *VerificationService) RedeemMagicLink(ctx context.Context, rawToken string) (*model.User, error) {
+	tokenHash := hashToken(rawToken)
+	now := time.Now()
+
+	var usr model.User
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var token model.MagicLinkToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND expires_at > ? AND used_at IS NULL", tokenHash, now).
+			First(&token).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apiErrors.BadRequest("Invalid or expired sign-in link")
+			}
+			return err
+		}
+
+		// Invalidate this and any other outstanding links for the address
+		if err := tx.Model(&model.MagicLinkToken{}).
+			Where("email = ? AND used_at IS NULL", token.Email).
+			Update("used_at", now).Error; err != nil {
+			return fmt.Errorf("failed to mark token as used: %w", err)
+		}
+
+		if token.UserID != nil {
+			err := tx.First(&usr, "id = ?", *token.UserID).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apiErrors.BadRequest("Invalid or expired sign-in link")
+			}
+			return err
+		}
+
+		// Signup link: the address may have registered since it was sent
+		err := tx.Where("email = ?", token.Email).First(&usr).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return createInvitedUser(tx, token.Email, now, &usr)
+		}
+		return err
+	})
+	if err != nil {
+		var apiErr *apiErrors.APIError
+		if errors.As(err, &apiErr) {
+			return nil, err
+		}
+		return nil, apiErrors.InternalServerError(err)
+	}
+
+	if usr.IsServiceAccount {
+		return nil, apiErrors.BadRequest("Invalid or expired sign-in link")
+	}
+
+	if !usr.EmailVerified {
+		if err := s.db.WithContext(ctx).Model(&usr).Update("email_verified", true).Error; err != nil {
+			return nil, apiErrors.InternalServerError(fmt.Errorf("failed to verify user email: %w", err))
+		}
+	}
+
+	if err := s.db.WithContext(ctx).Preload("Roles").First(&usr, "id = ?", usr.ID).Error; err != nil {
+		return nil, apiErrors.InternalServerError(err)
+	}
+	return &usr, nil
+}
+
+// createInvitedUser creates a passwordless account for an address that still
+// holds a pending org invite. The invite itself is accepted separately.
+func createInvitedUser(tx *gorm.DB, emailAddr string, now time.Time, usr *model.User) error {
+	invited, err := hasPendingInvite(tx, emailAddr, now)
+	if err != nil {
+		return err
+	}
+	if !invited {
+		return apiErrors.BadRequest("Invalid or expired sign-in link")
+	}
+
+	*usr = model.User{
+		Name:          nameFromEmail(emailAddr),
+		Email:         emailAddr,
+		EmailVerified: true,
+	}
+	if err := tx.Create(usr).Error; err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	var role model.Role
+	if err := tx.Where("name = ?", model.RoleUser).First(&role).Error; err != nil {
+		return fmt.Errorf("failed to find role: %w", err)
+	}
+	return tx.Create(&model.UserRole{UserID: usr.ID, RoleID: role.ID}).Error
+}
+
+func hasPendingInvite(db *gorm.DB, emailAddr string, now time.Time) (bool, error) {
+	var count int64
+	err := db.Model(&model.OrgInvite{}).
+		Where("email = ? AND accepted_at IS NULL AND expires_at > ?", emailAddr, now).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// nameFromEmail derives a display name for accounts created without a signup
+// form; users can change it later.
+func nameFromEmail(emailAddr string) string {
+	local, _, _ := strings.Cut(emailAddr, "@")
+	return local
+}
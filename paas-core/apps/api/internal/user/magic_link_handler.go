@@ -0,0 +1,127 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"paas-core/apps/api/internal/auth"
+	apiErrors "paas-core/apps/api/internal/errors"
+)
+
+// MagicLinkHandler handles passwordless email sign-in.
+type MagicLinkHandler struct {
+	verificationService *VerificationService
+	authService         auth.Service
+	mfa                 auth.MFAChallenger // optional; nil disables the MFA step
+	transport           *auth.SessionTransport
+}
+
+// NewMagicLinkHandler creates a new magic link handler.
+func NewMagicLinkHandler(vs *VerificationService, authService auth.Service, mfa auth.MFAChallenger, transport *auth.SessionTransport) *MagicLinkHandler {
+	return &MagicLinkHandler{
+		verificationService: vs,
+		authService:         authService,
+		mfa:                 mfa,
+		transport:           transport,
+	}
+}
+
+// RequestMagicLinkRequest is the DTO for requesting a sign-in link.
+type RequestMagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// RedeemMagicLinkRequest is the DTO for exchanging a sign-in link for tokens.
+type RedeemMagicLinkRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// RequestMagicLink godoc
+// @Summary Request a sign-in link
+// @Description Email a single-use sign-in link. Addresses with a pending org invite but no account can use it to sign up.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body RequestMagicLinkRequest true "Magic link request"
+// @Success 200 {object} errors.Response "Link sent (always returns success)"
+// @Router /api/v1/auth/magic-link [post]
+func (h *MagicLinkHandler) RequestMagicLink(c *gin.Context) {
+	var req RequestMagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	// Always return success to not reveal email existence
+	if err := h.verificationService.SendMagicLink(c.Request.Context(), req.Email); err != nil {
+		slog.Error("Failed to send magic link", "error", err)
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(gin.H{"message": "If that email can sign in, a sign-in link has been sent."}))
+}
+
+// RedeemMagicLink godoc
+// @Summary Sign in with a magic link
+// @Description Exchange the token from a sign-in email for access and refresh tokens. Users with MFA enabled get an mfa_token to complete at /auth/mfa/verify.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body RedeemMagicLinkRequest true "Magic link token"
+// @Success 200 {object} errors.Response{data=auth.AuthResponse} "Success"
+// @Failure 400 {object} errors.Response "Invalid or expired link"
+// @Router /api/v1/auth/magic-link/verify [post]
+func (h *MagicLinkHandler) RedeemMagicLink(c *gin.Context) {
+	var req RedeemMagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	usr, err := h.verificationService.RedeemMagicLink(ctx, req.Token)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	roles := extractRoleNames(usr.Roles)
+
+	// A magic link replaces the password, not the second factor
+	if h.mfa != nil {
+		enabled, err := h.mfa.IsEnabled(ctx, usr.ID)
+		if err != nil {
+			_ = c.Error(apiErrors.InternalServerError(err))
+			return
+		}
+		if enabled {
+			mfaToken, err := h.mfa.CreateChallenge(ctx, usr.ID)
+			if err != nil {
+				_ = c.Error(apiErrors.InternalServerError(err))
+				return
+			}
+			h.transport.WriteAuthResponse(c, &auth.AuthResponse{
+				MFARequired: true,
+				MFAToken:    mfaToken,
+				User:        *toUserResponse(usr),
+			})
+			return
+		}
+	}
+
+	tokenPair, err := h.authService.GenerateTokenPair(ctx, usr.ID, usr.Email, usr.Name, roles, []string{auth.AMRMagicLink})
+	if err != nil {
+		if errors.Is(err, auth.ErrSuspended) {
+			_ = c.Error(apiErrors.Forbidden("Your account is suspended"))
+			return
+		}
+		_ = c.Error(apiErrors.InternalServerError(fmt.Errorf("failed to generate tokens: %w", err)))
+		return
+	}
+
+	h.transport.WriteAuthResponse(c, auth.NewAuthResponse(tokenPair, usr, roles))
+}
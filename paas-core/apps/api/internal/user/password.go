@@ -4,6 +4,8 @@ import (
 	"errors"
 	"strings"
 	"unicode"
+
+	passwordPkg "paas-core/apps/api/internal/password"
 )
 
 // Password validation errors.
@@ -14,10 +16,11 @@ var (
 	ErrPasswordNoDigit     = errors.New("password must contain at least one digit")
 	ErrPasswordNoSpecial   = errors.New("password must contain at least one special character")
 	ErrPasswordCommon      = errors.New("password is too common")
+	ErrPasswordBreached    = errors.New("password has appeared in a data breach; choose a different one")
 )
 
-// commonPasswords is a minimal blocklist of extremely common passwords.
-// In production, consider checking against the HaveIBeenPwned API.
+// commonPasswords is a minimal blocklist of extremely common passwords,
+// checked even when no breach filter is configured.
 var commonPasswords = map[string]bool{
 	"password1234": true,
 	"123456789012": true,
@@ -38,10 +41,11 @@ var commonPasswords = map[string]bool{
 //   - At least one digit
 //   - At least one special character
 //   - Not in the common password blocklist
+//   - Not in the breached password filter, when one is configured
 //
 // These rules are stricter than the bare NIST minimum (8 chars + blocklist)
 // but aligned with Goilerplate's production hardening approach.
-func ValidatePasswordNIST(password string) error {
+func ValidatePasswordNIST(password string, breached *passwordPkg.BreachFilter) error {
 	if len(password) < 12 {
 		return ErrPasswordTooShort
 	}
@@ -77,5 +81,9 @@ func ValidatePasswordNIST(password string) error {
 		return ErrPasswordCommon
 	}
 
+	if breached.Contains(password) {
+		return ErrPasswordBreached
+	}
+
 	return nil
 }
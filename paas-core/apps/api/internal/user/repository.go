@@ -19,6 +19,7 @@ type Repository interface {
 	FindByEmail(ctx context.Context, email string) (*model.User, error)
 	FindByID(ctx context.Context, id uuid.UUID) (*model.User, error)
 	Update(ctx context.Context, user *model.User) error
+	UpdatePasswordHash(ctx context.Context, id uuid.UUID, oldHash, newHash string) error
 	Delete(ctx context.Context, id uuid.UUID) error
 	ListAllUsers(ctx context.Context, filters FilterParams, page, perPage int) ([]model.User, int64, error)
 	AssignRole(ctx context.Context, userID uuid.UUID, roleName string) error
@@ -76,6 +77,14 @@ func (r *repository) Update(ctx context.Context, user *model.User) error {
 		Save(user).Error
 }
 
+// UpdatePasswordHash replaces the password hash only if it is still oldHash,
+// so an upgrade never overwrites a password changed in the meantime.
+func (r *repository) UpdatePasswordHash(ctx context.Context, id uuid.UUID, oldHash, newHash string) error {
+	return r.getDB(ctx).WithContext(ctx).Model(&model.User{}).
+		Where("id = ? AND password_hash = ?", id, oldHash).
+		Update("password_hash", newHash).Error
+}
+
 func (r *repository) Delete(ctx context.Context, id uuid.UUID) error {
 	result := r.getDB(ctx).WithContext(ctx).Delete(&model.User{}, "id = ?", id)
 	if result.Error != nil {
@@ -4,14 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 
 	"paas-core/apps/api/internal/auth"
 	apiErrors "paas-core/apps/api/internal/errors"
 	"paas-core/apps/api/internal/model"
+	passwordPkg "paas-core/apps/api/internal/password"
 )
 
 var (
@@ -30,7 +32,7 @@ type FilterParams struct {
 // UpdateUserRequest is the DTO for user updates.
 type UpdateUserRequest struct {
 	Name      string `json:"name" binding:"omitempty,min=2,max=100"`
-	Email     string `json:"email" binding:"omitempty,email"`
+	Email     string `json:"email" binding:"omitempty,email"` // rejected unless unchanged; use the email change flow
 	AvatarURL string `json:"avatar_url" binding:"omitempty,url"`
 }
 
@@ -38,6 +40,8 @@ type UpdateUserRequest struct {
 type Service interface {
 	RegisterUser(ctx *gin.Context, req auth.RegisterRequest) (*auth.UserResponse, []string, error)
 	AuthenticateUser(ctx *gin.Context, req auth.LoginRequest) (*auth.UserResponse, []string, error)
+	VerifyPassword(ctx context.Context, userID uuid.UUID, password string) error
+	ImportPassword(ctx context.Context, userID uuid.UUID, password string) error
 	GetUserByID(ctx context.Context, id uuid.UUID) (*auth.UserResponse, error)
 	UpdateUser(ctx context.Context, id uuid.UUID, req UpdateUserRequest) (*auth.UserResponse, error)
 	DeleteUser(ctx context.Context, id uuid.UUID) error
@@ -45,17 +49,21 @@ type Service interface {
 }
 
 type service struct {
-	repo Repository
+	repo     Repository
+	lockout  *LockoutService
+	hasher   passwordPkg.Hasher
+	breached *passwordPkg.BreachFilter
 }
 
-// NewService creates a new user service.
-func NewService(repo Repository) Service {
-	return &service{repo: repo}
+// NewService creates a new user service. Password logins are throttled per
+// account by lockout; breached may be nil when no breach filter is configured.
+func NewService(repo Repository, lockout *LockoutService, hasher passwordPkg.Hasher, breached *passwordPkg.BreachFilter) Service {
+	return &service{repo: repo, lockout: lockout, hasher: hasher, breached: breached}
 }
 
 func (s *service) RegisterUser(ctx *gin.Context, req auth.RegisterRequest) (*auth.UserResponse, []string, error) {
-	// NIST password validation (min 12 chars, complexity, blocklist)
-	if err := ValidatePasswordNIST(req.Password); err != nil {
+	// NIST password validation (min 12 chars, complexity, blocklist, breaches)
+	if err := ValidatePasswordNIST(req.Password, s.breached); err != nil {
 		return nil, nil, apiErrors.BadRequest(err.Error())
 	}
 
@@ -67,15 +75,15 @@ func (s *service) RegisterUser(ctx *gin.Context, req auth.RegisterRequest) (*aut
 		return nil, nil, apiErrors.Conflict("Email already exists")
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := s.hasher.Hash(req.Password)
 	if err != nil {
-		return nil, nil, apiErrors.InternalServerError(fmt.Errorf("failed to hash password: %w", err))
+		return nil, nil, apiErrors.InternalServerError(err)
 	}
 
 	user := &model.User{
 		Name:         req.Name,
 		Email:        req.Email,
-		PasswordHash: string(hashedPassword),
+		PasswordHash: hashedPassword,
 	}
 
 	err = s.repo.Transaction(ctx.Request.Context(), func(txCtx context.Context) error {
@@ -110,14 +118,89 @@ func (s *service) AuthenticateUser(ctx *gin.Context, req auth.LoginRequest) (*au
 		return nil, nil, apiErrors.Unauthorized("Invalid email or password")
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		return nil, nil, apiErrors.Unauthorized("Invalid email or password")
+	if err := s.checkPassword(ctx.Request.Context(), user, req.Password, apiErrors.Unauthorized("Invalid email or password")); err != nil {
+		return nil, nil, err
+	}
+	if user.IsSuspended() {
+		return nil, nil, apiErrors.Forbidden("Your account is suspended")
 	}
 
 	roles := extractRoleNames(user.Roles)
 	return toUserResponse(user), roles, nil
 }
 
+// VerifyPassword checks the password of a signed-in user for step-up
+// re-authentication. Failures count toward the same lockout as logins.
+func (s *service) VerifyPassword(ctx context.Context, userID uuid.UUID, password string) error {
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return apiErrors.InternalServerError(fmt.Errorf("failed to find user: %w", err))
+	}
+	if user == nil {
+		return apiErrors.Unauthorized("")
+	}
+	if !user.HasPassword() {
+		return apiErrors.BadRequest("Your account has no password; confirm with a verification code or your sign-in provider")
+	}
+
+	return s.checkPassword(ctx, user, password, apiErrors.Unauthorized("Invalid password"))
+}
+
+// ImportPassword stores a password the user just proved at another auth
+// provider, so they can sign in locally once migrated. It is not screened
+// again since the user chose it before.
+func (s *service) ImportPassword(ctx context.Context, userID uuid.UUID, password string) error {
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	return s.repo.UpdatePasswordHash(ctx, userID, user.PasswordHash, hash)
+}
+
+// checkPassword verifies a password under the account lockout and returns
+// invalidErr on a mismatch. After a match, a hash made with an outdated
+// algorithm or cost is replaced.
+func (s *service) checkPassword(ctx context.Context, user *model.User, password string, invalidErr error) error {
+	if err := s.lockout.Check(ctx, user.ID); err != nil {
+		return err
+	}
+
+	needsRehash, err := s.hasher.Verify(user.PasswordHash, password)
+	if err != nil {
+		if !errors.Is(err, passwordPkg.ErrMismatch) && user.HasPassword() {
+			slog.Error("Failed to verify password hash", "user_id", user.ID, "error", err)
+		}
+		if lockErr := s.lockout.RecordFailure(ctx, user); lockErr != nil {
+			return lockErr
+		}
+		return invalidErr
+	}
+
+	if err := s.lockout.Reset(ctx, user.ID); err != nil {
+		return apiErrors.InternalServerError(fmt.Errorf("failed to reset login failures: %w", err))
+	}
+
+	if needsRehash {
+		// The login already succeeded, so a failed upgrade is only logged
+		if hash, err := s.hasher.Hash(password); err != nil {
+			slog.Error("Failed to rehash password", "user_id", user.ID, "error", err)
+		} else if err := s.repo.UpdatePasswordHash(ctx, user.ID, user.PasswordHash, hash); err != nil {
+			slog.Error("Failed to store rehashed password", "user_id", user.ID, "error", err)
+		} else {
+			user.PasswordHash = hash
+		}
+	}
+	return nil
+}
+
 func (s *service) GetUserByID(ctx context.Context, id uuid.UUID) (*auth.UserResponse, error) {
 	user, err := s.repo.FindByID(ctx, id)
 	if err != nil {
@@ -141,15 +224,9 @@ func (s *service) UpdateUser(ctx context.Context, id uuid.UUID, req UpdateUserRe
 	if req.Name != "" {
 		user.Name = req.Name
 	}
-	if req.Email != "" {
-		existing, err := s.repo.FindByEmail(ctx, req.Email)
-		if err != nil {
-			return nil, apiErrors.InternalServerError(err)
-		}
-		if existing != nil && existing.ID != user.ID {
-			return nil, apiErrors.Conflict("Email already exists")
-		}
-		user.Email = req.Email
+	// Email changes must be confirmed by the new address
+	if req.Email != "" && !strings.EqualFold(req.Email, user.Email) {
+		return nil, apiErrors.BadRequest("Use POST /users/me/email to change your email address")
 	}
 	if req.AvatarURL != "" {
 		user.AvatarURL = req.AvatarURL
@@ -8,12 +8,13 @@ import (
 	"fmt"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 
+	"paas-core/apps/api/internal/alert"
 	"paas-core/apps/api/internal/email"
 	apiErrors "paas-core/apps/api/internal/errors"
 	"paas-core/apps/api/internal/model"
+	passwordPkg "paas-core/apps/api/internal/password"
 )
 
 const (
@@ -26,15 +27,21 @@ const (
 type VerificationService struct {
 	db           *gorm.DB
 	emailService email.Service
+	hasher       passwordPkg.Hasher
+	breached     *passwordPkg.BreachFilter
+	alerts       *alert.Service
 	appName      string
 	appURL       string // e.g. "https://app.example.com"
 }
 
 // NewVerificationService creates a new verification service.
-func NewVerificationService(db *gorm.DB, emailService email.Service, appName, appURL string) *VerificationService {
+func NewVerificationService(db *gorm.DB, emailService email.Service, hasher passwordPkg.Hasher, breached *passwordPkg.BreachFilter, alerts *alert.Service, appName, appURL string) *VerificationService {
 	return &VerificationService{
 		db:           db,
 		emailService: emailService,
+		hasher:       hasher,
+		breached:     breached,
+		alerts:       alerts,
 		appName:      appName,
 		appURL:       appURL,
 	}
@@ -129,7 +136,7 @@ func (s *VerificationService) SendPasswordResetEmail(ctx context.Context, emailA
 
 // ResetPassword validates a reset token and updates the user's password.
 func (s *VerificationService) ResetPassword(ctx context.Context, rawToken, newPassword string) error {
-	if err := ValidatePasswordNIST(newPassword); err != nil {
+	if err := ValidatePasswordNIST(newPassword, s.breached); err != nil {
 		return apiErrors.BadRequest(err.Error())
 	}
 
@@ -142,17 +149,17 @@ func (s *VerificationService) ResetPassword(ctx context.Context, rawToken, newPa
 		return apiErrors.BadRequest("Invalid or expired reset token")
 	}
 
-	hashedPw, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	hashedPw, err := s.hasher.Hash(newPassword)
 	if err != nil {
-		return fmt.Errorf("failed to hash password: %w", err)
+		return err
 	}
 
 	now := time.Now()
-	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		if err := tx.Model(&token).Update("used_at", now).Error; err != nil {
 			return fmt.Errorf("failed to mark token as used: %w", err)
 		}
-		if err := tx.Model(&model.User{}).Where("id = ?", token.UserID).Update("password_hash", string(hashedPw)).Error; err != nil {
+		if err := tx.Model(&model.User{}).Where("id = ?", token.UserID).Update("password_hash", hashedPw).Error; err != nil {
 			return fmt.Errorf("failed to update password: %w", err)
 		}
 		// Invalidate all other reset tokens for this user
@@ -161,8 +168,18 @@ func (s *VerificationService) ResetPassword(ctx context.Context, rawToken, newPa
 			Update("used_at", now).Error; err != nil {
 			return fmt.Errorf("failed to invalidate other reset tokens: %w", err)
 		}
+		// A successful reset proves ownership, so lift any login lockout
+		if err := tx.Where("user_id = ?", token.UserID).Delete(&model.AccountLockout{}).Error; err != nil {
+			return fmt.Errorf("failed to clear lockout: %w", err)
+		}
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	s.alerts.PasswordChanged(ctx, token.UserID)
+	return nil
 }
 
 // --- Helpers ---
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	authPkg "paas-core/apps/api/internal/auth"
 	apiErrors "paas-core/apps/api/internal/errors"
 )
 
@@ -39,6 +40,16 @@ type ResendVerificationRequest struct {
 	Email string `json:"email" binding:"required,email"`
 }
 
+// ChangeEmailRequest is the DTO for requesting an email change.
+type ChangeEmailRequest struct {
+	Email string `json:"email" binding:"required,email,max=255"`
+}
+
+// EmailChangeTokenRequest is the DTO for confirming or cancelling an email change.
+type EmailChangeTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
 // VerifyEmail godoc
 // @Summary Verify email address
 // @Description Verify a user's email address with the token from the verification email
@@ -113,3 +124,88 @@ func (h *VerificationHandler) ResetPassword(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset successfully"})
 }
+
+// RequestEmailChange godoc
+// @Summary Request an email change
+// @Description Sends a confirmation link to the new address and a notice with a cancel link to the current one. The email changes only after confirmation. Requires a recent sign-in.
+// @Tags users
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body ChangeEmailRequest true "New email address"
+// @Success 202 {object} errors.Response "Confirmation sent"
+// @Failure 403 {object} errors.Response "Re-authentication required"
+// @Failure 409 {object} errors.Response "Email already exists"
+// @Router /api/v1/users/me/email [post]
+func (h *VerificationHandler) RequestEmailChange(c *gin.Context) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		_ = c.Error(apiErrors.Unauthorized(""))
+		return
+	}
+	authClaims := claims.(*authPkg.Claims)
+
+	var req ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	if err := h.verificationService.RequestEmailChange(c.Request.Context(), authClaims.UserID, req.Email); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, apiErrors.Success(gin.H{"message": "Check your new email address for a confirmation link"}))
+}
+
+// ConfirmEmailChange godoc
+// @Summary Confirm an email change
+// @Description Applies a pending email change with the token sent to the new address
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body EmailChangeTokenRequest true "Confirmation token"
+// @Success 200 {object} errors.Response "Email changed"
+// @Failure 400 {object} errors.Response "Invalid or expired token"
+// @Failure 409 {object} errors.Response "Email already exists"
+// @Router /api/v1/auth/email-change/confirm [post]
+func (h *VerificationHandler) ConfirmEmailChange(c *gin.Context) {
+	var req EmailChangeTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	if err := h.verificationService.ConfirmEmailChange(c.Request.Context(), req.Token); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(gin.H{"message": "Your email address has been changed"}))
+}
+
+// CancelEmailChange godoc
+// @Summary Cancel an email change
+// @Description Cancels a pending email change with the token sent to the current address
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body EmailChangeTokenRequest true "Cancel token"
+// @Success 200 {object} errors.Response "Email change cancelled"
+// @Failure 400 {object} errors.Response "Invalid or expired token"
+// @Router /api/v1/auth/email-change/cancel [post]
+func (h *VerificationHandler) CancelEmailChange(c *gin.Context) {
+	var req EmailChangeTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	if err := h.verificationService.CancelEmailChange(c.Request.Context(), req.Token); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(gin.H{"message": "The email change has been cancelled"}))
+}